@@ -3,10 +3,16 @@ package main
 import (
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	_ "time/tzdata" // site time zones (WIB, WITA, WIT) must resolve on hosts without zoneinfo
 
 	"cybercafe-backend/internal/config"
 	"cybercafe-backend/internal/database"
+	"cybercafe-backend/internal/notify"
+	"cybercafe-backend/internal/realtime"
 	"cybercafe-backend/internal/routes"
+	"cybercafe-backend/internal/workers"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -57,8 +63,17 @@ func main() {
 	// Static files for uploads
 	app.Static("/uploads", cfg.UploadPath)
 
+	// Real-time event hub shared by handlers and workers
+	hub := realtime.NewHub()
+
 	// Setup routes
-	routes.Setup(app, db, cfg)
+	routes.Setup(app, db, cfg, hub, notify.New(cfg))
+
+	// Start background workers
+	sessionExpiryWorker := workers.NewSessionExpiryWorker(db, cfg, hub)
+	sessionExpiryWorker.Start()
+	attendanceFlagWorker := workers.NewAttendanceFlagWorker(db, cfg, hub)
+	attendanceFlagWorker.Start()
 
 	// Start server
 	port := os.Getenv("SERVER_PORT")
@@ -66,6 +81,24 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
-	log.Fatal(app.Listen(":" + port))
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		if err := app.Listen(":" + port); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	// Wait for shutdown signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down server...")
+	// Close open event streams first so Shutdown does not wait on them
+	hub.Close()
+	if err := app.Shutdown(); err != nil {
+		log.Println("Error during server shutdown:", err)
+	}
+	sessionExpiryWorker.Stop()
+	attendanceFlagWorker.Stop()
 }
\ No newline at end of file
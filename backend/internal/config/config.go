@@ -2,7 +2,19 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+)
+
+// How check-ins on a day the location is closed are handled
+const (
+	NonWorkingDayAllow  = "allow"
+	NonWorkingDayFlag   = "flag"
+	NonWorkingDayReject = "reject"
 )
 
 type Config struct {
@@ -11,13 +23,45 @@ type Config struct {
 	DBUser     string
 	DBPassword string
 	DBName     string
+	DBTimezone string
+
+	JWTSecret          string
+	JWTExpire          string
+	RefreshTokenExpire string
+
+	PasswordMinLength        string
+	PasswordRequireMixedCase string
+	PasswordRequireDigit     string
+	PasswordRequireSymbol    string
+	PasswordResetExpire      string
+
+	LoginMaxAttempts      string
+	LoginMaxAttemptsPerIP string
+	LoginLockoutDuration  string
+	LoginAttemptWindow    string
+	LoginDelayBase        string
+	LoginDelayMax         string
 
-	JWTSecret string
-	JWTExpire string
+	TOTPIssuer                 string
+	TwoFactorRequiredForAdmins string
+	PreAuthTokenExpire         string
 
-	ServerPort     string
-	UploadPath     string
-	AllowedOrigins string
+	Notifier         string
+	NotifyWebhookURL string
+
+	ServerPort        string
+	UploadPath        string
+	PrivateUploadPath string // files that must not be served publicly, e.g. medical certificates
+	AllowedOrigins    string
+
+	SessionCheckInterval string
+	SessionWarningBefore string
+
+	MaxShiftLength          string
+	AttendanceCheckInterval string
+	NonWorkingDayCheckIn    string
+
+	AnnualLeaveDays string
 }
 
 func Load() *Config {
@@ -27,13 +71,45 @@ func Load() *Config {
 		DBUser:     getEnv("DB_USER", "postgres"),
 		DBPassword: getEnv("DB_PASSWORD", "987654321"),
 		DBName:     getEnv("DB_NAME", "cybercafe_db"),
+		DBTimezone: getEnv("DB_TIMEZONE", "Asia/Jakarta"),
+
+		JWTSecret:          getEnv("JWT_SECRET", "your-secret-key"),
+		JWTExpire:          getEnv("JWT_EXPIRE", "15m"),
+		RefreshTokenExpire: getEnv("REFRESH_TOKEN_EXPIRE", "168h"),
 
-		JWTSecret: getEnv("JWT_SECRET", "your-secret-key"),
-		JWTExpire: getEnv("JWT_EXPIRE", "24h"),
+		PasswordMinLength:        getEnv("PASSWORD_MIN_LENGTH", "8"),
+		PasswordRequireMixedCase: getEnv("PASSWORD_REQUIRE_MIXED_CASE", "false"),
+		PasswordRequireDigit:     getEnv("PASSWORD_REQUIRE_DIGIT", "true"),
+		PasswordRequireSymbol:    getEnv("PASSWORD_REQUIRE_SYMBOL", "false"),
+		PasswordResetExpire:      getEnv("PASSWORD_RESET_EXPIRE", "1h"),
 
-		ServerPort:     getEnv("SERVER_PORT", "8080"),
-		UploadPath:     getEnv("UPLOAD_PATH", "./uploads"),
-		AllowedOrigins: getEnv("ALLOWED_ORIGINS", "*"),
+		LoginMaxAttempts:      getEnv("LOGIN_MAX_ATTEMPTS", "5"),
+		LoginMaxAttemptsPerIP: getEnv("LOGIN_MAX_ATTEMPTS_PER_IP", "20"),
+		LoginLockoutDuration:  getEnv("LOGIN_LOCKOUT_DURATION", "15m"),
+		LoginAttemptWindow:    getEnv("LOGIN_ATTEMPT_WINDOW", "15m"),
+		LoginDelayBase:        getEnv("LOGIN_DELAY_BASE", "1s"),
+		LoginDelayMax:         getEnv("LOGIN_DELAY_MAX", "30s"),
+
+		TOTPIssuer:                 getEnv("TOTP_ISSUER", "Cybercafe"),
+		TwoFactorRequiredForAdmins: getEnv("TWO_FACTOR_REQUIRED_FOR_ADMINS", "true"),
+		PreAuthTokenExpire:         getEnv("PRE_AUTH_TOKEN_EXPIRE", "5m"),
+
+		Notifier:         getEnv("NOTIFIER", ""),
+		NotifyWebhookURL: getEnv("NOTIFY_WEBHOOK_URL", ""),
+
+		ServerPort:        getEnv("SERVER_PORT", "8080"),
+		UploadPath:        getEnv("UPLOAD_PATH", "./uploads"),
+		PrivateUploadPath: getEnv("PRIVATE_UPLOAD_PATH", "./private_uploads"),
+		AllowedOrigins:    getEnv("ALLOWED_ORIGINS", "*"),
+
+		SessionCheckInterval: getEnv("SESSION_CHECK_INTERVAL", "1m"),
+		SessionWarningBefore: getEnv("SESSION_WARNING_BEFORE", "5m"),
+
+		MaxShiftLength:          getEnv("MAX_SHIFT_LENGTH", "16h"),
+		AttendanceCheckInterval: getEnv("ATTENDANCE_CHECK_INTERVAL", "5m"),
+		NonWorkingDayCheckIn:    getEnv("NON_WORKING_DAY_CHECK_IN", NonWorkingDayFlag),
+
+		AnnualLeaveDays: getEnv("ANNUAL_LEAVE_DAYS", "12"),
 	}
 }
 
@@ -46,4 +122,76 @@ func getEnv(key, defaultValue string) string {
 
 func (c *Config) GetAllowedOrigins() []string {
 	return strings.Split(c.AllowedOrigins, ",")
-}
\ No newline at end of file
+}
+
+// GetPasswordPolicy builds the password policy from the PASSWORD_* settings
+func (c *Config) GetPasswordPolicy() utils.PasswordPolicy {
+	minLength, err := strconv.Atoi(c.PasswordMinLength)
+	if err != nil || minLength < 1 {
+		minLength = 8
+	}
+	return utils.PasswordPolicy{
+		MinLength:        minLength,
+		RequireMixedCase: c.PasswordRequireMixedCase == "true",
+		RequireDigit:     c.PasswordRequireDigit == "true",
+		RequireSymbol:    c.PasswordRequireSymbol == "true",
+	}
+}
+
+// GetLoginLimits builds the brute-force protection settings from the LOGIN_* settings
+func (c *Config) GetLoginLimits() models.LoginLimits {
+	return models.LoginLimits{
+		MaxAttempts:      parseInt(c.LoginMaxAttempts, 5),
+		MaxAttemptsPerIP: parseInt(c.LoginMaxAttemptsPerIP, 20),
+		LockoutDuration:  parseDuration(c.LoginLockoutDuration, 15*time.Minute),
+		Window:           parseDuration(c.LoginAttemptWindow, 15*time.Minute),
+		BaseDelay:        parseDuration(c.LoginDelayBase, time.Second),
+		MaxDelay:         parseDuration(c.LoginDelayMax, 30*time.Second),
+	}
+}
+
+// IsTwoFactorRequiredForAdmins reports whether roles holding "all" must use two-factor login
+func (c *Config) IsTwoFactorRequiredForAdmins() bool {
+	return c.TwoFactorRequiredForAdmins == "true"
+}
+
+// GetMaxShiftLength returns how long a check-in may stay open before it is flagged
+func (c *Config) GetMaxShiftLength() time.Duration {
+	maxLength := parseDuration(c.MaxShiftLength, 16*time.Hour)
+	if maxLength == 0 {
+		return 16 * time.Hour
+	}
+	return maxLength
+}
+
+// GetNonWorkingDayPolicy returns whether check-ins on a location's closed days are
+// allowed, flagged for review or rejected
+func (c *Config) GetNonWorkingDayPolicy() string {
+	switch c.NonWorkingDayCheckIn {
+	case NonWorkingDayAllow, NonWorkingDayReject:
+		return c.NonWorkingDayCheckIn
+	default:
+		return NonWorkingDayFlag
+	}
+}
+
+// GetAnnualLeaveDays returns the yearly annual leave entitlement given to new balances
+func (c *Config) GetAnnualLeaveDays() int {
+	return parseInt(c.AnnualLeaveDays, 12)
+}
+
+func parseInt(value string, fallback int) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return fallback
+	}
+	return n
+}
+
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return fallback
+	}
+	return d
+}
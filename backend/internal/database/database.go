@@ -3,6 +3,7 @@ package database
 import (
 	"fmt"
 	"log"
+	"time"
 
 	"cybercafe-backend/internal/config"
 	"cybercafe-backend/internal/models"
@@ -13,12 +14,13 @@ import (
 )
 
 func Initialize(cfg *config.Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Jakarta",
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=%s",
 		cfg.DBHost,
 		cfg.DBUser,
 		cfg.DBPassword,
 		cfg.DBName,
 		cfg.DBPort,
+		cfg.DBTimezone,
 	)
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
@@ -43,7 +45,7 @@ func Initialize(cfg *config.Config) (*gorm.DB, error) {
 }
 
 func autoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	if err := db.AutoMigrate(
 		&models.Role{},
 		&models.User{},
 		&models.AuditLog{},
@@ -51,15 +53,93 @@ func autoMigrate(db *gorm.DB) error {
 		&models.Location{},
 		&models.MealAllowancePolicy{},
 		&models.MealAllowanceClaim{},
-	)
+		&models.Computer{},
+		&models.TimePackage{},
+		&models.UsageSession{},
+		&models.Customer{},
+		&models.PointLedgerEntry{},
+		&models.StockItem{},
+		&models.StockMovement{},
+		&models.MenuItem{},
+		&models.RecipeLine{},
+		&models.Order{},
+		&models.OrderItem{},
+		&models.OrderStatusHistory{},
+		&models.Transaction{},
+		&models.CashierShift{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.PasswordResetToken{},
+		&models.LoginThrottle{},
+		&models.RecoveryCode{},
+		&models.DeviceSession{},
+		&models.LocationAssignment{},
+		&models.RemoteWorkRequest{},
+		&models.Shift{},
+		&models.ShiftRoster{},
+		&models.LeaveRequest{},
+		&models.LeaveBalance{},
+		&models.Holiday{},
+	); err != nil {
+		return err
+	}
+
+	// A sale earns points once, even when requests race. Earning is keyed by the sale
+	// rather than the session or order, which can be paid again after a void.
+	if err := db.Exec("DROP INDEX IF EXISTS idx_point_ledger_earned_source").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_point_ledger_earning_sale " +
+		"ON point_ledger_entries (sale_id) WHERE type = 'earn'").Error; err != nil {
+		return err
+	}
+
+	// A cashier has at most one open shift, even when open requests race
+	if err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_cashier_shifts_open_cashier " +
+		"ON cashier_shifts (cashier_id) WHERE closed_at IS NULL").Error; err != nil {
+		return err
+	}
+
+	// Check-ins flagged as overdue before overdue_at existed were flagged at that moment
+	if err := db.Model(&models.Attendance{}).Where("overdue_at IS NULL AND flag_reason = ?", models.AttendanceFlagMaxShiftLength).
+		Update("overdue_at", gorm.Expr("flagged_at")).Error; err != nil {
+		return err
+	}
+
+	// Attendance recorded before business dates existed belongs to its check-in day
+	return db.Model(&models.Attendance{}).Where("business_date IS NULL").
+		Update("business_date", gorm.Expr("DATE(check_in_time)")).Error
 }
 
 func seedData(db *gorm.DB) error {
 	// Create default roles
+	// Employees run the cafe floor: sessions, orders, sales and their own cashier shift
+	cashierPermissions := []string{
+		"computers.read", "packages.read", "sessions.read", "sessions.write",
+		"customers.read", "customers.write", "menu.read", "stock.read",
+		"orders.read", "orders.write", "transactions.read.own", "transactions.write",
+		"shifts.read.own", "shifts.write.own",
+	}
+	employeePermissions := append(models.PermissionList{"attendance.read.own", "attendance.write.own"}, cashierPermissions...)
+	managerPermissions := models.PermissionList{
+		"staff.read", "attendance.read", "attendance.write.own", "reports.read", "remote_work.approve", "leave.read", "leave.approve",
+		"computers.read", "computers.write", "packages.read", "packages.write", "sessions.read", "sessions.write",
+		"customers.read", "customers.write", "menu.read", "menu.write", "stock.read", "stock.write",
+		"orders.read", "orders.write", "transactions.read", "transactions.write", "transactions.void",
+		"shifts.read", "shifts.write",
+	}
+
 	roles := []models.Role{
-		{Name: "admin", Description: "Administrator with full access", Permissions: `["all"]`},
-		{Name: "employee", Description: "Regular employee", Permissions: `["attendance.read", "attendance.write.own"]`},
-		{Name: "manager", Description: "Manager with limited admin access", Permissions: `["staff.read", "attendance.read", "reports.read"]`},
+		{Name: "admin", Description: "Administrator with full access", Permissions: models.PermissionList{"all"}},
+		{Name: "employee", Description: "Regular employee", Permissions: employeePermissions},
+		{Name: "manager", Description: "Manager with limited admin access", Permissions: managerPermissions},
+	}
+
+	// Permissions earlier versions seeded; roles still holding exactly these were never
+	// customised and are brought up to date
+	previousDefaults := map[string][]models.PermissionList{
+		"employee": {{"attendance.read", "attendance.write.own"}},
+		"manager":  {{"staff.read", "attendance.read", "reports.read"}},
 	}
 
 	for _, role := range roles {
@@ -70,6 +150,15 @@ func seedData(db *gorm.DB) error {
 					return err
 				}
 			}
+			continue
+		}
+		for _, previous := range previousDefaults[role.Name] {
+			if samePermissions(existingRole.Permissions, previous) {
+				if err := db.Model(&existingRole).Update("permissions", role.Permissions).Error; err != nil {
+					return err
+				}
+				break
+			}
 		}
 	}
 
@@ -88,6 +177,8 @@ func seedData(db *gorm.DB) error {
 				Name:     "System Administrator",
 				RoleID:   adminRole.ID,
 				IsActive: true,
+				// The default password is public, so it must be replaced on first login
+				MustChangePassword: true,
 			}
 			
 			// Set default password: "admin123"
@@ -104,6 +195,11 @@ func seedData(db *gorm.DB) error {
 			log.Println("Password: admin123")
 			log.Println("Please change the password after first login!")
 		}
+	} else if !existingAdmin.MustChangePassword && existingAdmin.CheckPassword("admin123") {
+		// Installs seeded before forced password changes still use the default password
+		if err := db.Model(&existingAdmin).Update("must_change_password", true).Error; err != nil {
+			return err
+		}
 	}
 
 	// Create default locations
@@ -115,7 +211,7 @@ func seedData(db *gorm.DB) error {
 			Longitude:    106.8456,
 			Radius:       100,
 			Type:         "office",
-			WorkingHours: `{"start": "08:00", "end": "17:00", "days": [1,2,3,4,5]}`,
+			WorkingHours: models.WorkingHours{Start: "08:00", End: "17:00", Days: []int{1, 2, 3, 4, 5}},
 			Timezone:     "Asia/Jakarta",
 			CreatedBy:    "system",
 		},
@@ -126,7 +222,7 @@ func seedData(db *gorm.DB) error {
 			Longitude:    112.7521,
 			Radius:       150,
 			Type:         "branch",
-			WorkingHours: `{"start": "08:00", "end": "17:00", "days": [1,2,3,4,5]}`,
+			WorkingHours: models.WorkingHours{Start: "08:00", End: "17:00", Days: []int{1, 2, 3, 4, 5}},
 			Timezone:     "Asia/Jakarta",
 			CreatedBy:    "system",
 		},
@@ -137,7 +233,7 @@ func seedData(db *gorm.DB) error {
 			Longitude:    0,
 			Radius:       0,
 			Type:         "remote",
-			WorkingHours: `{"start": "08:00", "end": "17:00", "days": [1,2,3,4,5,6,7]}`,
+			WorkingHours: models.WorkingHours{Start: "08:00", End: "17:00", Days: []int{1, 2, 3, 4, 5, 6, 7}},
 			Timezone:     "Asia/Jakarta",
 			CreatedBy:    "system",
 		},
@@ -154,5 +250,84 @@ func seedData(db *gorm.DB) error {
 		}
 	}
 
-	return nil
-}
\ No newline at end of file
+	// Create default work shifts; the morning shift keeps the old 09:00 start as the default
+	shifts := []models.Shift{
+		{Name: "Morning", StartTime: "09:00", EndTime: "17:00", GraceMinutes: 0, BreakMinutes: 0, IsDefault: true, IsActive: true},
+		{Name: "Evening", StartTime: "15:00", EndTime: "23:00", GraceMinutes: 10, BreakMinutes: 30, IsActive: true},
+		{Name: "Overnight", StartTime: "22:00", EndTime: "06:00", GraceMinutes: 10, BreakMinutes: 30, IsActive: true},
+	}
+
+	for _, shift := range shifts {
+		var existingShift models.Shift
+		if err := db.Where("name = ?", shift.Name).First(&existingShift).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				if err := db.Create(&shift).Error; err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Create this year's fixed-date national holidays; holidays that follow the lunar
+	// calendar and cuti bersama change every year and are imported from an .ics file
+	year := time.Now().Year()
+	holidays := []models.Holiday{
+		{Date: time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC), Name: "Tahun Baru Masehi", Type: models.HolidayNational},
+		{Date: time.Date(year, time.May, 1, 0, 0, 0, 0, time.UTC), Name: "Hari Buruh Internasional", Type: models.HolidayNational},
+		{Date: time.Date(year, time.June, 1, 0, 0, 0, 0, time.UTC), Name: "Hari Lahir Pancasila", Type: models.HolidayNational},
+		{Date: time.Date(year, time.August, 17, 0, 0, 0, 0, time.UTC), Name: "Hari Kemerdekaan Republik Indonesia", Type: models.HolidayNational},
+		{Date: time.Date(year, time.December, 25, 0, 0, 0, 0, time.UTC), Name: "Hari Raya Natal", Type: models.HolidayNational},
+	}
+
+	for _, holiday := range holidays {
+		var existingHoliday models.Holiday
+		if err := db.Where("date = ? AND name = ? AND location_id IS NULL", holiday.Date.Format("2006-01-02"), holiday.Name).First(&existingHoliday).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				if err := db.Create(&holiday).Error; err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Create default time packages
+	timePackages := []models.TimePackage{
+		{Name: "1 Hour Package", Description: "Standard internet usage", DurationMinutes: 60, Price: 8000, IsActive: true},
+		{Name: "2 Hour Package", Description: "Standard internet usage", DurationMinutes: 120, Price: 15000, IsActive: true},
+		{Name: "5 Hour Package", Description: "Standard internet usage", DurationMinutes: 300, Price: 35000, IsActive: true},
+		{Name: "Gaming Package", Description: "High-performance gaming", DurationMinutes: 180, Price: 25000, IsActive: true},
+		{Name: "All Day Pass", Description: "Unlimited usage for the day", DurationMinutes: 720, Price: 80000, IsActive: true},
+	}
+
+	for _, timePackage := range timePackages {
+		var existingPackage models.TimePackage
+		if err := db.Where("name = ?", timePackage.Name).First(&existingPackage).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				if err := db.Create(&timePackage).Error; err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Existing employees keep checking in at the sites they used before assignments
+	// existed; narrow them down under location assignments afterwards
+	return models.BackfillLocationAssignments(db)
+}
+
+// samePermissions reports whether two permission lists hold the same keys in any order
+func samePermissions(a, b models.PermissionList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	keys := make(map[string]bool, len(a))
+	for _, key := range a {
+		keys[key] = true
+	}
+	for _, key := range b {
+		if !keys[key] {
+			return false
+		}
+	}
+	return true
+}
@@ -9,6 +9,7 @@ import (
 	"log"
 	"cybercafe-backend/internal/config"
 	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/realtime"
 	"cybercafe-backend/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -19,33 +20,77 @@ import (
 type AttendanceHandler struct {
 	db  *gorm.DB
 	cfg *config.Config
+	hub *realtime.Hub
 }
 
-func NewAttendanceHandler(db *gorm.DB, cfg *config.Config) *AttendanceHandler {
-	return &AttendanceHandler{db: db, cfg: cfg}
+func NewAttendanceHandler(db *gorm.DB, cfg *config.Config, hub *realtime.Hub) *AttendanceHandler {
+	return &AttendanceHandler{db: db, cfg: cfg, hub: hub}
 }
 
-
-
-type CheckInRequest struct {
-	Latitude  float64 `json:"latitude" validate:"required"`
-	Longitude float64 `json:"longitude" validate:"required"`
-	Address   string  `json:"address"`
-	Distance  float64 `json:"distance"`
-	IsValid   bool    `json:"is_valid"`
-	Notes     string  `json:"notes"`
+// parseCoordinates reads the latitude and longitude form values
+func parseCoordinates(c *fiber.Ctx) (float64, float64, bool) {
+	latitude, err := strconv.ParseFloat(c.FormValue("latitude"), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	longitude, err := strconv.ParseFloat(c.FormValue("longitude"), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return latitude, longitude, models.IsValidCoordinate(latitude, longitude)
 }
 
 func (h *AttendanceHandler) CheckIn(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uuid.UUID)
 
-	// Check if user already checked in today
-	today := time.Now().Format("2006-01-02")
+	now := time.Now()
+
+	// A forgotten check-out must not block today's check-in, so overdue records are
+	// flagged before looking for an open one
+	if _, err := models.FlagOverdueAttendances(h.db, userID, h.cfg.GetMaxShiftLength(), now); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to check open attendance", err)
+	}
+	if open, err := models.GetOpenAttendance(h.db, userID); err == nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest,
+			"Already checked in since "+open.CheckInTime.Format("2006-01-02 15:04")+"; check out first", nil)
+	} else if err != models.ErrNoOpenAttendance {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to check open attendance", err)
+	}
+
+	latitude, longitude, ok := parseCoordinates(c)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Valid latitude and longitude are required", nil)
+	}
+
+	geofence, err := models.MatchLocation(h.db, userID, latitude, longitude, now)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to validate location", err)
+	}
+
+	// Dates and shift times are the site's local ones, so a WITA branch's day starts at
+	// its own midnight
+	siteNow := now
+	timezone := ""
+	if geofence.Location != nil {
+		siteNow = now.In(geofence.Location.TimeLocation())
+		timezone = geofence.Location.Timezone
+	}
+
+	// Check if user already checked in for this business date
+	businessDate := models.ResolveBusinessDate(h.db, userID, siteNow)
 	var existingAttendance models.Attendance
-	if err := h.db.Where("user_id = ? AND DATE(check_in_time) = ?", userID, today).First(&existingAttendance).Error; err == nil {
+	if err := h.db.Where("user_id = ? AND business_date = ?", userID, businessDate.Format("2006-01-02")).First(&existingAttendance).Error; err == nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Already checked in today", nil)
 	}
 
+	// Check-ins on a day the site is closed need a roster entry to be regular
+	nonWorkingDay := geofence.Location != nil && !geofence.Location.WorkingHours.IsWorkingDay(businessDate) &&
+		!models.HasRosterEntry(h.db, userID, businessDate)
+	if nonWorkingDay && h.cfg.GetNonWorkingDayPolicy() == config.NonWorkingDayReject {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest,
+			geofence.Location.Name+" is closed on "+businessDate.Weekday().String(), nil)
+	}
+
 	// Handle file upload
 	file, err := c.FormFile("photo")
 	if err != nil {
@@ -62,23 +107,29 @@ func (h *AttendanceHandler) CheckIn(c *fiber.Ctx) error {
 	}
 
 	// Parse form data
-	latitude, _ := strconv.ParseFloat(c.FormValue("latitude"), 64)
-	longitude, _ := strconv.ParseFloat(c.FormValue("longitude"), 64)
-	distance, _ := strconv.ParseFloat(c.FormValue("distance"), 64)
-	isValid, _ := strconv.ParseBool(c.FormValue("is_valid"))
 	address := c.FormValue("address")
 	notes := c.FormValue("notes")
 
 	attendance := models.Attendance{
-		UserID:      userID,
-		CheckInTime: time.Now(),
-		PhotoPath:   fmt.Sprintf("/uploads/attendance/%s", filename),
-		Latitude:    latitude,
-		Longitude:   longitude,
-		Address:     address,
-		Distance:    distance,
-		IsValid:     isValid,
-		Notes:       notes,
+		UserID:       userID,
+		CheckInTime:  now,
+		BusinessDate: businessDate,
+		Timezone:     timezone,
+		PhotoPath:    fmt.Sprintf("/uploads/attendance/%s", filename),
+		Latitude:     latitude,
+		Longitude:    longitude,
+		Address:      address,
+		Distance:     geofence.Distance,
+		IsValid:      geofence.IsValid,
+		Notes:        notes,
+	}
+	if geofence.Location != nil {
+		attendance.LocationID = &geofence.Location.ID
+	}
+	if nonWorkingDay && h.cfg.GetNonWorkingDayPolicy() == config.NonWorkingDayFlag {
+		attendance.Flagged = true
+		attendance.FlagReason = models.AttendanceFlagNonWorkingDay
+		attendance.FlaggedAt = &now
 	}
 
 	if err := h.db.Create(&attendance).Error; err != nil {
@@ -86,7 +137,9 @@ func (h *AttendanceHandler) CheckIn(c *fiber.Ctx) error {
 	}
 
 	// Load user for response
-	h.db.Preload("User").First(&attendance, attendance.ID)
+	h.db.Preload("User").Preload("Location").First(&attendance, "id = ?", attendance.ID)
+
+	h.hub.PublishForUser(realtime.TopicAttendance, "attendance.check_in", userID, attendance)
 
 	return utils.SuccessResponse(c, "Check-in recorded successfully", attendance)
 }
@@ -94,11 +147,43 @@ func (h *AttendanceHandler) CheckIn(c *fiber.Ctx) error {
 func (h *AttendanceHandler) CheckOut(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uuid.UUID)
 
-	// Find today's attendance record
-	today := time.Now().Format("2006-01-02")
-	var attendance models.Attendance
-	if err := h.db.Where("user_id = ? AND DATE(check_in_time) = ? AND check_out_time IS NULL", userID, today).First(&attendance).Error; err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "No active check-in found for today", err)
+	// Find the open check-in, which may have started on an earlier calendar day
+	flagged, err := models.FlagOverdueAttendances(h.db, userID, h.cfg.GetMaxShiftLength(), time.Now())
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to check open attendance", err)
+	}
+	open, err := models.GetOpenAttendance(h.db, userID)
+	if err == models.ErrNoOpenAttendance {
+		if len(flagged) > 0 {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Your check-in was open longer than the maximum shift length and has been flagged for review", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "No active check-in found", err)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to find active check-in", err)
+	}
+	attendance := *open
+
+	// The cash drawer must be reconciled before the cashier leaves
+	if _, err := models.GetOpenShift(h.db, userID); err == nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Close your cashier shift before checking out", nil)
+	}
+
+	latitude, longitude, ok := parseCoordinates(c)
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Valid latitude and longitude are required", nil)
+	}
+
+	geofence, err := models.MatchLocation(h.db, userID, latitude, longitude, time.Now())
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to validate location", err)
+	}
+	attendance.CheckOutLatitude = &latitude
+	attendance.CheckOutLongitude = &longitude
+	attendance.CheckOutDistance = &geofence.Distance
+	attendance.CheckOutIsValid = &geofence.IsValid
+	if geofence.Location != nil {
+		attendance.CheckOutLocationID = &geofence.Location.ID
 	}
 
 	// Handle photo upload for checkout
@@ -134,7 +219,9 @@ func (h *AttendanceHandler) CheckOut(c *fiber.Ctx) error {
 	}
 
 	// Load user for response
-	h.db.Preload("User").First(&attendance, attendance.ID)
+	h.db.Preload("User").Preload("Location").Preload("CheckOutLocation").First(&attendance, "id = ?", attendance.ID)
+
+	h.hub.PublishForUser(realtime.TopicAttendance, "attendance.check_out", userID, attendance)
 
 	return utils.SuccessResponse(c, "Check-out recorded successfully", attendance)
 }
@@ -157,10 +244,10 @@ func (h *AttendanceHandler) GetMyAttendance(c *fiber.Ctx) error {
 	// Filter berdasarkan month dan year
 	if month != "" && year != "" {
 		log.Printf("[GET MY ATTENDANCE] Filtering by month: %s, year: %s", month, year)
-		query = query.Where("EXTRACT(MONTH FROM check_in_time) = ? AND EXTRACT(YEAR FROM check_in_time) = ?", month, year)
+		query = query.Where("EXTRACT(MONTH FROM business_date) = ? AND EXTRACT(YEAR FROM business_date) = ?", month, year)
 	} else if month != "" {
 		log.Printf("[GET MY ATTENDANCE] Filtering by month only: %s", month)
-		query = query.Where("TO_CHAR(check_in_time, 'YYYY-MM') = ?", month)
+		query = query.Where("TO_CHAR(business_date, 'YYYY-MM') = ?", month)
 	}
 
 	var total int64
@@ -196,7 +283,7 @@ func (h *AttendanceHandler) GetAllAttendance(c *fiber.Ctx) error {
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	limit, _ := strconv.Atoi(c.Query("limit", "10"))
 	month := c.Query("month", "")
-	userIDStr := c.Query("user_id", "")
+	userIDStr := scopedUserFilter(c, c.Query("user_id", ""))
 
 	offset := (page - 1) * limit
 
@@ -204,7 +291,13 @@ func (h *AttendanceHandler) GetAllAttendance(c *fiber.Ctx) error {
 
 	if month != "" {
 		// Ganti DATE_FORMAT (MySQL) dengan TO_CHAR (PostgreSQL)
-		query = query.Where("TO_CHAR(check_in_time, 'YYYY-MM') = ?", month)
+		query = query.Where("TO_CHAR(business_date, 'YYYY-MM') = ?", month)
+	}
+
+	// Records flagged for staying open past the maximum shift length need review
+	if flagged := c.Query("flagged", ""); flagged != "" {
+		isFlagged, _ := strconv.ParseBool(flagged)
+		query = query.Where("flagged = ?", isFlagged)
 	}
 
 	if userIDStr != "" {
@@ -235,13 +328,15 @@ func (h *AttendanceHandler) GetAllAttendance(c *fiber.Ctx) error {
 
 // GetAttendanceStats returns attendance statistics
 func (h *AttendanceHandler) GetAttendanceStats(c *fiber.Ctx) error {
-	userIDStr := c.Query("user_id", "")
+	userIDStr := scopedUserFilter(c, c.Query("user_id", ""))
 	month := c.Query("month", time.Now().Format("2006-01"))
 
 	query := h.db.Model(&models.Attendance{})
 
+	userID := uuid.Nil
 	if userIDStr != "" {
-		userID, err := uuid.Parse(userIDStr)
+		var err error
+		userID, err = uuid.Parse(userIDStr)
 		if err != nil {
 			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID format", err)
 		}
@@ -250,36 +345,60 @@ func (h *AttendanceHandler) GetAttendanceStats(c *fiber.Ctx) error {
 
 	if month != "" {
 		// Ganti DATE_FORMAT (MySQL) dengan TO_CHAR (PostgreSQL)
-		query = query.Where("TO_CHAR(check_in_time, 'YYYY-MM') = ?", month)
+		query = query.Where("TO_CHAR(business_date, 'YYYY-MM') = ?", month)
 	}
 
-	var totalDays int64
-	query.Count(&totalDays)
+	var attendances []models.Attendance
+	if err := query.Find(&attendances).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch attendance records", err)
+	}
 
-	var presentDays int64
-	query.Where("check_out_time IS NOT NULL").Count(&presentDays)
+	// Lateness is judged against each employee's shift for the day
+	totalDays := len(attendances)
+	presentDays := 0
+	lateDays := 0
+	earlyLeaveDays := 0
+	schedule := models.LoadShiftSchedule(h.db, attendances)
+	for _, att := range attendances {
+		eval := schedule.Evaluate(&att)
+		if !eval.IsIncomplete {
+			presentDays++
+		}
+		if eval.IsLate {
+			lateDays++
+		}
+		if eval.IsEarlyLeave {
+			earlyLeaveDays++
+		}
+	}
 
-	var lateDays int64
-	query.Where("TIME(check_in_time) > '09:00:00'").Count(&lateDays)
+	// Absences are working days in the calendar with neither a check-in nor approved leave
+	summary := h.summarizePeriod(userID, month, "")
 
 	stats := map[string]interface{}{
-		"total_days":   totalDays,
-		"present_days": presentDays,
-		"absent_days":  totalDays - presentDays,
-		"late_days":    lateDays,
-		"month":        month,
+		"total_days":       totalDays,
+		"present_days":     presentDays,
+		"incomplete_days":  totalDays - presentDays,
+		"absent_days":      summary.AbsentDays,
+		"late_days":        lateDays,
+		"early_leave_days": earlyLeaveDays,
+		"working_days":     summary.WorkingDays,
+		"holiday_days":     summary.HolidayDays,
+		"leave_days":       summary.LeaveDays,
+		"attendance_rate":  summary.AttendanceRate(),
+		"month":            month,
 	}
 
 	return utils.SuccessResponse(c, "Attendance statistics retrieved successfully", stats)
 }
 
-// GetTodayAttendance returns today's attendance status for a user
+// GetTodayAttendance returns the user's current attendance status: an open check-in
+// from any day, or else today's record
 func (h *AttendanceHandler) GetTodayAttendance(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uuid.UUID)
 	today := time.Now().Format("2006-01-02")
 
-	var attendance models.Attendance
-	err := h.db.Where("user_id = ? AND DATE(check_in_time) = ?", userID, today).First(&attendance).Error
+	attendance, err := models.GetCurrentAttendance(h.db, userID, time.Now())
 
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -297,6 +416,7 @@ func (h *AttendanceHandler) GetTodayAttendance(c *fiber.Ctx) error {
 		"check_in_time":  attendance.CheckInTime,
 		"check_out_time": attendance.CheckOutTime,
 		"date":           today,
+		"business_date":  attendance.WorkDate().Format("2006-01-02"),
 		"attendance":     attendance,
 	}
 
@@ -342,6 +462,10 @@ func (h *AttendanceHandler) UpdateAttendance(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to find attendance record", err)
 	}
 
+	if !canAccessUser(c, attendance.UserID) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You can only update your own attendance records", nil)
+	}
+
 	type UpdateRequest struct {
 		Notes       *string    `json:"notes"`
 		CheckOutTime *time.Time `json:"check_out_time"`
@@ -356,7 +480,12 @@ func (h *AttendanceHandler) UpdateAttendance(c *fiber.Ctx) error {
 		attendance.Notes = *req.Notes
 	}
 
+	// Employees correct their own notes only; check-out times go through check-out, where
+	// the geofence and maximum shift length are enforced
 	if req.CheckOutTime != nil {
+		if isOwnScope(c) {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Only notes can be changed on your own attendance records", nil)
+		}
 		attendance.CheckOutTime = req.CheckOutTime
 	}
 
@@ -407,19 +536,8 @@ func (h *AttendanceHandler) GetMealAllowanceManagement(c *fiber.Ctx) error {
 	unclaimedCount := 0
 
 	for _, user := range users {
-		// Count total attendance for the month
-		var totalAttendance int64
-		h.db.Model(&models.Attendance{}).
-			Where("user_id = ? AND EXTRACT(MONTH FROM check_in_time) = ? AND EXTRACT(YEAR FROM check_in_time) = ?",
-				user.ID, month, year).
-			Count(&totalAttendance)
-
-		// Count valid attendance (is_valid = true)
-		var validAttendance int64
-		h.db.Model(&models.Attendance{}).
-			Where("user_id = ? AND EXTRACT(MONTH FROM check_in_time) = ? AND EXTRACT(YEAR FROM check_in_time) = ? AND is_valid = ?",
-				user.ID, month, year, true).
-			Count(&validAttendance)
+		// Count total and valid attendance; only working days in the calendar are valid
+		totalAttendance, validAttendance := models.GetValidAttendanceCount(h.db, user.ID, month, year)
 
 		// Calculate meal allowance (15000 per valid attendance)
 		mealAllowance := float64(validAttendance) * 15000
@@ -472,6 +590,14 @@ func (h *AttendanceHandler) GetEmployeeAttendanceDetail(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "User ID is required", nil)
 	}
 
+	ownerID, err := uuid.Parse(userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID format", err)
+	}
+	if !canAccessUser(c, ownerID) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You can only view your own attendance records", nil)
+	}
+
 	// Parse month and year from query parameters
 	monthStr := c.Query("month")
 	yearStr := c.Query("year")
@@ -493,9 +619,9 @@ func (h *AttendanceHandler) GetEmployeeAttendanceDetail(c *fiber.Ctx) error {
 	// Get attendance records for the specific user, month, and year
 	var attendances []models.Attendance
 	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
-	endDate := startDate.AddDate(0, 1, -1).Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	endDate := startDate.AddDate(0, 1, -1)
 
-	err = h.db.Where("user_id = ? AND check_in_time BETWEEN ? AND ?", userID, startDate, endDate).Find(&attendances).Error
+	err = h.db.Where("user_id = ? AND business_date BETWEEN ? AND ?", userID, startDate.Format("2006-01-02"), endDate.Format("2006-01-02")).Find(&attendances).Error
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to retrieve attendance records", err)
 	}
@@ -16,7 +16,7 @@ import (
 func (h *AttendanceHandler) GetAttendanceHistory(c *fiber.Ctx) error {
 	page, _ := strconv.Atoi(c.Query("page", "1"))
 	limit, _ := strconv.Atoi(c.Query("limit", "20"))
-	userIDStr := c.Query("user_id", "")
+	userIDStr := scopedUserFilter(c, c.Query("user_id", ""))
 	month := c.Query("month", "")
 	date := c.Query("date", "")
 	year := c.Query("year", "")
@@ -41,7 +41,7 @@ func (h *AttendanceHandler) GetAttendanceHistory(c *fiber.Ctx) error {
 		if err != nil {
 			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
 		}
-		query = query.Where("DATE(check_in_time) = ?", parsedDate.Format("2006-01-02"))
+		query = query.Where("business_date = ?", parsedDate.Format("2006-01-02"))
 	}
 
 	// Filter by month (YYYY-MM format)
@@ -50,7 +50,7 @@ func (h *AttendanceHandler) GetAttendanceHistory(c *fiber.Ctx) error {
 		if err != nil {
 			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid month format. Use YYYY-MM", err)
 		}
-		query = query.Where("TO_CHAR(check_in_time, 'YYYY-MM') = ?", month)
+		query = query.Where("TO_CHAR(business_date, 'YYYY-MM') = ?", month)
 	}
 
 	// Filter by year
@@ -59,7 +59,7 @@ func (h *AttendanceHandler) GetAttendanceHistory(c *fiber.Ctx) error {
 		if err != nil || yearInt < 2000 || yearInt > 3000 {
 			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid year format", err)
 		}
-		query = query.Where("EXTRACT(YEAR FROM check_in_time) = ?", yearInt)
+		query = query.Where("EXTRACT(YEAR FROM business_date) = ?", yearInt)
 	}
 
 	// Get total count for pagination
@@ -73,27 +73,13 @@ func (h *AttendanceHandler) GetAttendanceHistory(c *fiber.Ctx) error {
 	}
 
 	// Transform data to include calculated fields
+	schedule := models.LoadShiftSchedule(h.db, attendances)
 	var records []map[string]interface{}
 	for _, att := range attendances {
-		// Calculate working hours
-		workingHours := 0.0
-		if att.CheckOutTime != nil {
-			workingHours = att.CheckOutTime.Sub(att.CheckInTime).Hours()
-		}
-
-		// Determine status
-		status := "present"
-		checkInTime := att.CheckInTime
-		
-		// Check if late (after 9 AM)
-		if checkInTime.Hour() > 9 || (checkInTime.Hour() == 9 && checkInTime.Minute() > 0) {
-			status = "late"
-		}
-		
-		// Check if incomplete (not checked out or working hours < 8)
-		if att.CheckOutTime == nil || workingHours < 8 {
-			status = "incomplete"
-		}
+		// Status is measured against the employee's shift for the day
+		eval := schedule.Evaluate(&att)
+		workingHours := eval.WorkingHours
+		status := eval.Status
 
 		// Apply status filter if specified
 		if c.Query("status") != "" && status != c.Query("status") {
@@ -109,8 +95,13 @@ func (h *AttendanceHandler) GetAttendanceHistory(c *fiber.Ctx) error {
 			"check_out_time":         att.CheckOutTime,
 			"working_hours":          workingHours,
 			"status":                 status,
+			"shift":                  eval.Shift.Name,
+			"late_minutes":           eval.LateMinutes,
+			"early_leave_minutes":    eval.EarlyLeaveMinutes,
+			"overtime_minutes":       eval.OvertimeMinutes,
 			"notes":                  att.Notes,
-			"date":                   att.CheckInTime.Format("2006-01-02"),
+			"date":                   att.WorkDate().Format("2006-01-02"),
+			"flagged":                att.Flagged,
 			"is_valid":               att.IsValid,
 			"distance":               att.Distance,
 			"address":                att.Address,
@@ -150,15 +141,17 @@ func (h *AttendanceHandler) GetAttendanceHistory(c *fiber.Ctx) error {
 
 // GetAttendanceStatsByPeriod returns attendance statistics for a specific period
 func (h *AttendanceHandler) GetAttendanceStatsByPeriod(c *fiber.Ctx) error {
-	userIDStr := c.Query("user_id", "")
+	userIDStr := scopedUserFilter(c, c.Query("user_id", ""))
 	month := c.Query("month", time.Now().Format("2006-01"))
 	year := c.Query("year", strconv.Itoa(time.Now().Year()))
 
 	query := h.db.Model(&models.Attendance{})
 
 	// Apply filters
+	userID := uuid.Nil
 	if userIDStr != "" {
-		userID, err := uuid.Parse(userIDStr)
+		var err error
+		userID, err = uuid.Parse(userIDStr)
 		if err != nil {
 			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID format", err)
 		}
@@ -167,13 +160,13 @@ func (h *AttendanceHandler) GetAttendanceStatsByPeriod(c *fiber.Ctx) error {
 
 	// Filter by month if provided
 	if month != "" {
-		query = query.Where("TO_CHAR(check_in_time, 'YYYY-MM') = ?", month)
+		query = query.Where("TO_CHAR(business_date, 'YYYY-MM') = ?", month)
 	} else if year != "" {
 		yearInt, err := strconv.Atoi(year)
 		if err != nil {
 			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid year format", err)
 		}
-		query = query.Where("EXTRACT(YEAR FROM check_in_time) = ?", yearInt)
+		query = query.Where("EXTRACT(YEAR FROM business_date) = ?", yearInt)
 	}
 
 	// Get all records for calculation
@@ -186,25 +179,25 @@ func (h *AttendanceHandler) GetAttendanceStatsByPeriod(c *fiber.Ctx) error {
 	totalDays := len(attendances)
 	presentDays := 0
 	lateDays := 0
+	earlyLeaveDays := 0
 	incompleteDays := 0
 	totalWorkingHours := 0.0
+	overtimeMinutes := 0
 
+	schedule := models.LoadShiftSchedule(h.db, attendances)
 	for _, att := range attendances {
-		workingHours := 0.0
-		if att.CheckOutTime != nil {
-			workingHours = att.CheckOutTime.Sub(att.CheckInTime).Hours()
-			totalWorkingHours += workingHours
-		}
-
-		checkInTime := att.CheckInTime
-		isLate := checkInTime.Hour() > 9 || (checkInTime.Hour() == 9 && checkInTime.Minute() > 0)
-		isIncomplete := att.CheckOutTime == nil || workingHours < 8
+		eval := schedule.Evaluate(&att)
+		totalWorkingHours += eval.WorkingHours
+		overtimeMinutes += eval.OvertimeMinutes
 
-		if isIncomplete {
+		switch eval.Status {
+		case models.AttendanceStatusIncomplete:
 			incompleteDays++
-		} else if isLate {
+		case models.AttendanceStatusLate:
 			lateDays++
-		} else {
+		case models.AttendanceStatusEarlyLeave:
+			earlyLeaveDays++
+		default:
 			presentDays++
 		}
 	}
@@ -215,29 +208,64 @@ func (h *AttendanceHandler) GetAttendanceStatsByPeriod(c *fiber.Ctx) error {
 		averageWorkingHours = totalWorkingHours / float64(totalDays)
 	}
 
-	attendanceRate := 0.0
-	if totalDays > 0 {
-		attendanceRate = float64(presentDays+lateDays) / float64(totalDays) * 100
-	}
+	// Absences and the attendance rate follow the work calendar
+	summary := h.summarizePeriod(userID, month, year)
 
 	stats := map[string]interface{}{
 		"total_days":            totalDays,
 		"present_days":          presentDays,
 		"late_days":             lateDays,
+		"early_leave_days":      earlyLeaveDays,
 		"incomplete_days":       incompleteDays,
+		"working_days":          summary.WorkingDays,
+		"holiday_days":          summary.HolidayDays,
+		"absent_days":           summary.AbsentDays,
+		"leave_days":            summary.LeaveDays,
+		"overtime_hours":        float64(overtimeMinutes) / 60,
 		"total_working_hours":   totalWorkingHours,
 		"average_working_hours": averageWorkingHours,
-		"attendance_rate":       attendanceRate,
+		"attendance_rate":       summary.AttendanceRate(),
 		"period":                map[string]string{"month": month, "year": year},
 	}
 
 	return utils.SuccessResponse(c, "Attendance statistics retrieved successfully", stats)
 }
 
+// summarizePeriod compares attendance with the work calendar for a YYYY-MM month, or for
+// the year when no month is given. Without a user it totals every employee who checks in.
+func (h *AttendanceHandler) summarizePeriod(userID uuid.UUID, month, year string) models.AttendanceSummary {
+	var from, to time.Time
+	if start, err := time.Parse("2006-01", month); err == nil {
+		from, to = start, start.AddDate(0, 1, -1)
+	} else if yearInt, err := strconv.Atoi(year); err == nil {
+		from = time.Date(yearInt, time.January, 1, 0, 0, 0, 0, time.UTC)
+		to = from.AddDate(1, 0, -1)
+	} else {
+		return models.AttendanceSummary{}
+	}
+
+	now := time.Now()
+	if userID != uuid.Nil {
+		return models.SummarizeAttendance(h.db, userID, from, to, now)
+	}
+
+	var users []models.User
+	h.db.Preload("Role").Where("is_active = ?", true).Find(&users)
+
+	var total models.AttendanceSummary
+	for i := range users {
+		if granted, _ := users[i].Role.Grants("attendance.write"); !granted || users[i].Role.HasAdminRights() {
+			continue
+		}
+		total.Add(models.SummarizeAttendance(h.db, users[i].ID, from, to, now))
+	}
+	return total
+}
+
 // ExportAttendanceHistory exports attendance data to CSV format
 func (h *AttendanceHandler) ExportAttendanceHistory(c *fiber.Ctx) error {
 	// Get the same filters as GetAttendanceHistory
-	userIDStr := c.Query("user_id", "")
+	userIDStr := scopedUserFilter(c, c.Query("user_id", ""))
 	month := c.Query("month", "")
 	date := c.Query("date", "")
 	year := c.Query("year", "")
@@ -259,11 +287,11 @@ func (h *AttendanceHandler) ExportAttendanceHistory(c *fiber.Ctx) error {
 		if err != nil {
 			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format", err)
 		}
-		query = query.Where("DATE(check_in_time) = ?", parsedDate.Format("2006-01-02"))
+		query = query.Where("business_date = ?", parsedDate.Format("2006-01-02"))
 	}
 
 	if month != "" {
-		query = query.Where("TO_CHAR(check_in_time, 'YYYY-MM') = ?", month)
+		query = query.Where("TO_CHAR(business_date, 'YYYY-MM') = ?", month)
 	}
 
 	if year != "" {
@@ -271,7 +299,7 @@ func (h *AttendanceHandler) ExportAttendanceHistory(c *fiber.Ctx) error {
 		if err != nil {
 			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid year format", err)
 		}
-		query = query.Where("EXTRACT(YEAR FROM check_in_time) = ?", yearInt)
+		query = query.Where("EXTRACT(YEAR FROM business_date) = ?", yearInt)
 	}
 
 	// Get all records (no pagination for export)
@@ -285,22 +313,13 @@ func (h *AttendanceHandler) ExportAttendanceHistory(c *fiber.Ctx) error {
 	c.Set("Content-Disposition", "attachment; filename=attendance_history.csv")
 
 	// Create CSV content
-	csvContent := "Name,Email,Date,Check In,Check Out,Working Hours,Status,Notes\n"
+	csvContent := "Name,Email,Date,Shift,Check In,Check Out,Working Hours,Status,Late Minutes,Overtime Minutes,Notes\n"
 
+	schedule := models.LoadShiftSchedule(h.db, attendances)
 	for _, att := range attendances {
-		workingHours := 0.0
-		if att.CheckOutTime != nil {
-			workingHours = att.CheckOutTime.Sub(att.CheckInTime).Hours()
-		}
-
-		status := "present"
-		checkInTime := att.CheckInTime
-		if checkInTime.Hour() > 9 || (checkInTime.Hour() == 9 && checkInTime.Minute() > 0) {
-			status = "late"
-		}
-		if att.CheckOutTime == nil || workingHours < 8 {
-			status = "incomplete"
-		}
+		eval := schedule.Evaluate(&att)
+		workingHours := eval.WorkingHours
+		status := eval.Status
 
 		// Apply status filter if specified
 		if c.Query("status") != "" && status != c.Query("status") {
@@ -309,19 +328,22 @@ func (h *AttendanceHandler) ExportAttendanceHistory(c *fiber.Ctx) error {
 
 		checkOutTime := "-"
 		if att.CheckOutTime != nil {
-			checkOutTime = att.CheckOutTime.Format("15:04")
+			checkOutTime = att.CheckOutTime.In(att.TimeLocation()).Format("15:04")
 		}
 
 		notes := att.Notes
 
-		csvContent += fmt.Sprintf("%s,%s,%s,%s,%s,%.2f,%s,\"%s\"\n",
+		csvContent += fmt.Sprintf("%s,%s,%s,%s,%s,%s,%.2f,%s,%d,%d,\"%s\"\n",
 			att.User.Name,
 			att.User.Email,
-			att.CheckInTime.Format("2006-01-02"),
-			att.CheckInTime.Format("15:04"),
+			att.WorkDate().Format("2006-01-02"),
+			eval.Shift.Name,
+			att.CheckInTime.In(att.TimeLocation()).Format("15:04"),
 			checkOutTime,
 			workingHours,
 			status,
+			eval.LateMinutes,
+			eval.OvertimeMinutes,
 			notes,
 		)
 	}
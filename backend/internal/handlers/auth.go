@@ -1,6 +1,10 @@
 package handlers
 
 import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
 	"time"
 
 	"cybercafe-backend/internal/config"
@@ -10,6 +14,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AuthHandler struct {
@@ -27,8 +32,78 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	Token string                `json:"token"`
-	User  models.UserResponse   `json:"user"`
+	Token            string              `json:"token"`
+	ExpiresAt        time.Time           `json:"expires_at"`
+	RefreshToken     string              `json:"refresh_token"`
+	RefreshExpiresAt time.Time           `json:"refresh_expires_at"`
+	SessionID        uuid.UUID           `json:"session_id"`
+	User             models.UserResponse `json:"user"`
+
+	TwoFactorSetupRequired bool `json:"two_factor_setup_required,omitempty"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// startSession records a device session for a completed login and issues its tokens
+func (h *AuthHandler) startSession(c *fiber.Ctx, user models.User) (LoginResponse, error) {
+	session, err := models.StartDeviceSession(h.db, user.ID, c.IP(), c.Get("User-Agent"))
+	if err != nil {
+		return LoginResponse{}, err
+	}
+	response, _, err := h.issueTokens(h.db, user, session.ID)
+	return response, err
+}
+
+// issueTokens creates a short-lived access token and a persisted refresh token for a
+// device session. The refresh token row is written with db so rotation can run inside
+// a transaction.
+func (h *AuthHandler) issueTokens(db *gorm.DB, user models.User, sessionID uuid.UUID) (LoginResponse, *models.RefreshToken, error) {
+	now := time.Now()
+
+	expireDuration, err := time.ParseDuration(h.cfg.JWTExpire)
+	if err != nil {
+		expireDuration = 15 * time.Minute
+	}
+	accessToken, err := utils.GenerateToken(user.ID, sessionID, user.Username, user.Role.Name, h.cfg.JWTSecret, expireDuration)
+	if err != nil {
+		return LoginResponse{}, nil, err
+	}
+
+	refreshDuration, err := time.ParseDuration(h.cfg.RefreshTokenExpire)
+	if err != nil {
+		refreshDuration = 7 * 24 * time.Hour
+	}
+	rawRefreshToken, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return LoginResponse{}, nil, err
+	}
+
+	refreshToken := models.RefreshToken{
+		UserID:    user.ID,
+		SessionID: &sessionID,
+		TokenHash: utils.HashToken(rawRefreshToken),
+		ExpiresAt: now.Add(refreshDuration),
+	}
+	if err := db.Create(&refreshToken).Error; err != nil {
+		return LoginResponse{}, nil, err
+	}
+
+	return LoginResponse{
+		Token:            accessToken,
+		ExpiresAt:        now.Add(expireDuration),
+		RefreshToken:     rawRefreshToken,
+		RefreshExpiresAt: refreshToken.ExpiresAt,
+		SessionID:        sessionID,
+		User:             user.ToResponse(),
+
+		TwoFactorSetupRequired: !user.TOTPEnabled && h.cfg.IsTwoFactorRequiredForAdmins() && user.Role.HasAdminRights(),
+	}, &refreshToken, nil
 }
 
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
@@ -37,48 +112,47 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
 	}
 
+	now := time.Now()
+	limits := h.cfg.GetLoginLimits()
+	ipAddress := c.IP()
+	userAgent := c.Get("User-Agent")
+
+	// Refuse attempts while the username or the client is locked out or still in its delay
+	usernameThrottle := models.GetLoginThrottle(h.db, models.LoginScopeUsername, req.Username)
+	ipThrottle := models.GetLoginThrottle(h.db, models.LoginScopeIP, ipAddress)
+	wait := usernameThrottle.RetryAfter(now, limits)
+	if ipWait := ipThrottle.RetryAfter(now, limits); ipWait > wait {
+		wait = ipWait
+	}
+	if wait > 0 {
+		return loginThrottled(c, wait, usernameThrottle.IsLocked(now) || ipThrottle.IsLocked(now))
+	}
+
 	var user models.User
 	if err := h.db.Preload("Role").Where("username = ? AND is_active = ?", req.Username, true).First(&user).Error; err != nil {
-		// Log failed login attempt
-		go func() {
-			auditLog := models.AuditLog{
-				Action:    "LOGIN_FAILED",
-				Resource:  "/auth/login",
-				Details:   "Failed login attempt for username: " + req.Username,
-				IPAddress: c.IP(),
-				UserAgent: c.Get("User-Agent"),
-			}
-			h.db.Create(&auditLog)
-		}()
+		h.recordLoginFailure(req.Username, ipAddress, userAgent, nil, "Invalid password", now, limits)
 		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid credentials", nil)
 	}
 
 	if !user.CheckPassword(req.Password) {
-		// Log failed login attempt
-		go func() {
-			auditLog := models.AuditLog{
-				Action:    "LOGIN_FAILED",
-				Resource:  "/auth/login",
-				Details:   "Invalid password for username: " + req.Username,
-				IPAddress: c.IP(),
-				UserAgent: c.Get("User-Agent"),
-			}
-			h.db.Create(&auditLog)
-		}()
+		h.recordLoginFailure(req.Username, ipAddress, userAgent, &user, "Invalid password", now, limits)
 		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid credentials", nil)
 	}
 
-	// Generate JWT token
-	expireDuration, _ := time.ParseDuration(h.cfg.JWTExpire)
-	token, err := utils.GenerateToken(user.ID, user.Username, user.Role.Name, h.cfg.JWTSecret, expireDuration)
+	// With two-factor enabled the password only earns a pre-auth token for the second step
+	if user.TOTPEnabled {
+		return h.twoFactorChallenge(c, user)
+	}
+
+	// The IP counter is left alone so one valid login cannot reset guessing from a shared PC
+	models.ClearLoginThrottle(h.db, models.LoginScopeUsername, req.Username)
+
+	// Start a device session with access and refresh tokens
+	response, err := h.startSession(c, user)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate token", err)
 	}
 
-	// Capture values before goroutine to avoid context reuse issues
-	ipAddress := c.IP()
-	userAgent := c.Get("User-Agent")
-
 	// Log successful login
 	go func() {
 		auditLog := models.AuditLog{
@@ -92,12 +166,165 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		h.db.Create(&auditLog)
 	}()
 
-	response := LoginResponse{
-		Token: token,
-		User:  user.ToResponse(),
+	return utils.SuccessResponse(c, "Login successful", response)
+}
+
+// recordLoginFailure counts a failed login against the username and the client IP and
+// writes the audit trail, including an ACCOUNT_LOCKED entry when a staff account locks.
+func (h *AuthHandler) recordLoginFailure(username, ipAddress, userAgent string, user *models.User, reason string, now time.Time, limits models.LoginLimits) {
+	usernameThrottle, err := models.RecordLoginFailure(h.db, models.LoginScopeUsername, username, now, limits)
+	if err != nil {
+		log.Printf("[LOGIN] Failed to record failed login for %s: %v", username, err)
+	}
+	ipThrottle, err := models.RecordLoginFailure(h.db, models.LoginScopeIP, ipAddress, now, limits)
+	if err != nil {
+		log.Printf("[LOGIN] Failed to record failed login from %s: %v", ipAddress, err)
 	}
 
-	return utils.SuccessResponse(c, "Login successful", response)
+	if ipThrottle.IsLocked(now) && ipThrottle.FailedCount == limits.MaxAttemptsPerIP {
+		log.Printf("[LOGIN] Client %s locked out until %s", ipAddress, ipThrottle.LockedUntil.Format(time.RFC3339))
+	}
+
+	// Audit rows reference a user, so attempts on unknown usernames are only logged
+	if user == nil {
+		log.Printf("[LOGIN] Failed login for unknown username %q from %s", username, ipAddress)
+		return
+	}
+
+	auditLogs := []models.AuditLog{{
+		UserID:    user.ID,
+		Action:    "LOGIN_FAILED",
+		Resource:  "/auth/login",
+		Details:   fmt.Sprintf("%s for username: %s (%d failed attempts)", reason, username, usernameThrottle.FailedCount),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}}
+	if usernameThrottle.IsLocked(now) && usernameThrottle.FailedCount == limits.MaxAttempts {
+		auditLogs = append(auditLogs, models.AuditLog{
+			UserID:    user.ID,
+			Action:    "ACCOUNT_LOCKED",
+			Resource:  "/auth/login",
+			Details:   "Account locked until " + usernameThrottle.LockedUntil.Format(time.RFC3339) + " after repeated failed logins",
+			IPAddress: ipAddress,
+			UserAgent: userAgent,
+		})
+	}
+	h.db.Create(&auditLogs)
+}
+
+// loginThrottled rejects a login attempt with the number of seconds to wait
+func loginThrottled(c *fiber.Ctx, wait time.Duration, locked bool) error {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+
+	message := "Too many login attempts, please wait before trying again"
+	if locked {
+		message = "Too many failed login attempts, login is temporarily locked"
+	}
+	return c.Status(fiber.StatusTooManyRequests).JSON(utils.Response{
+		Success: false,
+		Message: message,
+		Data: fiber.Map{
+			"locked":      locked,
+			"retry_after": seconds,
+		},
+	})
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a new refresh token.
+// Each refresh token works once; presenting one that was already rotated revokes every
+// token of the user, since it means the token was copied.
+func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
+	var req RefreshTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+	if req.RefreshToken == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Refresh token is required", nil)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var stored models.RefreshToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", utils.HashToken(req.RefreshToken)).First(&stored).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid refresh token", nil)
+	}
+
+	now := time.Now()
+	if stored.ReplacedByID != nil {
+		if err := models.RevokeAllUserTokens(tx, stored.UserID); err != nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke tokens", err)
+		}
+		auditLog := models.AuditLog{
+			UserID:    stored.UserID,
+			Action:    "REFRESH_TOKEN_REUSE",
+			Resource:  "/auth/refresh",
+			Details:   "Rotated refresh token was reused; all sessions revoked",
+			IPAddress: c.IP(),
+			UserAgent: c.Get("User-Agent"),
+		}
+		tx.Create(&auditLog)
+		tx.Commit()
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Refresh token has already been used", nil)
+	}
+	if !stored.IsActive(now) {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Refresh token has expired or been revoked", nil)
+	}
+
+	var user models.User
+	if err := tx.Preload("Role").Where("id = ? AND is_active = ?", stored.UserID, true).First(&user).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User is not active", nil)
+	}
+
+	// Refresh tokens issued before device sessions existed get a session on first use
+	var sessionID uuid.UUID
+	if stored.SessionID != nil {
+		sessionID = *stored.SessionID
+		if err := tx.Model(&models.DeviceSession{}).Where("id = ?", sessionID).Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"ip_address":   c.IP(),
+		}).Error; err != nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to rotate refresh token", err)
+		}
+	} else {
+		session, err := models.StartDeviceSession(tx, user.ID, c.IP(), c.Get("User-Agent"))
+		if err != nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to rotate refresh token", err)
+		}
+		sessionID = session.ID
+	}
+
+	response, replacement, err := h.issueTokens(tx, user, sessionID)
+	if err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate token", err)
+	}
+
+	if err := tx.Model(&stored).Updates(map[string]interface{}{
+		"revoked_at":     now,
+		"replaced_by_id": replacement.ID,
+	}).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to rotate refresh token", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to rotate refresh token", err)
+	}
+
+	return utils.SuccessResponse(c, "Token refreshed successfully", response)
 }
 
 func (h *AuthHandler) GetProfile(c *fiber.Ctx) error {
@@ -113,7 +340,7 @@ func (h *AuthHandler) GetProfile(c *fiber.Ctx) error {
 
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" validate:"required"`
-	NewPassword     string `json:"new_password" validate:"required,min=6"`
+	NewPassword     string `json:"new_password" validate:"required"`
 }
 
 func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
@@ -133,15 +360,127 @@ func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Current password is incorrect", nil)
 	}
 
+	if req.NewPassword == req.CurrentPassword {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "New password must differ from the current password", nil)
+	}
+	if err := h.cfg.GetPasswordPolicy().Validate(req.NewPassword); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	if err := user.SetPassword(req.NewPassword); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to hash password", err)
+	}
+	user.MustChangePassword = false
+
+	if err := h.db.Omit(clause.Associations).Save(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update password", err)
+	}
+
+	// Sign out every session that used the old password
+	if err := models.RevokeAllUserTokens(h.db, user.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke existing sessions", err)
+	}
+
+	// The current session was revoked too, so hand back fresh tokens
+	h.db.Preload("Role").First(&user, "id = ?", user.ID)
+	response, err := h.startSession(c, user)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate token", err)
+	}
+
+	return utils.SuccessResponse(c, "Password changed successfully", response)
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+// ResetPassword sets a new password using a one-time token issued by an admin
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+	if req.Token == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Reset token is required", nil)
+	}
+	if err := h.cfg.GetPasswordPolicy().Validate(req.NewPassword); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var resetToken models.PasswordResetToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ?", utils.HashToken(req.Token)).First(&resetToken).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid or expired reset token", nil)
+	}
+
+	now := time.Now()
+	if resetToken.UsedAt != nil || now.After(resetToken.ExpiresAt) {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid or expired reset token", nil)
+	}
+
+	var user models.User
+	if err := tx.Where("id = ? AND is_active = ?", resetToken.UserID, true).First(&user).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid or expired reset token", nil)
+	}
+
 	if err := user.SetPassword(req.NewPassword); err != nil {
+		tx.Rollback()
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to hash password", err)
 	}
 
-	if err := h.db.Save(&user).Error; err != nil {
+	if err := tx.Model(&user).Updates(map[string]interface{}{
+		"password":             user.Password,
+		"must_change_password": false,
+	}).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update password", err)
+	}
+
+	if err := tx.Model(&resetToken).Update("used_at", now).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update password", err)
+	}
+
+	if err := models.RevokeAllUserTokens(tx, user.ID); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke existing sessions", err)
+	}
+
+	auditLog := models.AuditLog{
+		UserID:    user.ID,
+		Action:    "PASSWORD_RESET",
+		Resource:  "/auth/reset-password",
+		Details:   "Password reset with admin-issued token",
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+	}
+	if err := tx.Create(&auditLog).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update password", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update password", err)
 	}
 
-	return utils.SuccessResponse(c, "Password changed successfully", nil)
+	return utils.SuccessResponse(c, "Password reset successfully", nil)
+}
+
+// GetPasswordPolicy returns the rules new passwords must satisfy
+func (h *AuthHandler) GetPasswordPolicy(c *fiber.Ctx) error {
+	return utils.SuccessResponse(c, "Password policy retrieved successfully", h.cfg.GetPasswordPolicy())
 }
 
 func (h *AuthHandler) Logout(c *fiber.Ctx) error {
@@ -150,6 +489,36 @@ func (h *AuthHandler) Logout(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not authenticated", nil)
 	}
 
+	// Revoke the access token used for this request and, if given, the refresh token
+	if tokenID, ok := c.Locals("token_id").(string); ok && tokenID != "" {
+		expiresAt, _ := c.Locals("token_expires_at").(time.Time)
+		revoked := models.RevokedToken{
+			TokenID:   tokenID,
+			UserID:    c.Locals("user_id").(uuid.UUID),
+			ExpiresAt: expiresAt,
+		}
+		if err := h.db.Create(&revoked).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke token", err)
+		}
+		// Entries past their token's expiry are no longer needed
+		h.db.Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{})
+	}
+
+	// End the device session so its other tokens stop working too
+	if sessionID, err := uuid.Parse(c.Locals("session_id").(string)); err == nil {
+		userUUID := c.Locals("user_id").(uuid.UUID)
+		if err := models.RevokeDeviceSession(h.db, sessionID, &userUUID); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to end session", err)
+		}
+	}
+
+	var req LogoutRequest
+	if err := c.BodyParser(&req); err == nil && req.RefreshToken != "" {
+		h.db.Model(&models.RefreshToken{}).
+			Where("token_hash = ? AND user_id = ? AND revoked_at IS NULL", utils.HashToken(req.RefreshToken), c.Locals("user_id")).
+			Update("revoked_at", time.Now())
+	}
+
 	// Capture values before goroutine to avoid context reuse issues
 	ipAddress := c.IP()
 	userAgent := c.Get("User-Agent")
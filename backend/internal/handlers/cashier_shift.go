@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CashierShiftHandler struct {
+	db *gorm.DB
+}
+
+func NewCashierShiftHandler(db *gorm.DB) *CashierShiftHandler {
+	return &CashierShiftHandler{db: db}
+}
+
+type OpenShiftRequest struct {
+	OpeningFloat int64  `json:"opening_float"`
+	Notes        string `json:"notes"`
+}
+
+type CloseShiftRequest struct {
+	CountedCash    *int64 `json:"counted_cash" validate:"required"`
+	VarianceReason string `json:"variance_reason"`
+	Notes          string `json:"notes"`
+}
+
+type ShiftResponse struct {
+	models.CashierShift
+	CashTaken int64 `json:"cash_taken"`
+}
+
+func (h *CashierShiftHandler) toShiftResponse(shift models.CashierShift) ShiftResponse {
+	response := ShiftResponse{
+		CashierShift: shift,
+		CashTaken:    models.GetShiftCashTotal(h.db, shift.ID),
+	}
+	// Open shifts report the running expectation; closed shifts keep the figure fixed at close
+	if shift.Status == models.ShiftStatusOpen {
+		response.ExpectedCash = shift.OpeningFloat + response.CashTaken
+	}
+	return response
+}
+
+// OpenShift starts a cash drawer shift for the current user. The user must be checked in;
+// the shift is linked to that attendance record.
+func (h *CashierShiftHandler) OpenShift(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req OpenShiftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if req.OpeningFloat < 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Opening float cannot be negative", nil)
+	}
+
+	if _, err := models.GetOpenShift(h.db, userID); err == nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "You already have an open shift", nil)
+	}
+
+	attendance, err := models.GetOpenAttendance(h.db, userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Check in before opening a cashier shift", err)
+	}
+
+	shift := models.CashierShift{
+		CashierID:    userID,
+		AttendanceID: &attendance.ID,
+		Status:       models.ShiftStatusOpen,
+		OpeningFloat: req.OpeningFloat,
+		OpenedAt:     time.Now(),
+		Notes:        req.Notes,
+	}
+
+	// The unique index on open shifts turns a concurrent second open into a no-op
+	result := h.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&shift)
+	if result.Error != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to open shift", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "You already have an open shift", nil)
+	}
+
+	h.db.Preload("Cashier").Preload("Attendance").First(&shift, "id = ?", shift.ID)
+
+	return utils.SuccessResponse(c, "Shift opened successfully", h.toShiftResponse(shift))
+}
+
+// GetCurrentShift returns the current user's open shift with its running cash total
+func (h *CashierShiftHandler) GetCurrentShift(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	shift, err := models.GetOpenShift(h.db.Preload("Attendance"), userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "No open shift", nil)
+	}
+
+	return utils.SuccessResponse(c, "Shift retrieved successfully", h.toShiftResponse(*shift))
+}
+
+// CloseShift closes a shift with the counted drawer cash and records the variance.
+// A reason is required whenever counted cash differs from expected.
+func (h *CashierShiftHandler) CloseShift(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	shiftID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid shift ID", err)
+	}
+
+	var req CloseShiftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if req.CountedCash == nil || *req.CountedCash < 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Counted cash is required", nil)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var shift models.CashierShift
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", shiftID).First(&shift).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Shift not found", err)
+	}
+
+	if !canAccessUser(c, shift.CashierID) {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You can only close your own shift", nil)
+	}
+	if shift.Status != models.ShiftStatusOpen {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Shift is already closed", nil)
+	}
+
+	expected := shift.OpeningFloat + models.GetShiftCashTotal(tx, shift.ID)
+	variance := *req.CountedCash - expected
+	if variance != 0 && req.VarianceReason == "" {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Reason is required when counted cash differs from expected", nil)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":          models.ShiftStatusClosed,
+		"closed_at":       now,
+		"closed_by":       userID,
+		"expected_cash":   expected,
+		"counted_cash":    *req.CountedCash,
+		"variance":        variance,
+		"variance_reason": req.VarianceReason,
+	}
+	if req.Notes != "" {
+		updates["notes"] = req.Notes
+	}
+
+	if err := tx.Model(&shift).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to close shift", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to close shift", err)
+	}
+
+	h.db.Preload("Cashier").Preload("Attendance").First(&shift, "id = ?", shift.ID)
+
+	return utils.SuccessResponse(c, "Shift closed successfully", h.toShiftResponse(shift))
+}
+
+// GetAllShifts lists shifts with the cashier and attendance record that held the drawer
+func (h *CashierShiftHandler) GetAllShifts(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	status := c.Query("status", "")
+	cashierID := scopedUserFilter(c, c.Query("cashier_id", ""))
+	date := c.Query("date", "")
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.CashierShift{})
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if cashierID != "" {
+		query = query.Where("cashier_id = ?", cashierID)
+	}
+	if date != "" {
+		parsedDate, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+		}
+		query = query.Where("DATE(opened_at) = ?", parsedDate.Format("2006-01-02"))
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var shifts []models.CashierShift
+	if err := query.Preload("Cashier").Preload("Attendance").Order("opened_at DESC").
+		Offset(offset).Limit(limit).Find(&shifts).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch shifts", err)
+	}
+
+	responses := make([]ShiftResponse, 0, len(shifts))
+	for _, shift := range shifts {
+		responses = append(responses, h.toShiftResponse(shift))
+	}
+
+	meta := utils.PaginationMeta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+
+	return utils.PaginatedSuccessResponse(c, "Shifts retrieved successfully", responses, meta)
+}
+
+// GetShiftByID retrieves a shift with the cash transactions recorded during it
+func (h *CashierShiftHandler) GetShiftByID(c *fiber.Ctx) error {
+	shiftID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid shift ID", err)
+	}
+
+	var shift models.CashierShift
+	if err := h.db.Preload("Cashier").Preload("Attendance").Where("id = ?", shiftID).First(&shift).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Shift not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch shift", err)
+	}
+
+	if !canAccessUser(c, shift.CashierID) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You can only view your own shifts", nil)
+	}
+
+	var transactions []models.Transaction
+	h.db.Where("shift_id = ?", shift.ID).Order("created_at ASC").Find(&transactions)
+
+	return utils.SuccessResponse(c, "Shift retrieved successfully", fiber.Map{
+		"shift":        h.toShiftResponse(shift),
+		"transactions": transactions,
+	})
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/realtime"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ComputerHandler struct {
+	db  *gorm.DB
+	hub *realtime.Hub
+}
+
+func NewComputerHandler(db *gorm.DB, hub *realtime.Hub) *ComputerHandler {
+	return &ComputerHandler{db: db, hub: hub}
+}
+
+type CreateComputerRequest struct {
+	Name            string     `json:"name" validate:"required"`
+	Specs           string     `json:"specs"`
+	IPAddress       string     `json:"ip_address"`
+	Zone            string     `json:"zone"`
+	Status          string     `json:"status"`
+	LastMaintenance *time.Time `json:"last_maintenance"`
+	Notes           string     `json:"notes"`
+}
+
+type UpdateComputerRequest struct {
+	Name            string     `json:"name"`
+	Specs           string     `json:"specs"`
+	IPAddress       string     `json:"ip_address"`
+	Zone            string     `json:"zone"`
+	Status          string     `json:"status"`
+	LastMaintenance *time.Time `json:"last_maintenance"`
+	Notes           *string    `json:"notes"`
+}
+
+// CreateComputer registers a new computer station
+func (h *ComputerHandler) CreateComputer(c *fiber.Ctx) error {
+	var req CreateComputerRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if req.Name == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Computer name is required", nil)
+	}
+
+	if req.Status == "" {
+		req.Status = models.ComputerStatusAvailable
+	}
+	if !models.IsValidComputerStatus(req.Status) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid computer status", nil)
+	}
+
+	// Check if computer name already exists
+	var existingComputer models.Computer
+	if err := h.db.Where("name = ?", req.Name).First(&existingComputer).Error; err == nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Computer name already exists", nil)
+	}
+
+	computer := models.Computer{
+		Name:            req.Name,
+		Specs:           req.Specs,
+		IPAddress:       req.IPAddress,
+		Zone:            req.Zone,
+		Status:          req.Status,
+		LastMaintenance: req.LastMaintenance,
+		Notes:           req.Notes,
+	}
+
+	if err := h.db.Create(&computer).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create computer", err)
+	}
+
+	h.hub.Publish(realtime.TopicComputers, "computer.created", computer)
+
+	return utils.SuccessResponse(c, "Computer created successfully", computer)
+}
+
+// GetAllComputers retrieves all computers with optional status and zone filters
+func (h *ComputerHandler) GetAllComputers(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	status := c.Query("status", "")
+	zone := c.Query("zone", "")
+	search := c.Query("search", "")
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.Computer{})
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if zone != "" {
+		query = query.Where("zone = ?", zone)
+	}
+	if search != "" {
+		query = query.Where("name LIKE ? OR ip_address LIKE ?", "%"+search+"%", "%"+search+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var computers []models.Computer
+	if err := query.Order("name ASC").Offset(offset).Limit(limit).Find(&computers).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch computers", err)
+	}
+
+	meta := utils.PaginationMeta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+
+	return utils.PaginatedSuccessResponse(c, "Computers retrieved successfully", computers, meta)
+}
+
+// GetComputerByID retrieves a computer by ID
+func (h *ComputerHandler) GetComputerByID(c *fiber.Ctx) error {
+	computerID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid computer ID", err)
+	}
+
+	var computer models.Computer
+	if err := h.db.Where("id = ?", computerID).First(&computer).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Computer not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch computer", err)
+	}
+
+	return utils.SuccessResponse(c, "Computer retrieved successfully", computer)
+}
+
+// UpdateComputer updates a computer's details or status
+func (h *ComputerHandler) UpdateComputer(c *fiber.Ctx) error {
+	computerID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid computer ID", err)
+	}
+
+	var req UpdateComputerRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	var computer models.Computer
+	if err := h.db.Where("id = ?", computerID).First(&computer).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Computer not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch computer", err)
+	}
+
+	if req.Name != "" && req.Name != computer.Name {
+		var existingComputer models.Computer
+		if err := h.db.Where("name = ? AND id <> ?", req.Name, computer.ID).First(&existingComputer).Error; err == nil {
+			return utils.ErrorResponse(c, fiber.StatusConflict, "Computer name already exists", nil)
+		}
+		computer.Name = req.Name
+	}
+	previousStatus := computer.Status
+	if req.Status != "" {
+		if !models.IsValidComputerStatus(req.Status) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid computer status", nil)
+		}
+		computer.Status = req.Status
+	}
+	if req.Specs != "" {
+		computer.Specs = req.Specs
+	}
+	if req.IPAddress != "" {
+		computer.IPAddress = req.IPAddress
+	}
+	if req.Zone != "" {
+		computer.Zone = req.Zone
+	}
+	if req.LastMaintenance != nil {
+		computer.LastMaintenance = req.LastMaintenance
+	}
+	if req.Notes != nil {
+		computer.Notes = *req.Notes
+	}
+
+	if err := h.db.Save(&computer).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update computer", err)
+	}
+
+	if computer.Status != previousStatus {
+		publishComputerStatus(h.hub, computer)
+	} else {
+		h.hub.Publish(realtime.TopicComputers, "computer.updated", computer)
+	}
+
+	return utils.SuccessResponse(c, "Computer updated successfully", computer)
+}
+
+// DeleteComputer deletes a computer
+func (h *ComputerHandler) DeleteComputer(c *fiber.Ctx) error {
+	computerID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid computer ID", err)
+	}
+
+	var computer models.Computer
+	if err := h.db.Where("id = ?", computerID).First(&computer).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Computer not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch computer", err)
+	}
+
+	if computer.Status == models.ComputerStatusInUse {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Cannot delete a computer that is in use", nil)
+	}
+
+	if err := h.db.Delete(&computer).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete computer", err)
+	}
+
+	h.hub.Publish(realtime.TopicComputers, "computer.deleted", fiber.Map{"id": computer.ID, "name": computer.Name})
+
+	return utils.SuccessResponse(c, "Computer deleted successfully", nil)
+}
+
+// publishComputerStatus notifies stream subscribers that a computer changed status
+func publishComputerStatus(hub *realtime.Hub, computer models.Computer) {
+	hub.Publish(realtime.TopicComputers, "computer.status_changed", fiber.Map{
+		"id":     computer.ID,
+		"name":   computer.Name,
+		"zone":   computer.Zone,
+		"status": computer.Status,
+	})
+}
@@ -0,0 +1,462 @@
+package handlers
+
+import (
+	"strconv"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CustomerHandler struct {
+	db *gorm.DB
+}
+
+func NewCustomerHandler(db *gorm.DB) *CustomerHandler {
+	return &CustomerHandler{db: db}
+}
+
+type CreateCustomerRequest struct {
+	Name           string `json:"name" validate:"required"`
+	Email          string `json:"email"`
+	Phone          string `json:"phone"`
+	Notes          string `json:"notes"`
+	PreferredGames string `json:"preferred_games"`
+}
+
+type UpdateCustomerRequest struct {
+	Name           string  `json:"name"`
+	Email          string  `json:"email"`
+	Phone          string  `json:"phone"`
+	Notes          *string `json:"notes"`
+	PreferredGames string  `json:"preferred_games"`
+	IsActive       *bool   `json:"is_active"`
+}
+
+type EarnPointsRequest struct {
+	SourceType string    `json:"source_type" validate:"required"` // session, order
+	SourceID   uuid.UUID `json:"source_id" validate:"required"`
+}
+
+type RedeemPointsRequest struct {
+	PackageID uuid.UUID `json:"package_id" validate:"required"`
+	Reason    string    `json:"reason"`
+}
+
+type AdjustPointsRequest struct {
+	Points int    `json:"points" validate:"required"`
+	Reason string `json:"reason" validate:"required"`
+}
+
+type CustomerResponse struct {
+	models.Customer
+	models.PointsSummary
+	MemberBalance int64 `json:"member_balance"`
+}
+
+func (h *CustomerHandler) toCustomerResponse(customer models.Customer) CustomerResponse {
+	return CustomerResponse{
+		Customer:      customer,
+		PointsSummary: models.GetPointsSummary(h.db, customer.ID),
+		MemberBalance: models.GetMemberBalance(h.db, customer.ID),
+	}
+}
+
+// CreateCustomer registers a new customer
+func (h *CustomerHandler) CreateCustomer(c *fiber.Ctx) error {
+	var req CreateCustomerRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if req.Name == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Customer name is required", nil)
+	}
+
+	if req.Phone != "" {
+		var existingCustomer models.Customer
+		if err := h.db.Where("phone = ?", req.Phone).First(&existingCustomer).Error; err == nil {
+			return utils.ErrorResponse(c, fiber.StatusConflict, "A customer with this phone number already exists", nil)
+		}
+	}
+
+	customer := models.Customer{
+		Name:           req.Name,
+		Email:          req.Email,
+		Phone:          req.Phone,
+		Notes:          req.Notes,
+		PreferredGames: req.PreferredGames,
+		IsActive:       true,
+	}
+
+	if err := h.db.Create(&customer).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create customer", err)
+	}
+
+	return utils.SuccessResponse(c, "Customer created successfully", h.toCustomerResponse(customer))
+}
+
+// GetAllCustomers retrieves customers with search and pagination
+func (h *CustomerHandler) GetAllCustomers(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	search := c.Query("search", "")
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.Customer{})
+	if search != "" {
+		query = query.Where("name LIKE ? OR email LIKE ? OR phone LIKE ?", "%"+search+"%", "%"+search+"%", "%"+search+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var customers []models.Customer
+	if err := query.Order("name ASC").Offset(offset).Limit(limit).Find(&customers).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch customers", err)
+	}
+
+	responses := make([]CustomerResponse, 0, len(customers))
+	for _, customer := range customers {
+		responses = append(responses, h.toCustomerResponse(customer))
+	}
+
+	meta := utils.PaginationMeta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+
+	return utils.PaginatedSuccessResponse(c, "Customers retrieved successfully", responses, meta)
+}
+
+// GetCustomerByID retrieves a customer by ID
+func (h *CustomerHandler) GetCustomerByID(c *fiber.Ctx) error {
+	customerID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid customer ID", err)
+	}
+
+	var customer models.Customer
+	if err := h.db.Where("id = ?", customerID).First(&customer).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Customer not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch customer", err)
+	}
+
+	return utils.SuccessResponse(c, "Customer retrieved successfully", h.toCustomerResponse(customer))
+}
+
+// UpdateCustomer updates a customer's profile. Points and tier are not editable here.
+func (h *CustomerHandler) UpdateCustomer(c *fiber.Ctx) error {
+	customerID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid customer ID", err)
+	}
+
+	var req UpdateCustomerRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	var customer models.Customer
+	if err := h.db.Where("id = ?", customerID).First(&customer).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Customer not found", err)
+	}
+
+	if req.Name != "" {
+		customer.Name = req.Name
+	}
+	if req.Email != "" {
+		customer.Email = req.Email
+	}
+	if req.Phone != "" && req.Phone != customer.Phone {
+		var existingCustomer models.Customer
+		if err := h.db.Where("phone = ? AND id <> ?", req.Phone, customer.ID).First(&existingCustomer).Error; err == nil {
+			return utils.ErrorResponse(c, fiber.StatusConflict, "A customer with this phone number already exists", nil)
+		}
+		customer.Phone = req.Phone
+	}
+	if req.Notes != nil {
+		customer.Notes = *req.Notes
+	}
+	if req.PreferredGames != "" {
+		customer.PreferredGames = req.PreferredGames
+	}
+	if req.IsActive != nil {
+		customer.IsActive = *req.IsActive
+	}
+
+	if err := h.db.Save(&customer).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update customer", err)
+	}
+
+	return utils.SuccessResponse(c, "Customer updated successfully", h.toCustomerResponse(customer))
+}
+
+// DeleteCustomer deactivates a customer, keeping the points ledger intact
+func (h *CustomerHandler) DeleteCustomer(c *fiber.Ctx) error {
+	customerID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid customer ID", err)
+	}
+
+	var customer models.Customer
+	if err := h.db.Where("id = ?", customerID).First(&customer).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Customer not found", err)
+	}
+
+	// Soft delete by setting is_active to false
+	customer.IsActive = false
+	if err := h.db.Save(&customer).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete customer", err)
+	}
+
+	return utils.SuccessResponse(c, "Customer deleted successfully", nil)
+}
+
+// GetPointsHistory returns the customer's points ledger, newest first
+func (h *CustomerHandler) GetPointsHistory(c *fiber.Ctx) error {
+	customerID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid customer ID", err)
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.PointLedgerEntry{}).Where("customer_id = ?", customerID)
+	if entryType := c.Query("type", ""); entryType != "" {
+		query = query.Where("type = ?", entryType)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var entries []models.PointLedgerEntry
+	if err := query.Preload("Creator").Order("created_at DESC").Offset(offset).Limit(limit).Find(&entries).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch points history", err)
+	}
+
+	meta := utils.PaginationMeta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+
+	return utils.PaginatedSuccessResponse(c, "Points history retrieved successfully", entries, meta)
+}
+
+// EarnPoints credits points for a paid session or order of the customer. The amount is
+// taken from the session or order, not from the request.
+func (h *CustomerHandler) EarnPoints(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	customerID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid customer ID", err)
+	}
+
+	var req EarnPointsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if req.SourceType != models.PointSourceSession && req.SourceType != models.PointSourceOrder {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Source type must be session or order", nil)
+	}
+	if req.SourceID == uuid.Nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Source ID is required", nil)
+	}
+
+	var customer models.Customer
+	if err := h.db.Where("id = ? AND is_active = ?", customerID, true).First(&customer).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Customer not found", err)
+	}
+
+	var sourceCustomerID *uuid.UUID
+	var amount int64
+	switch req.SourceType {
+	case models.PointSourceSession:
+		var session models.UsageSession
+		if err := h.db.Where("id = ?", req.SourceID).First(&session).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found", err)
+		}
+		sourceCustomerID, amount = session.CustomerID, session.TotalAmount
+	case models.PointSourceOrder:
+		var order models.Order
+		if err := h.db.Where("id = ?", req.SourceID).First(&order).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Order not found", err)
+		}
+		sourceCustomerID, amount = order.CustomerID, order.Total
+	}
+
+	sale, err := models.GetPaidSale(h.db, req.SourceType, req.SourceID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Points can only be earned for a paid "+req.SourceType, nil)
+	}
+
+	// The source belongs to the customer when it was opened for them or paid as theirs
+	ownedBySource := sourceCustomerID != nil && *sourceCustomerID == customer.ID
+	paidByCustomer := sale.CustomerID != nil && *sale.CustomerID == customer.ID
+	if !ownedBySource && !paidByCustomer {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "This "+req.SourceType+" does not belong to the customer", nil)
+	}
+
+	entry, err := models.EarnPoints(h.db, customer.ID, sale, amount, userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to record earned points", err)
+	}
+	if entry == nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "No points earned: amount too small or source already credited", nil)
+	}
+
+	return utils.SuccessResponse(c, "Points earned successfully", fiber.Map{
+		"entry":    entry,
+		"customer": h.toCustomerResponse(customer),
+	})
+}
+
+// RedeemPoints deducts the points needed to pay for a time package
+func (h *CustomerHandler) RedeemPoints(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	customerID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid customer ID", err)
+	}
+
+	var req RedeemPointsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	var pkg models.TimePackage
+	if err := h.db.Where("id = ? AND is_active = ?", req.PackageID, true).First(&pkg).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid or inactive package", err)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock the customer row so concurrent redemptions cannot overdraw the balance
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND is_active = ?", customerID, true).First(&customer).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Customer not found", err)
+	}
+
+	cost := models.PointsForPrice(pkg.Price)
+	summary := models.GetPointsSummary(tx, customer.ID)
+	if summary.Balance < cost {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Insufficient points balance", nil)
+	}
+
+	entry := models.PointLedgerEntry{
+		CustomerID: customer.ID,
+		Type:       models.PointEntryRedeem,
+		Points:     -cost,
+		SourceType: models.PointSourcePackage,
+		SourceID:   &pkg.ID,
+		Reason:     req.Reason,
+		CreatedBy:  userID,
+	}
+	if entry.Reason == "" {
+		entry.Reason = "Redeemed for " + pkg.Name
+	}
+
+	if err := tx.Create(&entry).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to redeem points", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to redeem points", err)
+	}
+
+	return utils.SuccessResponse(c, "Points redeemed successfully", fiber.Map{
+		"entry":    entry,
+		"package":  pkg,
+		"customer": h.toCustomerResponse(customer),
+	})
+}
+
+// AdjustPoints records a manual correction to a customer's balance
+func (h *CustomerHandler) AdjustPoints(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	customerID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid customer ID", err)
+	}
+
+	var req AdjustPointsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if req.Points == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Points must not be zero", nil)
+	}
+	if req.Reason == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Reason is required for manual adjustments", nil)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", customerID).First(&customer).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Customer not found", err)
+	}
+
+	if req.Points < 0 {
+		summary := models.GetPointsSummary(tx, customer.ID)
+		if summary.Balance+req.Points < 0 {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Adjustment would make the balance negative", nil)
+		}
+	}
+
+	entry := models.PointLedgerEntry{
+		CustomerID: customer.ID,
+		Type:       models.PointEntryAdjust,
+		Points:     req.Points,
+		SourceType: models.PointSourceManual,
+		Reason:     req.Reason,
+		CreatedBy:  userID,
+	}
+
+	if err := tx.Create(&entry).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to adjust points", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to adjust points", err)
+	}
+
+	return utils.SuccessResponse(c, "Points adjusted successfully", fiber.Map{
+		"entry":    entry,
+		"customer": h.toCustomerResponse(customer),
+	})
+}
@@ -54,6 +54,8 @@ type MonthlyStatsData struct {
 	TotalWorkingDays int     `json:"total_working_days"`
 	PresentDays      int     `json:"present_days"`
 	AbsentDays       int     `json:"absent_days"`
+	LeaveDays        int     `json:"leave_days"` // approved leave, excused rather than absent
+	HolidayDays      int     `json:"holiday_days"`
 	LateDays         int     `json:"late_days"`
 	AttendanceRate   float64 `json:"attendance_rate"`
 	AverageWorkHours float64 `json:"average_work_hours"`
@@ -64,7 +66,8 @@ type RecentActivityData struct {
 	CheckIn     *time.Time `json:"check_in"`
 	CheckOut    *time.Time `json:"check_out"`
 	WorkHours   float64   `json:"work_hours"`
-	Status      string    `json:"status"`
+	Status      string    `json:"status"` // incomplete, late, early_leave or present against the day's shift
+	Shift       string    `json:"shift"`
 }
 
 // GetEmployeeDashboard returns comprehensive dashboard data for employee
@@ -97,10 +100,7 @@ func (h *DashboardHandler) GetEmployeeDashboard(c *fiber.Ctx) error {
 }
 
 func (h *DashboardHandler) getTodayAttendance(userID uuid.UUID) *TodayAttendanceData {
-	today := time.Now().Format("2006-01-02")
-	var attendance models.Attendance
-
-	err := h.db.Where("user_id = ? AND DATE(check_in_time) = ?", userID, today).First(&attendance).Error
+	attendance, err := models.GetCurrentAttendance(h.db, userID, time.Now())
 	if err != nil {
 		return &TodayAttendanceData{
 			CheckedIn:    false,
@@ -173,40 +173,40 @@ func (h *DashboardHandler) getMealAllowanceData(userID uuid.UUID, month, year in
 func (h *DashboardHandler) getMonthlyStats(userID uuid.UUID, month, year int) *MonthlyStatsData {
 	// Get all attendance for the month
 	var attendances []models.Attendance
-	h.db.Where("user_id = ? AND EXTRACT(MONTH FROM check_in_time) = ? AND EXTRACT(YEAR FROM check_in_time) = ?", 
+	h.db.Where("user_id = ? AND EXTRACT(MONTH FROM business_date) = ? AND EXTRACT(YEAR FROM business_date) = ?", 
 		userID, month, year).Find(&attendances)
 
 	presentDays := len(attendances)
 	lateDays := 0
 	totalWorkHours := 0.0
 
+	schedule := models.LoadShiftSchedule(h.db, attendances)
 	for _, att := range attendances {
-		// Check if late (after 9:00 AM)
-		if att.CheckInTime.Hour() > 9 || (att.CheckInTime.Hour() == 9 && att.CheckInTime.Minute() > 0) {
+		eval := schedule.Evaluate(&att)
+		if eval.IsLate {
 			lateDays++
 		}
-
-		// Calculate work hours
-		if att.CheckOutTime != nil {
-			totalWorkHours += att.CheckOutTime.Sub(att.CheckInTime).Hours()
-		}
+		totalWorkHours += eval.WorkingHours
 	}
 
-	// Calculate total working days in month (assuming 22 working days)
-	totalWorkingDays := 22
-	absentDays := totalWorkingDays - presentDays
-	attendanceRate := float64(presentDays) / float64(totalWorkingDays) * 100
+	// Working days, absences and the attendance rate come from the work calendar;
+	// approved leave is excused rather than absent
+	monthStart := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	summary := models.SummarizeAttendance(h.db, userID, monthStart, monthStart.AddDate(0, 1, -1), time.Now())
+
 	averageWorkHours := 0.0
 	if presentDays > 0 {
 		averageWorkHours = totalWorkHours / float64(presentDays)
 	}
 
 	return &MonthlyStatsData{
-		TotalWorkingDays: totalWorkingDays,
+		TotalWorkingDays: summary.WorkingDays,
 		PresentDays:      presentDays,
-		AbsentDays:       absentDays,
+		AbsentDays:       summary.AbsentDays,
+		LeaveDays:        summary.LeaveDays,
+		HolidayDays:      summary.HolidayDays,
 		LateDays:         lateDays,
-		AttendanceRate:   attendanceRate,
+		AttendanceRate:   summary.AttendanceRate(),
 		AverageWorkHours: averageWorkHours,
 	}
 }
@@ -219,30 +219,18 @@ func (h *DashboardHandler) getRecentActivities(userID uuid.UUID, days int) []Rec
 		Order("check_in_time DESC").Find(&attendances)
 
 	var activities []RecentActivityData
-	for _, att := range attendances {
-		workHours := 0.0
-		status := "present"
-
-		if att.CheckOutTime != nil {
-			workHours = att.CheckOutTime.Sub(att.CheckInTime).Hours()
-			if workHours < 8 {
-				status = "early_leave"
-			}
-		} else {
-			status = "not_checked_out"
-		}
-
-		// Check if late
-		if att.CheckInTime.Hour() > 9 || (att.CheckInTime.Hour() == 9 && att.CheckInTime.Minute() > 0) {
-			status = "late"
-		}
+	schedule := models.LoadShiftSchedule(h.db, attendances)
+	for i := range attendances {
+		att := &attendances[i]
+		eval := schedule.Evaluate(att)
 
 		activity := RecentActivityData{
-			Date:      time.Date(att.CheckInTime.Year(), att.CheckInTime.Month(), att.CheckInTime.Day(), 0, 0, 0, 0, time.Local),
+			Date:      att.WorkDate(),
 			CheckIn:   &att.CheckInTime,
 			CheckOut:  att.CheckOutTime,
-			WorkHours: workHours,
-			Status:    status,
+			WorkHours: eval.WorkingHours,
+			Status:    eval.Status,
+			Shift:     eval.Shift.Name,
 		}
 
 		activities = append(activities, activity)
@@ -260,11 +248,10 @@ func (h *DashboardHandler) GetEmployeeSummary(c *fiber.Ctx) error {
 
 	// Get today's attendance status
 	today := now.Format("2006-01-02")
-	var todayAttendance models.Attendance
 	checkedIn := false
 	checkedOut := false
 
-	if err := h.db.Where("user_id = ? AND DATE(check_in_time) = ?", userID, today).First(&todayAttendance).Error; err == nil {
+	if todayAttendance, err := models.GetCurrentAttendance(h.db, userID, now); err == nil {
 		checkedIn = true
 		checkedOut = todayAttendance.CheckOutTime != nil
 	}
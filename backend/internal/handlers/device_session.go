@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type DeviceSessionResponse struct {
+	models.DeviceSession
+	Current bool `json:"current"`
+}
+
+func toDeviceSessionResponses(sessions []models.DeviceSession, currentID string) []DeviceSessionResponse {
+	responses := make([]DeviceSessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, DeviceSessionResponse{
+			DeviceSession: session,
+			Current:       session.ID.String() == currentID,
+		})
+	}
+	return responses
+}
+
+// GetSessions lists the devices currently signed in to the user's account
+func (h *AuthHandler) GetSessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	sessions, err := models.GetActiveDeviceSessions(h.db, userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch sessions", err)
+	}
+
+	currentID, _ := c.Locals("session_id").(string)
+	return utils.SuccessResponse(c, "Sessions retrieved successfully", toDeviceSessionResponses(sessions, currentID))
+}
+
+// RevokeSession signs one of the user's devices out
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	sessionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID", err)
+	}
+
+	var session models.DeviceSession
+	if err := h.db.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found", nil)
+	}
+	if session.RevokedAt != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Session is already signed out", nil)
+	}
+
+	if err := models.RevokeDeviceSession(h.db, session.ID, &userID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke session", err)
+	}
+
+	auditLog := models.AuditLog{
+		UserID:    userID,
+		Action:    "SESSION_REVOKED",
+		Resource:  "/auth/sessions/" + session.ID.String(),
+		Details:   fmt.Sprintf("Signed out device %s (%s)", session.IPAddress, session.UserAgent),
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+	}
+	h.db.Create(&auditLog)
+
+	return utils.SuccessResponse(c, "Session revoked successfully", nil)
+}
+
+// GetStaffSessions lists the devices currently signed in to a staff account
+func (h *StaffHandler) GetStaffSessions(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID", err)
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Staff not found", err)
+	}
+
+	sessions, err := models.GetActiveDeviceSessions(h.db, user.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch sessions", err)
+	}
+
+	currentID, _ := c.Locals("session_id").(string)
+	return utils.SuccessResponse(c, "Sessions retrieved successfully", toDeviceSessionResponses(sessions, currentID))
+}
+
+// RevokeStaffSessions signs a staff member out of every device, for example when a
+// phone used for attendance is lost
+func (h *StaffHandler) RevokeStaffSessions(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uuid.UUID)
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID", err)
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Staff not found", err)
+	}
+
+	var active int64
+	h.db.Model(&models.DeviceSession{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&active)
+
+	if err := models.RevokeAllUserTokens(h.db, user.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke staff sessions", err)
+	}
+
+	auditLog := models.AuditLog{
+		UserID:    adminID,
+		Action:    "SESSIONS_REVOKED",
+		Resource:  "/staff/" + user.ID.String() + "/sessions",
+		Details:   fmt.Sprintf("Signed %s out of %d device(s)", user.Username, active),
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+	}
+	h.db.Create(&auditLog)
+
+	return utils.SuccessResponse(c, "Staff sessions revoked successfully", fiber.Map{
+		"revoked_sessions": active,
+	})
+}
@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxHolidaySpan limits how many days a single holiday entry or imported event may cover
+const maxHolidaySpan = 31
+
+type HolidayHandler struct {
+	db *gorm.DB
+}
+
+func NewHolidayHandler(db *gorm.DB) *HolidayHandler {
+	return &HolidayHandler{db: db}
+}
+
+type CreateHolidayRequest struct {
+	Date       string     `json:"date" validate:"required"` // YYYY-MM-DD
+	EndDate    string     `json:"end_date"`                 // YYYY-MM-DD, one entry is created per day
+	Name       string     `json:"name" validate:"required"`
+	Type       string     `json:"type" validate:"required"`
+	LocationID *uuid.UUID `json:"location_id"` // required for closures
+	Notes      string     `json:"notes"`
+}
+
+type UpdateHolidayRequest struct {
+	Date          *string    `json:"date"`
+	Name          *string    `json:"name"`
+	Type          *string    `json:"type"`
+	LocationID    *uuid.UUID `json:"location_id"`
+	ClearLocation bool       `json:"clear_location"` // make the holiday company-wide
+	Notes         *string    `json:"notes"`
+}
+
+// validateHoliday checks the type and that closures name the branch that is closed
+func (h *HolidayHandler) validateHoliday(holidayType string, locationID *uuid.UUID) (string, bool) {
+	if !models.IsValidHolidayType(holidayType) {
+		return "Type must be one of national, cuti_bersama, closure", false
+	}
+	if holidayType == models.HolidayClosure && locationID == nil {
+		return "A closure needs the location that is closed", false
+	}
+	if locationID != nil {
+		var location models.Location
+		if err := h.db.Where("id = ?", *locationID).First(&location).Error; err != nil {
+			return "Invalid location ID", false
+		}
+	}
+	return "", true
+}
+
+// GetHolidays lists the calendar for a year, optionally narrowed to a month, type or
+// location. Filtering by location includes the company-wide holidays.
+func (h *HolidayHandler) GetHolidays(c *fiber.Ctx) error {
+	year := c.QueryInt("year", time.Now().Year())
+	month := c.QueryInt("month", 0)
+	holidayType := c.Query("type", "")
+	locationID := c.Query("location_id", "")
+
+	query := h.db.Model(&models.Holiday{}).Where("EXTRACT(YEAR FROM date) = ?", year)
+
+	if month != 0 {
+		query = query.Where("EXTRACT(MONTH FROM date) = ?", month)
+	}
+	if holidayType != "" {
+		query = query.Where("type = ?", holidayType)
+	}
+	if locationID != "" {
+		parsedID, err := uuid.Parse(locationID)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid location ID", err)
+		}
+		query = query.Where("location_id IS NULL OR location_id = ?", parsedID)
+	}
+
+	var holidays []models.Holiday
+	if err := query.Preload("Location").Order("date ASC").Find(&holidays).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch holidays", err)
+	}
+
+	return utils.SuccessResponse(c, "Holidays retrieved successfully", holidays)
+}
+
+// CreateHoliday adds a holiday or closure, one entry per day of the range
+func (h *HolidayHandler) CreateHoliday(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uuid.UUID)
+
+	var req CreateHolidayRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+	if req.Name == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Name is required", nil)
+	}
+	if message, ok := h.validateHoliday(req.Type, req.LocationID); !ok {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, message, nil)
+	}
+	if req.EndDate == "" {
+		req.EndDate = req.Date
+	}
+
+	startDate, endDate, err := parseDateRange(req.Date, req.EndDate)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+	}
+	if endDate.Before(startDate) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "End date cannot be before the start date", nil)
+	}
+	if endDate.Sub(startDate) >= maxHolidaySpan*24*time.Hour {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "A holiday can cover at most 31 days", nil)
+	}
+
+	var holidays []models.Holiday
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		for day := startDate; !day.After(*endDate); day = day.AddDate(0, 0, 1) {
+			holiday := models.Holiday{
+				Date:       day,
+				Name:       req.Name,
+				Type:       req.Type,
+				LocationID: req.LocationID,
+				Notes:      req.Notes,
+				CreatedBy:  adminID,
+			}
+			if _, err := upsertHoliday(tx, &holiday); err != nil {
+				return err
+			}
+			holidays = append(holidays, holiday)
+		}
+		return nil
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create holiday", err)
+	}
+
+	return utils.SuccessResponse(c, "Holiday created successfully", holidays)
+}
+
+// UpdateHoliday changes one day of the calendar
+func (h *HolidayHandler) UpdateHoliday(c *fiber.Ctx) error {
+	holidayID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid holiday ID", err)
+	}
+
+	var holiday models.Holiday
+	if err := h.db.Where("id = ?", holidayID).First(&holiday).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Holiday not found", err)
+	}
+
+	var req UpdateHolidayRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	updates := map[string]interface{}{}
+	if req.Date != nil {
+		date, err := time.Parse("2006-01-02", *req.Date)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+		}
+		updates["date"] = date
+	}
+	if req.Name != nil {
+		if *req.Name == "" {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Name cannot be empty", nil)
+		}
+		updates["name"] = *req.Name
+	}
+	if req.Notes != nil {
+		updates["notes"] = *req.Notes
+	}
+
+	holidayType, locationID := holiday.Type, holiday.LocationID
+	if req.Type != nil {
+		holidayType = *req.Type
+		updates["type"] = holidayType
+	}
+	if req.ClearLocation {
+		locationID = nil
+		updates["location_id"] = nil
+	} else if req.LocationID != nil {
+		locationID = req.LocationID
+		updates["location_id"] = *req.LocationID
+	}
+	if message, ok := h.validateHoliday(holidayType, locationID); !ok {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, message, nil)
+	}
+
+	if err := h.db.Model(&holiday).Updates(updates).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update holiday", err)
+	}
+
+	h.db.Preload("Location").First(&holiday, "id = ?", holiday.ID)
+
+	return utils.SuccessResponse(c, "Holiday updated successfully", holiday)
+}
+
+// DeleteHoliday removes one day from the calendar
+func (h *HolidayHandler) DeleteHoliday(c *fiber.Ctx) error {
+	holidayID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid holiday ID", err)
+	}
+
+	result := h.db.Where("id = ?", holidayID).Delete(&models.Holiday{})
+	if result.Error != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete holiday", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Holiday not found", nil)
+	}
+
+	return utils.SuccessResponse(c, "Holiday deleted successfully", nil)
+}
+
+// ImportHolidays loads holidays from an uploaded iCalendar (.ics) file, such as a
+// published Indonesian holiday calendar. Events named "Cuti Bersama" are imported as
+// collective leave, observances that are not days off are skipped, and importing the
+// same file again updates the existing entries. A location_id imports the events as
+// closures of that branch.
+func (h *HolidayHandler) ImportHolidays(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uuid.UUID)
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "An .ics file is required", err)
+	}
+	if strings.ToLower(filepath.Ext(file.Filename)) != ".ics" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Only iCalendar (.ics) files can be imported", nil)
+	}
+
+	holidayType := c.FormValue("type", models.HolidayNational)
+	var locationID *uuid.UUID
+	if value := c.FormValue("location_id"); value != "" {
+		parsedID, err := uuid.Parse(value)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid location ID", err)
+		}
+		locationID = &parsedID
+		holidayType = models.HolidayClosure
+	}
+	if message, ok := h.validateHoliday(holidayType, locationID); !ok {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, message, nil)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to read the uploaded file", err)
+	}
+	defer src.Close()
+
+	events, err := utils.ParseICal(src)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid iCalendar file", err)
+	}
+
+	created, updated, skipped := 0, 0, 0
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		for _, event := range events {
+			if event.Summary == "" || isObservance(event) || event.End.Sub(event.Start) >= maxHolidaySpan*24*time.Hour {
+				skipped++
+				continue
+			}
+
+			eventType := holidayType
+			if eventType == models.HolidayNational && strings.Contains(strings.ToLower(event.Summary), "cuti bersama") {
+				eventType = models.HolidayCutiBersama
+			}
+
+			for day := event.Start; !day.After(event.End); day = day.AddDate(0, 0, 1) {
+				holiday := models.Holiday{
+					Date:       day,
+					Name:       event.Summary,
+					Type:       eventType,
+					LocationID: locationID,
+					UID:        event.UID,
+					CreatedBy:  adminID,
+				}
+				isNew, err := upsertHoliday(tx, &holiday)
+				if err != nil {
+					return err
+				}
+				if isNew {
+					created++
+				} else {
+					updated++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to import holidays", err)
+	}
+
+	return utils.SuccessResponse(c, "Holidays imported successfully", map[string]interface{}{
+		"created": created,
+		"updated": updated,
+		"skipped": skipped,
+	})
+}
+
+// GetWorkingDays returns the working days and days off of an employee between two
+// dates, defaulting to the current month. Own-scoped users always get their own.
+func (h *HolidayHandler) GetWorkingDays(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+	if requested := scopedUserFilter(c, c.Query("user_id", "")); requested != "" {
+		parsed, err := uuid.Parse(requested)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID", err)
+		}
+		userID = parsed
+	}
+
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	from, to, err := parseDateRange(c.Query("from", monthStart.Format("2006-01-02")),
+		c.Query("to", monthStart.AddDate(0, 1, -1).Format("2006-01-02")))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+	}
+	if to.Before(from) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "End date cannot be before the start date", nil)
+	}
+	if to.Sub(from) > 366*24*time.Hour {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "The range can cover at most one year", nil)
+	}
+
+	calendar := models.LoadWorkCalendar(h.db, userID, from, *to)
+	holidays := []models.Holiday{}
+	for day := from; !day.After(*to); day = day.AddDate(0, 0, 1) {
+		if holiday := calendar.HolidayFor(userID, day); holiday != nil {
+			holidays = append(holidays, *holiday)
+		}
+	}
+
+	return utils.SuccessResponse(c, "Working days retrieved successfully", map[string]interface{}{
+		"user_id":      userID,
+		"from":         from.Format("2006-01-02"),
+		"to":           to.Format("2006-01-02"),
+		"working_days": calendar.WorkingDays(userID, from, *to),
+		"holidays":     holidays,
+	})
+}
+
+// upsertHoliday stores the holiday, updating the entry for the same day and location
+// that has the same iCalendar UID, or the same name when there is no UID. It reports
+// whether a new entry was created.
+func upsertHoliday(tx *gorm.DB, holiday *models.Holiday) (bool, error) {
+	query := tx.Where("date = ?", holiday.Date.Format("2006-01-02"))
+	if holiday.LocationID == nil {
+		query = query.Where("location_id IS NULL")
+	} else {
+		query = query.Where("location_id = ?", *holiday.LocationID)
+	}
+	if holiday.UID != "" {
+		query = query.Where("uid = ?", holiday.UID)
+	} else {
+		query = query.Where("name = ?", holiday.Name)
+	}
+
+	var existing models.Holiday
+	if err := query.First(&existing).Error; err == nil {
+		holiday.ID = existing.ID
+		holiday.CreatedAt = existing.CreatedAt
+		updates := map[string]interface{}{"name": holiday.Name, "type": holiday.Type}
+		if holiday.Notes != "" {
+			updates["notes"] = holiday.Notes
+		}
+		return false, tx.Model(&existing).Updates(updates).Error
+	}
+	return true, tx.Create(holiday).Error
+}
+
+// isObservance reports whether a published holiday calendar marks the event as an
+// observance, a commemoration that is not a day off
+func isObservance(event utils.ICalEvent) bool {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(event.Description)), "observance") {
+		return true
+	}
+	for _, category := range event.Categories {
+		if strings.EqualFold(category, "observance") {
+			return true
+		}
+	}
+	return false
+}
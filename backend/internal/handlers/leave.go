@@ -0,0 +1,453 @@
+package handlers
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"cybercafe-backend/internal/config"
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var errLeaveProcessed = errors.New("Request has already been processed")
+
+type LeaveHandler struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewLeaveHandler(db *gorm.DB, cfg *config.Config) *LeaveHandler {
+	return &LeaveHandler{db: db, cfg: cfg}
+}
+
+type RejectLeaveRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+type UpdateLeaveBalanceRequest struct {
+	Year        int     `json:"year" validate:"required"`
+	Entitlement *int    `json:"entitlement"`
+	CarriedOver *int    `json:"carried_over"`
+	Adjustment  *int    `json:"adjustment"`
+	Notes       *string `json:"notes"`
+}
+
+// LeaveBalanceResponse is a yearly balance with the days accrued and still available today
+type LeaveBalanceResponse struct {
+	models.LeaveBalance
+	Accrued   int `json:"accrued"`
+	Pending   int `json:"pending"` // annual leave days awaiting approval
+	Available int `json:"available"`
+}
+
+// CreateLeaveRequest submits a leave request as multipart form data. Sick leave needs a
+// medical certificate in the "attachment" field.
+func (h *LeaveHandler) CreateLeaveRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	leaveType := c.FormValue("type")
+	reason := c.FormValue("reason")
+	if !models.IsValidLeaveType(leaveType) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Type must be one of annual, sick, unpaid, permission", nil)
+	}
+	if reason == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Reason is required", nil)
+	}
+
+	endDateValue := c.FormValue("end_date")
+	if endDateValue == "" {
+		endDateValue = c.FormValue("start_date")
+	}
+	startDate, endDate, err := parseDateRange(c.FormValue("start_date"), endDateValue)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+	}
+	if endDate.Before(startDate) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "End date cannot be before the start date", nil)
+	}
+
+	days := models.LoadWorkCalendar(h.db, userID, startDate, *endDate).WorkingDays(userID, startDate, *endDate)
+	if days == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "The requested period has no working days", nil)
+	}
+
+	if leaveType == models.LeaveTypeAnnual {
+		if startDate.Format("2006-01-02") < time.Now().Format("2006-01-02") {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Annual leave cannot be requested for past days", nil)
+		}
+		if startDate.Year() != endDate.Year() {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Annual leave cannot span two years; submit one request per year", nil)
+		}
+		balance, err := models.GetLeaveBalance(h.db, userID, startDate.Year(), h.cfg.GetAnnualLeaveDays())
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load leave balance", err)
+		}
+		asOf := time.Now()
+		if startDate.After(asOf) {
+			asOf = startDate
+		}
+		if balance.Available(asOf) < days {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest,
+				"Insufficient annual leave balance: "+strconv.Itoa(balance.Available(asOf))+" days available", nil)
+		}
+	}
+
+	var overlapping int64
+	h.db.Model(&models.LeaveRequest{}).
+		Where("user_id = ? AND status IN ?", userID, []string{models.LeavePending, models.LeaveApproved}).
+		Where("start_date <= ? AND end_date >= ?", endDate.Format("2006-01-02"), startDate.Format("2006-01-02")).
+		Count(&overlapping)
+	if overlapping > 0 {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "You already have a leave request covering some of these days", nil)
+	}
+
+	attachmentPath := ""
+	if file, err := c.FormFile("attachment"); err == nil {
+		if !utils.IsValidDocumentFile(file.Filename) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Attachment must be an image or PDF", nil)
+		}
+		// Medical certificates are kept out of the public uploads directory
+		filename, err := utils.SaveUploadedFile(file, filepath.Join(h.cfg.PrivateUploadPath, "leave"))
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save attachment", err)
+		}
+		attachmentPath = "leave/" + filename
+	} else if leaveType == models.LeaveTypeSick {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "A medical certificate is required for sick leave", err)
+	}
+
+	request := models.LeaveRequest{
+		UserID:         userID,
+		Type:           leaveType,
+		StartDate:      startDate,
+		EndDate:        *endDate,
+		Days:           days,
+		Reason:         reason,
+		AttachmentPath: attachmentPath,
+		Status:         models.LeavePending,
+	}
+
+	if err := h.db.Create(&request).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create leave request", err)
+	}
+
+	h.db.Preload("User").First(&request, "id = ?", request.ID)
+
+	return utils.SuccessResponse(c, "Leave request created successfully", request)
+}
+
+// canReadAllLeave reports whether the user may see colleagues' leave. Attendance access
+// alone is not enough, since sick leave carries medical certificates; the role must hold
+// leave.read or leave.approve.
+func (h *LeaveHandler) canReadAllLeave(c *fiber.Ctx) bool {
+	var role models.Role
+	if err := h.db.Joins("JOIN users ON users.role_id = roles.id").
+		Where("users.id = ?", c.Locals("user_id")).First(&role).Error; err != nil {
+		return false
+	}
+	for _, permission := range []string{"leave.read", "leave.approve"} {
+		if granted, scope := role.Grants(permission); granted && scope == models.ScopeAny {
+			return true
+		}
+	}
+	return false
+}
+
+// leaveUserFilter returns the user_id filter for leave records: users who cannot read
+// all leave are limited to their own
+func (h *LeaveHandler) leaveUserFilter(c *fiber.Ctx, requested string) string {
+	if !h.canReadAllLeave(c) {
+		return c.Locals("user_id").(uuid.UUID).String()
+	}
+	return scopedUserFilter(c, requested)
+}
+
+// GetLeaveRequests lists leave requests; users without leave.read only see their own
+func (h *LeaveHandler) GetLeaveRequests(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	status := c.Query("status", "")
+	leaveType := c.Query("type", "")
+	userID := h.leaveUserFilter(c, c.Query("user_id", ""))
+	date := c.Query("date", "")
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.LeaveRequest{})
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if leaveType != "" {
+		query = query.Where("type = ?", leaveType)
+	}
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if date != "" {
+		parsedDate, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+		}
+		day := parsedDate.Format("2006-01-02")
+		query = query.Where("start_date <= ? AND end_date >= ?", day, day)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var requests []models.LeaveRequest
+	if err := query.Preload("User").Preload("Reviewer").Order("start_date DESC").
+		Offset(offset).Limit(limit).Find(&requests).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch leave requests", err)
+	}
+
+	meta := utils.PaginationMeta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+
+	return utils.PaginatedSuccessResponse(c, "Leave requests retrieved successfully", requests, meta)
+}
+
+// GetLeaveAttachment sends the medical certificate of a leave request to its owner or
+// to users who can read all leave
+func (h *LeaveHandler) GetLeaveAttachment(c *fiber.Ctx) error {
+	requestID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request ID", err)
+	}
+
+	var request models.LeaveRequest
+	if err := h.db.Where("id = ?", requestID).First(&request).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Leave request not found", err)
+	}
+	if request.UserID != c.Locals("user_id").(uuid.UUID) && !h.canReadAllLeave(c) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You can only view your own leave attachments", nil)
+	}
+	if request.AttachmentPath == "" {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Leave request has no attachment", nil)
+	}
+
+	c.Set("Cache-Control", "private, no-store")
+	return c.SendFile(filepath.Join(h.cfg.PrivateUploadPath, filepath.Clean("/"+request.AttachmentPath)))
+}
+
+// ApproveLeaveRequest approves a pending leave request, deducting annual leave from the balance
+func (h *LeaveHandler) ApproveLeaveRequest(c *fiber.Ctx) error {
+	return h.review(c, models.LeaveApproved, "")
+}
+
+// RejectLeaveRequest rejects a pending leave request with a reason
+func (h *LeaveHandler) RejectLeaveRequest(c *fiber.Ctx) error {
+	var req RejectLeaveRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+	if req.Reason == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Rejection reason is required", nil)
+	}
+	return h.review(c, models.LeaveRejected, req.Reason)
+}
+
+func (h *LeaveHandler) review(c *fiber.Ctx, status, rejectionReason string) error {
+	reviewerID := c.Locals("user_id").(uuid.UUID)
+
+	requestID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request ID", err)
+	}
+
+	var request models.LeaveRequest
+	if err := h.db.Where("id = ?", requestID).First(&request).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Leave request not found", err)
+	}
+	if request.Status != models.LeavePending {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, errLeaveProcessed.Error(), nil)
+	}
+	if request.UserID == reviewerID {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You cannot review your own request", nil)
+	}
+
+	now := time.Now()
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&request).Where("status = ?", models.LeavePending).Updates(map[string]interface{}{
+			"status":           status,
+			"reviewed_by":      reviewerID,
+			"reviewed_at":      now,
+			"rejection_reason": rejectionReason,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errLeaveProcessed
+		}
+
+		if status == models.LeaveApproved && request.Type == models.LeaveTypeAnnual {
+			return models.DeductAnnualLeave(tx, &request, h.cfg.GetAnnualLeaveDays(), now)
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errLeaveProcessed), errors.Is(err, models.ErrInsufficientLeave):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	case err != nil:
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update leave request", err)
+	}
+
+	h.db.Preload("User").Preload("Reviewer").First(&request, "id = ?", request.ID)
+
+	return utils.SuccessResponse(c, "Leave request "+status+" successfully", request)
+}
+
+// CancelLeaveRequest withdraws the user's own request. Approved leave can only be
+// cancelled before it starts; annual leave days go back to the balance.
+func (h *LeaveHandler) CancelLeaveRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	requestID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request ID", err)
+	}
+
+	var request models.LeaveRequest
+	if err := h.db.Where("id = ? AND user_id = ?", requestID, userID).First(&request).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Leave request not found", err)
+	}
+
+	switch request.Status {
+	case models.LeavePending:
+	case models.LeaveApproved:
+		if request.StartDate.Format("2006-01-02") <= time.Now().Format("2006-01-02") {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Leave that has already started cannot be cancelled", nil)
+		}
+	default:
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, errLeaveProcessed.Error(), nil)
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&request).Where("status = ?", request.Status).Update("status", models.LeaveCancelled)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errLeaveProcessed
+		}
+
+		if request.Status == models.LeaveApproved && request.Type == models.LeaveTypeAnnual {
+			return models.RestoreAnnualLeave(tx, &request)
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errLeaveProcessed):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	case err != nil:
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to cancel leave request", err)
+	}
+
+	return utils.SuccessResponse(c, "Leave request cancelled successfully", nil)
+}
+
+// GetLeaveBalance returns an employee's annual leave balance for a year. Users without
+// leave.read always get their own balance.
+func (h *LeaveHandler) GetLeaveBalance(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+	if requested := h.leaveUserFilter(c, c.Query("user_id", "")); requested != "" {
+		parsed, err := uuid.Parse(requested)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID", err)
+		}
+		userID = parsed
+	}
+
+	now := time.Now()
+	year, err := strconv.Atoi(c.Query("year", strconv.Itoa(now.Year())))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid year", err)
+	}
+
+	balance, err := models.GetLeaveBalance(h.db, userID, year, h.cfg.GetAnnualLeaveDays())
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load leave balance", err)
+	}
+
+	return utils.SuccessResponse(c, "Leave balance retrieved successfully", h.balanceResponse(balance, now))
+}
+
+// UpdateLeaveBalance sets an employee's entitlement, carried-over days or adjustment for a year
+func (h *LeaveHandler) UpdateLeaveBalance(c *fiber.Ctx) error {
+	userID, err := uuid.Parse(c.Params("userId"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID", err)
+	}
+
+	var req UpdateLeaveBalanceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+	if req.Year == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Year is required", nil)
+	}
+	if (req.Entitlement != nil && *req.Entitlement < 0) || (req.CarriedOver != nil && *req.CarriedOver < 0) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Entitlement and carried-over days cannot be negative", nil)
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", err)
+	}
+
+	balance, err := models.GetLeaveBalance(h.db, userID, req.Year, h.cfg.GetAnnualLeaveDays())
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load leave balance", err)
+	}
+
+	updates := map[string]interface{}{}
+	if req.Entitlement != nil {
+		updates["entitlement"] = *req.Entitlement
+	}
+	if req.CarriedOver != nil {
+		updates["carried_over"] = *req.CarriedOver
+	}
+	if req.Adjustment != nil {
+		updates["adjustment"] = *req.Adjustment
+	}
+	if req.Notes != nil {
+		updates["notes"] = *req.Notes
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.Model(balance).Updates(updates).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update leave balance", err)
+		}
+	}
+
+	h.db.First(balance, "id = ?", balance.ID)
+
+	return utils.SuccessResponse(c, "Leave balance updated successfully", h.balanceResponse(balance, time.Now()))
+}
+
+func (h *LeaveHandler) balanceResponse(balance *models.LeaveBalance, now time.Time) LeaveBalanceResponse {
+	var pending int64
+	h.db.Model(&models.LeaveRequest{}).
+		Where("user_id = ? AND type = ? AND status = ?", balance.UserID, models.LeaveTypeAnnual, models.LeavePending).
+		Where("EXTRACT(YEAR FROM start_date) = ?", balance.Year).
+		Select("COALESCE(SUM(days), 0)").Scan(&pending)
+
+	return LeaveBalanceResponse{
+		LeaveBalance: *balance,
+		Accrued:      balance.Accrued(now),
+		Pending:      int(pending),
+		Available:    balance.Available(now),
+	}
+}
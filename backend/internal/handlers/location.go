@@ -2,8 +2,8 @@ package handlers
 
 import (
 	"fmt"
-	"math"
 	"strconv"
+	"time"
 
 	"cybercafe-backend/internal/models"
 	"cybercafe-backend/internal/utils"
@@ -22,26 +22,29 @@ func NewLocationHandler(db *gorm.DB) *LocationHandler {
 }
 
 type CreateLocationRequest struct {
-	Name         string  `json:"name" validate:"required"`
-	Address      string  `json:"address"`
-	Latitude     float64 `json:"latitude" validate:"required"`
-	Longitude    float64 `json:"longitude" validate:"required"`
-	Radius       int     `json:"radius"`
-	Type         string  `json:"type"`
-	WorkingHours string  `json:"working_hours"`
-	Timezone     string  `json:"timezone"`
+	Name         string               `json:"name" validate:"required"`
+	Address      string               `json:"address"`
+	Latitude     float64              `json:"latitude" validate:"required"`
+	Longitude    float64              `json:"longitude" validate:"required"`
+	Radius       int                  `json:"radius"`
+	Boundary     *models.GeoPolygon   `json:"boundary"` // GeoJSON Polygon; the center defaults to its centroid
+	Type         string               `json:"type"`
+	WorkingHours *models.WorkingHours `json:"working_hours"`
+	Timezone     string               `json:"timezone"`
 }
 
 type UpdateLocationRequest struct {
-	Name         string  `json:"name"`
-	Address      string  `json:"address"`
-	Latitude     float64 `json:"latitude"`
-	Longitude    float64 `json:"longitude"`
-	Radius       int     `json:"radius"`
-	Type         string  `json:"type"`
-	IsActive     *bool   `json:"is_active"`
-	WorkingHours string  `json:"working_hours"`
-	Timezone     string  `json:"timezone"`
+	Name          string               `json:"name"`
+	Address       string               `json:"address"`
+	Latitude      float64              `json:"latitude"`
+	Longitude     float64              `json:"longitude"`
+	Radius        int                  `json:"radius"`
+	Boundary      *models.GeoPolygon   `json:"boundary"`
+	ClearBoundary bool                 `json:"clear_boundary"` // go back to the radius check
+	Type          string               `json:"type"`
+	IsActive      *bool                `json:"is_active"`
+	WorkingHours  *models.WorkingHours `json:"working_hours"`
+	Timezone      string               `json:"timezone"`
 }
 
 type NearbyLocationRequest struct {
@@ -57,18 +60,39 @@ func (h *LocationHandler) CreateLocation(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
 	}
 
+	if req.Boundary != nil {
+		if err := req.Boundary.Validate(); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid boundary", err)
+		}
+		if req.Latitude == 0 && req.Longitude == 0 {
+			req.Latitude, req.Longitude = req.Boundary.Centroid()
+		}
+	}
+
+	if req.WorkingHours != nil {
+		if err := req.WorkingHours.Validate(); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid working hours", err)
+		}
+	}
+	if req.Timezone != "" && !models.IsValidTimezone(req.Timezone) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid timezone. Use an IANA name such as Asia/Makassar", nil)
+	}
+
 	userID := c.Locals("user_id").(uuid.UUID)
 
 	location := models.Location{
-		Name:         req.Name,
-		Address:      req.Address,
-		Latitude:     req.Latitude,
-		Longitude:    req.Longitude,
-		Radius:       req.Radius,
-		Type:         req.Type,
-		WorkingHours: req.WorkingHours,
-		Timezone:     req.Timezone,
-		CreatedBy:    userID.String(),
+		Name:      req.Name,
+		Address:   req.Address,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Radius:    req.Radius,
+		Boundary:  req.Boundary,
+		Type:      req.Type,
+		Timezone:  req.Timezone,
+		CreatedBy: userID.String(),
+	}
+	if req.WorkingHours != nil {
+		location.WorkingHours = *req.WorkingHours
 	}
 
 	// Set defaults
@@ -79,7 +103,7 @@ func (h *LocationHandler) CreateLocation(c *fiber.Ctx) error {
 		location.Type = "office"
 	}
 	if location.Timezone == "" {
-		location.Timezone = "Asia/Jakarta"
+		location.Timezone = models.DefaultTimezone
 	}
 
 	if err := h.db.Create(&location).Error; err != nil {
@@ -189,16 +213,30 @@ func (h *LocationHandler) UpdateLocation(c *fiber.Ctx) error {
 	if req.Radius != 0 {
 		location.Radius = req.Radius
 	}
+	if req.Boundary != nil {
+		if err := req.Boundary.Validate(); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid boundary", err)
+		}
+		location.Boundary = req.Boundary
+	} else if req.ClearBoundary {
+		location.Boundary = nil
+	}
 	if req.Type != "" {
 		location.Type = req.Type
 	}
 	if req.IsActive != nil {
 		location.IsActive = *req.IsActive
 	}
-	if req.WorkingHours != "" {
-		location.WorkingHours = req.WorkingHours
+	if req.WorkingHours != nil {
+		if err := req.WorkingHours.Validate(); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid working hours", err)
+		}
+		location.WorkingHours = *req.WorkingHours
 	}
 	if req.Timezone != "" {
+		if !models.IsValidTimezone(req.Timezone) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid timezone. Use an IANA name such as Asia/Makassar", nil)
+		}
 		location.Timezone = req.Timezone
 	}
 
@@ -264,19 +302,26 @@ func (h *LocationHandler) GetNearbyLocations(c *fiber.Ctx) error {
 
 	type NearbyLocation struct {
 		models.LocationResponse
-		Distance        float64 `json:"distance"`
-		IsWithinRadius  bool    `json:"is_within_radius"`
+		Distance       float64  `json:"distance"`                   // meters to the location's center
+		DistanceToEdge *float64 `json:"distance_to_edge,omitempty"` // meters to the boundary, 0 when inside; only for polygon locations
+		IsInside       bool     `json:"is_inside"`                  // inside the location's geofence
+		IsWithinRadius bool     `json:"is_within_radius"`           // within the search radius
 	}
 
 	var nearbyLocations []NearbyLocation
 	for _, location := range locations {
 		distance := calculateDistance(latitude, longitude, location.Latitude, location.Longitude)
-		isWithinRadius := distance <= float64(radius)
+		fenceDistance, inside := location.Locate(latitude, longitude)
 
 		nearbyLocation := NearbyLocation{
 			LocationResponse: location.ToResponse(),
 			Distance:         distance,
-			IsWithinRadius:   isWithinRadius,
+			IsInside:         inside,
+			IsWithinRadius:   distance <= float64(radius),
+		}
+		if location.Boundary != nil {
+			nearbyLocation.DistanceToEdge = &fenceDistance
+			nearbyLocation.IsWithinRadius = fenceDistance <= float64(radius)
 		}
 
 		nearbyLocations = append(nearbyLocations, nearbyLocation)
@@ -289,7 +334,7 @@ func (h *LocationHandler) GetNearbyLocations(c *fiber.Ctx) error {
 func (h *LocationHandler) ValidateLocation(c *fiber.Ctx) error {
 	// LOG: Endpoint validation dipanggil
 	fmt.Printf("[LOCATION VALIDATION] Endpoint /locations/validate dipanggil dari IP: %s\n", c.IP())
-	
+
 	var req NearbyLocationRequest
 	if err := c.BodyParser(&req); err != nil {
 		fmt.Printf("[LOCATION VALIDATION ERROR] Invalid request body: %v\n", err)
@@ -299,44 +344,48 @@ func (h *LocationHandler) ValidateLocation(c *fiber.Ctx) error {
 	// LOG: Koordinat yang diterima
 	fmt.Printf("[LOCATION VALIDATION] Koordinat diterima - Lat: %f, Lng: %f\n", req.Latitude, req.Longitude)
 
-	var locations []models.Location
-	if err := h.db.Where("is_active = ?", true).Find(&locations).Error; err != nil {
+	if !models.IsValidCoordinate(req.Latitude, req.Longitude) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Valid latitude and longitude are required", nil)
+	}
+
+	// Same matching check-in and check-out use, so the preview agrees with what gets recorded
+	userID := c.Locals("user_id").(uuid.UUID)
+	geofence, err := models.MatchLocation(h.db, userID, req.Latitude, req.Longitude, time.Now())
+	if err != nil {
 		fmt.Printf("[LOCATION VALIDATION ERROR] Failed to fetch locations: %v\n", err)
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch locations", err)
 	}
 
-	// LOG: Jumlah lokasi aktif yang ditemukan
-	fmt.Printf("[LOCATION VALIDATION] Ditemukan %d lokasi aktif untuk validasi\n", len(locations))
-
 	type ValidationResult struct {
-		IsValid  bool                    `json:"is_valid"`
+		IsValid  bool                     `json:"is_valid"`
 		Location *models.LocationResponse `json:"location,omitempty"`
-		Distance float64                 `json:"distance,omitempty"`
-		Message  string                  `json:"message"`
-	}
-
-	for i, location := range locations {
-		distance := calculateDistance(req.Latitude, req.Longitude, location.Latitude, location.Longitude)
-		// LOG: Detail perhitungan jarak untuk setiap lokasi
-		fmt.Printf("[LOCATION VALIDATION] Lokasi %d (%s): Jarak = %.2fm, Radius = %dm\n", i+1, location.Name, distance, location.Radius)
-		
-		if distance <= float64(location.Radius) {
-			fmt.Printf("[LOCATION VALIDATION SUCCESS] User berada dalam radius lokasi: %s\n", location.Name)
-			locationResponse := location.ToResponse()
-			result := ValidationResult{
-				IsValid:  true,
-				Location: &locationResponse,
-				Distance: distance,
-				Message:  "Location is valid for attendance",
-			}
-			return utils.SuccessResponse(c, "Location validation successful", result)
+		Distance float64                  `json:"distance,omitempty"`
+		Remote   bool                     `json:"remote,omitempty"`
+		Message  string                   `json:"message"`
+	}
+
+	if geofence.IsValid {
+		fmt.Printf("[LOCATION VALIDATION SUCCESS] User berada dalam radius lokasi: %s (%.2fm)\n", geofence.Location.Name, geofence.Distance)
+		locationResponse := geofence.Location.ToResponse()
+		result := ValidationResult{
+			IsValid:  true,
+			Location: &locationResponse,
+			Distance: geofence.Distance,
+			Remote:   geofence.Remote,
+			Message:  "Location is valid for attendance",
 		}
+		return utils.SuccessResponse(c, "Location validation successful", result)
 	}
 
 	fmt.Printf("[LOCATION VALIDATION FAILED] User tidak berada dalam radius lokasi manapun\n")
 	result := ValidationResult{
 		IsValid: false,
-		Message: "Location is not within any allowed radius",
+		Message: "Location is not within the radius or boundary of any assigned location",
+	}
+	if geofence.Location != nil {
+		nearest := geofence.Location.ToResponse()
+		result.Location = &nearest
+		result.Distance = geofence.Distance
 	}
 
 	return utils.SuccessResponse(c, "Location validation completed", result)
@@ -344,32 +393,13 @@ func (h *LocationHandler) ValidateLocation(c *fiber.Ctx) error {
 
 // calculateDistance calculates the distance between two coordinates using Haversine formula
 func calculateDistance(lat1, lng1, lat2, lng2 float64) float64 {
-    // Validasi koordinat
-    if lat1 < -90 || lat1 > 90 || lat2 < -90 || lat2 > 90 {
-        return 0 // atau return error
-    }
-    if lng1 < -180 || lng1 > 180 || lng2 < -180 || lng2 > 180 {
-        return 0 // atau return error
-    }
-    
-    const earthRadius = 6371000 // Earth radius in meters
-
-    // Convert degrees to radians
-    lat1Rad := lat1 * math.Pi / 180
-    lng1Rad := lng1 * math.Pi / 180
-    lat2Rad := lat2 * math.Pi / 180
-    lng2Rad := lng2 * math.Pi / 180
-
-    // Haversine formula
-    dlat := lat2Rad - lat1Rad
-    dlng := lng2Rad - lng1Rad
-
-    a := math.Sin(dlat/2)*math.Sin(dlat/2) + math.Cos(lat1Rad)*math.Cos(lat2Rad)*math.Sin(dlng/2)*math.Sin(dlng/2)
-    c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-
-    result := earthRadius * c
-    if math.IsNaN(result) || math.IsInf(result, 0) {
-        return 0
-    }
-    return result
-}
\ No newline at end of file
+	// Validasi koordinat
+	if lat1 < -90 || lat1 > 90 || lat2 < -90 || lat2 > 90 {
+		return 0 // atau return error
+	}
+	if lng1 < -180 || lng1 > 180 || lng2 < -180 || lng2 > 180 {
+		return 0 // atau return error
+	}
+
+	return models.DistanceMeters(lat1, lng1, lat2, lng2)
+}
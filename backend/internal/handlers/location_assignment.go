@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type LocationAssignmentHandler struct {
+	db *gorm.DB
+}
+
+func NewLocationAssignmentHandler(db *gorm.DB) *LocationAssignmentHandler {
+	return &LocationAssignmentHandler{db: db}
+}
+
+type CreateLocationAssignmentRequest struct {
+	UserID        uuid.UUID `json:"user_id" validate:"required"`
+	LocationID    uuid.UUID `json:"location_id" validate:"required"`
+	EffectiveFrom string    `json:"effective_from" validate:"required"` // YYYY-MM-DD
+	EffectiveTo   string    `json:"effective_to"`                       // YYYY-MM-DD, empty for no end
+	Notes         string    `json:"notes"`
+}
+
+type UpdateLocationAssignmentRequest struct {
+	EffectiveFrom *string `json:"effective_from"`
+	EffectiveTo   *string `json:"effective_to"` // empty string removes the end date
+	Notes         *string `json:"notes"`
+}
+
+// parseDateRange parses an inclusive YYYY-MM-DD range. An empty end means open-ended.
+func parseDateRange(from, to string) (time.Time, *time.Time, error) {
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	if to == "" {
+		return start, nil, nil
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	return start, &end, nil
+}
+
+// CreateAssignment allows an employee to check in at a location from a given date
+func (h *LocationAssignmentHandler) CreateAssignment(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uuid.UUID)
+
+	var req CreateLocationAssignmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	effectiveFrom, effectiveTo, err := parseDateRange(req.EffectiveFrom, req.EffectiveTo)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+	}
+	if effectiveTo != nil && effectiveTo.Before(effectiveFrom) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Effective end date cannot be before the start date", nil)
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ?", req.UserID).First(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID", err)
+	}
+	var location models.Location
+	if err := h.db.Where("id = ?", req.LocationID).First(&location).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid location ID", err)
+	}
+
+	if h.overlaps(uuid.Nil, req.UserID, req.LocationID, effectiveFrom, effectiveTo) {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "The employee is already assigned to this location for part of that period", nil)
+	}
+
+	assignment := models.LocationAssignment{
+		UserID:        req.UserID,
+		LocationID:    req.LocationID,
+		EffectiveFrom: effectiveFrom,
+		EffectiveTo:   effectiveTo,
+		Notes:         req.Notes,
+		CreatedBy:     adminID,
+	}
+
+	if err := h.db.Create(&assignment).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create assignment", err)
+	}
+
+	h.db.Preload("User").Preload("Location").First(&assignment, "id = ?", assignment.ID)
+
+	return utils.SuccessResponse(c, "Assignment created successfully", assignment)
+}
+
+// GetAllAssignments lists location assignments, optionally only those effective on a date
+func (h *LocationAssignmentHandler) GetAllAssignments(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	userID := scopedUserFilter(c, c.Query("user_id", ""))
+	locationID := c.Query("location_id", "")
+	date := c.Query("date", "")
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.LocationAssignment{})
+
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if locationID != "" {
+		query = query.Where("location_id = ?", locationID)
+	}
+	if date != "" {
+		parsedDate, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+		}
+		day := parsedDate.Format("2006-01-02")
+		query = query.Where("effective_from <= ? AND (effective_to IS NULL OR effective_to >= ?)", day, day)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var assignments []models.LocationAssignment
+	if err := query.Preload("User").Preload("Location").Order("effective_from DESC").
+		Offset(offset).Limit(limit).Find(&assignments).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch assignments", err)
+	}
+
+	meta := utils.PaginationMeta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+
+	return utils.PaginatedSuccessResponse(c, "Assignments retrieved successfully", assignments, meta)
+}
+
+// GetMyLocations returns the locations the current user may check in at today
+func (h *LocationAssignmentHandler) GetMyLocations(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	now := time.Now()
+	locations, err := models.GetAssignedLocations(h.db, userID, now)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch locations", err)
+	}
+
+	// Remote work days follow the remote location's calendar, or the default time zone
+	remoteNow := now.In(models.LoadTimezone(""))
+	responses := make([]models.LocationResponse, 0, len(locations))
+	for _, location := range locations {
+		responses = append(responses, location.ToResponse())
+		if location.Type == models.LocationTypeRemote {
+			remoteNow = now.In(location.TimeLocation())
+		}
+	}
+
+	return utils.SuccessResponse(c, "Assigned locations retrieved successfully", fiber.Map{
+		"locations":         responses,
+		"remote_work_today": models.HasApprovedRemoteWork(h.db, userID, remoteNow),
+	})
+}
+
+// UpdateAssignment changes the effective dates or notes of an assignment. Ending an
+// assignment is done by setting its end date rather than deleting it, so past check-ins
+// keep their history.
+func (h *LocationAssignmentHandler) UpdateAssignment(c *fiber.Ctx) error {
+	assignmentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid assignment ID", err)
+	}
+
+	var req UpdateLocationAssignmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	var assignment models.LocationAssignment
+	if err := h.db.Where("id = ?", assignmentID).First(&assignment).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Assignment not found", err)
+	}
+
+	from := assignment.EffectiveFrom.Format("2006-01-02")
+	if req.EffectiveFrom != nil {
+		from = *req.EffectiveFrom
+	}
+	to := ""
+	if assignment.EffectiveTo != nil {
+		to = assignment.EffectiveTo.Format("2006-01-02")
+	}
+	if req.EffectiveTo != nil {
+		to = *req.EffectiveTo
+	}
+
+	effectiveFrom, effectiveTo, err := parseDateRange(from, to)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+	}
+	if effectiveTo != nil && effectiveTo.Before(effectiveFrom) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Effective end date cannot be before the start date", nil)
+	}
+	if h.overlaps(assignment.ID, assignment.UserID, assignment.LocationID, effectiveFrom, effectiveTo) {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "The employee is already assigned to this location for part of that period", nil)
+	}
+
+	updates := map[string]interface{}{
+		"effective_from": effectiveFrom,
+		"effective_to":   effectiveTo,
+	}
+	if req.Notes != nil {
+		updates["notes"] = *req.Notes
+	}
+
+	if err := h.db.Model(&assignment).Updates(updates).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update assignment", err)
+	}
+
+	h.db.Preload("User").Preload("Location").First(&assignment, "id = ?", assignment.ID)
+
+	return utils.SuccessResponse(c, "Assignment updated successfully", assignment)
+}
+
+// DeleteAssignment removes an assignment entered by mistake
+func (h *LocationAssignmentHandler) DeleteAssignment(c *fiber.Ctx) error {
+	assignmentID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid assignment ID", err)
+	}
+
+	result := h.db.Where("id = ?", assignmentID).Delete(&models.LocationAssignment{})
+	if result.Error != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete assignment", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Assignment not found", nil)
+	}
+
+	return utils.SuccessResponse(c, "Assignment deleted successfully", nil)
+}
+
+// overlaps reports whether another assignment of the user to the same location covers
+// any day of the given range
+func (h *LocationAssignmentHandler) overlaps(excludeID, userID, locationID uuid.UUID, from time.Time, to *time.Time) bool {
+	query := h.db.Model(&models.LocationAssignment{}).
+		Where("id <> ? AND user_id = ? AND location_id = ?", excludeID, userID, locationID).
+		Where("(effective_to IS NULL OR effective_to >= ?)", from.Format("2006-01-02"))
+	if to != nil {
+		query = query.Where("effective_from <= ?", to.Format("2006-01-02"))
+	}
+
+	var count int64
+	query.Count(&count)
+	return count > 0
+}
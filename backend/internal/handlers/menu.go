@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"errors"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MenuHandler struct {
+	db *gorm.DB
+}
+
+func NewMenuHandler(db *gorm.DB) *MenuHandler {
+	return &MenuHandler{db: db}
+}
+
+type RecipeLineRequest struct {
+	StockItemID        uuid.UUID `json:"stock_item_id"`
+	QuantityPerServing float64   `json:"quantity_per_serving"`
+}
+
+type CreateMenuItemRequest struct {
+	Name        string              `json:"name" validate:"required"`
+	Description string              `json:"description"`
+	Category    string              `json:"category"`
+	Price       int64               `json:"price" validate:"required"`
+	Available   *bool               `json:"available"`
+	Recipe      []RecipeLineRequest `json:"recipe"`
+}
+
+type UpdateMenuItemRequest struct {
+	Name        string               `json:"name"`
+	Description *string              `json:"description"`
+	Category    string               `json:"category"`
+	Price       *int64               `json:"price"`
+	Available   *bool                `json:"available"`
+	Recipe      *[]RecipeLineRequest `json:"recipe"` // replaces the whole recipe when present
+}
+
+type MenuItemResponse struct {
+	models.MenuItem
+	MaxServings int `json:"max_servings"` // -1 when the item has no recipe
+}
+
+func toMenuItemResponse(item models.MenuItem, levels map[uuid.UUID]float64) MenuItemResponse {
+	return MenuItemResponse{
+		MenuItem:    item,
+		MaxServings: item.MaxServings(levels),
+	}
+}
+
+// validateRecipe checks that every line references an existing stock item once with a positive quantity
+func (h *MenuHandler) validateRecipe(lines []RecipeLineRequest) error {
+	seen := make(map[uuid.UUID]bool, len(lines))
+	for _, line := range lines {
+		if line.QuantityPerServing <= 0 {
+			return errors.New("quantity per serving must be greater than 0")
+		}
+		if seen[line.StockItemID] {
+			return errors.New("a stock item can only appear once in a recipe")
+		}
+		seen[line.StockItemID] = true
+
+		var count int64
+		h.db.Model(&models.StockItem{}).Where("id = ?", line.StockItemID).Count(&count)
+		if count == 0 {
+			return errors.New("stock item " + line.StockItemID.String() + " not found")
+		}
+	}
+	return nil
+}
+
+func toRecipeLines(menuItemID uuid.UUID, lines []RecipeLineRequest) []models.RecipeLine {
+	recipe := make([]models.RecipeLine, 0, len(lines))
+	for _, line := range lines {
+		recipe = append(recipe, models.RecipeLine{
+			MenuItemID:         menuItemID,
+			StockItemID:        line.StockItemID,
+			QuantityPerServing: line.QuantityPerServing,
+		})
+	}
+	return recipe
+}
+
+// CreateMenuItem creates a menu item with its recipe
+func (h *MenuHandler) CreateMenuItem(c *fiber.Ctx) error {
+	var req CreateMenuItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if req.Name == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Menu item name is required", nil)
+	}
+	if req.Price < 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Price cannot be negative", nil)
+	}
+	if err := h.validateRecipe(req.Recipe); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid recipe", err)
+	}
+
+	var existingItem models.MenuItem
+	if err := h.db.Where("name = ?", req.Name).First(&existingItem).Error; err == nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Menu item name already exists", nil)
+	}
+
+	item := models.MenuItem{
+		Name:        req.Name,
+		Description: req.Description,
+		Category:    req.Category,
+		Price:       req.Price,
+		Available:   true,
+	}
+	if req.Available != nil {
+		item.Available = *req.Available
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Omit("Recipe").Create(&item).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create menu item", err)
+	}
+
+	if recipe := toRecipeLines(item.ID, req.Recipe); len(recipe) > 0 {
+		if err := tx.Create(&recipe).Error; err != nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save recipe", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create menu item", err)
+	}
+
+	h.db.Preload("Recipe.StockItem").First(&item, "id = ?", item.ID)
+
+	return utils.SuccessResponse(c, "Menu item created successfully", toMenuItemResponse(item, models.GetOnHandQuantities(h.db)))
+}
+
+// GetAllMenuItems retrieves menu items with their recipes
+func (h *MenuHandler) GetAllMenuItems(c *fiber.Ctx) error {
+	query := h.db.Preload("Recipe.StockItem")
+
+	if category := c.Query("category", ""); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if c.Query("available_only") == "true" {
+		query = query.Where("available = ?", true)
+	}
+
+	var items []models.MenuItem
+	if err := query.Order("category ASC, name ASC").Find(&items).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch menu items", err)
+	}
+
+	levels := models.GetOnHandQuantities(h.db)
+	responses := make([]MenuItemResponse, 0, len(items))
+	for _, item := range items {
+		responses = append(responses, toMenuItemResponse(item, levels))
+	}
+
+	return utils.SuccessResponse(c, "Menu items retrieved successfully", responses)
+}
+
+// GetMenuItemByID retrieves a menu item by ID
+func (h *MenuHandler) GetMenuItemByID(c *fiber.Ctx) error {
+	itemID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid menu item ID", err)
+	}
+
+	var item models.MenuItem
+	if err := h.db.Preload("Recipe.StockItem").Where("id = ?", itemID).First(&item).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Menu item not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch menu item", err)
+	}
+
+	return utils.SuccessResponse(c, "Menu item retrieved successfully", toMenuItemResponse(item, models.GetOnHandQuantities(h.db)))
+}
+
+// UpdateMenuItem updates a menu item and optionally replaces its recipe
+func (h *MenuHandler) UpdateMenuItem(c *fiber.Ctx) error {
+	itemID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid menu item ID", err)
+	}
+
+	var req UpdateMenuItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	var item models.MenuItem
+	if err := h.db.Where("id = ?", itemID).First(&item).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Menu item not found", err)
+	}
+
+	if req.Name != "" && req.Name != item.Name {
+		var existingItem models.MenuItem
+		if err := h.db.Where("name = ? AND id <> ?", req.Name, item.ID).First(&existingItem).Error; err == nil {
+			return utils.ErrorResponse(c, fiber.StatusConflict, "Menu item name already exists", nil)
+		}
+		item.Name = req.Name
+	}
+	if req.Description != nil {
+		item.Description = *req.Description
+	}
+	if req.Category != "" {
+		item.Category = req.Category
+	}
+	if req.Price != nil {
+		if *req.Price < 0 {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Price cannot be negative", nil)
+		}
+		item.Price = *req.Price
+	}
+	if req.Available != nil {
+		item.Available = *req.Available
+	}
+	if req.Recipe != nil {
+		if err := h.validateRecipe(*req.Recipe); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid recipe", err)
+		}
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Omit("Recipe").Save(&item).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update menu item", err)
+	}
+
+	if req.Recipe != nil {
+		if err := tx.Where("menu_item_id = ?", item.ID).Delete(&models.RecipeLine{}).Error; err != nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to replace recipe", err)
+		}
+		if recipe := toRecipeLines(item.ID, *req.Recipe); len(recipe) > 0 {
+			if err := tx.Create(&recipe).Error; err != nil {
+				tx.Rollback()
+				return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save recipe", err)
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update menu item", err)
+	}
+
+	h.db.Preload("Recipe.StockItem").First(&item, "id = ?", item.ID)
+
+	return utils.SuccessResponse(c, "Menu item updated successfully", toMenuItemResponse(item, models.GetOnHandQuantities(h.db)))
+}
+
+// DeleteMenuItem deletes a menu item and its recipe
+func (h *MenuHandler) DeleteMenuItem(c *fiber.Ctx) error {
+	itemID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid menu item ID", err)
+	}
+
+	var item models.MenuItem
+	if err := h.db.Where("id = ?", itemID).First(&item).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Menu item not found", err)
+	}
+
+	tx := h.db.Begin()
+	if err := tx.Where("menu_item_id = ?", item.ID).Delete(&models.RecipeLine{}).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete recipe", err)
+	}
+	if err := tx.Delete(&item).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete menu item", err)
+	}
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete menu item", err)
+	}
+
+	return utils.SuccessResponse(c, "Menu item deleted successfully", nil)
+}
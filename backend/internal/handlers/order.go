@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/realtime"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OrderHandler struct {
+	db  *gorm.DB
+	hub *realtime.Hub
+}
+
+func NewOrderHandler(db *gorm.DB, hub *realtime.Hub) *OrderHandler {
+	return &OrderHandler{db: db, hub: hub}
+}
+
+type OrderItemRequest struct {
+	MenuItemID uuid.UUID `json:"menu_item_id"`
+	Quantity   int       `json:"quantity"`
+	Notes      string    `json:"notes"`
+}
+
+type CreateOrderRequest struct {
+	CustomerID   *uuid.UUID         `json:"customer_id"`
+	CustomerName string             `json:"customer_name"`
+	TableNumber  string             `json:"table_number"`
+	IsDelivery   bool               `json:"is_delivery"`
+	Notes        string             `json:"notes"`
+	Items        []OrderItemRequest `json:"items" validate:"required"`
+}
+
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" validate:"required"`
+	Reason string `json:"reason"`
+}
+
+type QueuedOrder struct {
+	models.Order
+	AgeMinutes int `json:"age_minutes"`
+}
+
+// CreateOrder places a new kitchen order from available menu items
+func (h *OrderHandler) CreateOrder(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req CreateOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if len(req.Items) == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Order must contain at least one item", nil)
+	}
+
+	if req.CustomerID != nil {
+		var customer models.Customer
+		if err := h.db.Where("id = ? AND is_active = ?", *req.CustomerID, true).First(&customer).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid or inactive customer", err)
+		}
+		if req.CustomerName == "" {
+			req.CustomerName = customer.Name
+		}
+	}
+
+	now := time.Now()
+	order := models.Order{
+		CustomerID:      req.CustomerID,
+		CustomerName:    req.CustomerName,
+		TableNumber:     req.TableNumber,
+		IsDelivery:      req.IsDelivery,
+		Notes:           req.Notes,
+		Status:          models.OrderStatusPending,
+		StatusChangedAt: now,
+		CreatedBy:       userID,
+	}
+
+	for _, itemReq := range req.Items {
+		if itemReq.Quantity <= 0 {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Item quantity must be greater than 0", nil)
+		}
+
+		var menuItem models.MenuItem
+		if err := h.db.Where("id = ?", itemReq.MenuItemID).First(&menuItem).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Menu item not found", err)
+		}
+		if !menuItem.Available {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, menuItem.Name+" is not available", nil)
+		}
+
+		order.Items = append(order.Items, models.OrderItem{
+			MenuItemID: menuItem.ID,
+			Name:       menuItem.Name,
+			Price:      menuItem.Price,
+			Quantity:   itemReq.Quantity,
+			Notes:      itemReq.Notes,
+		})
+		order.Total += menuItem.Price * int64(itemReq.Quantity)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Create(&order).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create order", err)
+	}
+
+	history := models.OrderStatusHistory{
+		OrderID:   order.ID,
+		ToStatus:  models.OrderStatusPending,
+		ChangedBy: userID,
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to record order history", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create order", err)
+	}
+
+	h.db.Preload("Items").Preload("History").First(&order, "id = ?", order.ID)
+
+	h.hub.Publish(realtime.TopicOrders, "order.created", order)
+
+	return utils.SuccessResponse(c, "Order created successfully", order)
+}
+
+// GetAllOrders retrieves orders with optional status and date filters
+func (h *OrderHandler) GetAllOrders(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	status := c.Query("status", "")
+	date := c.Query("date", "")
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.Order{})
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if date != "" {
+		parsedDate, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+		}
+		query = query.Where("DATE(created_at) = ?", parsedDate.Format("2006-01-02"))
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var orders []models.Order
+	if err := query.Preload("Items").Order("created_at DESC").Offset(offset).Limit(limit).Find(&orders).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch orders", err)
+	}
+
+	meta := utils.PaginationMeta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+
+	return utils.PaginatedSuccessResponse(c, "Orders retrieved successfully", orders, meta)
+}
+
+// GetOrderQueue returns open kitchen tickets, oldest first
+func (h *OrderHandler) GetOrderQueue(c *fiber.Ctx) error {
+	statuses := []string{models.OrderStatusPending, models.OrderStatusPreparing, models.OrderStatusReady}
+	if status := c.Query("status", ""); status != "" {
+		statuses = []string{status}
+	}
+
+	var orders []models.Order
+	if err := h.db.Preload("Items").Where("status IN ?", statuses).
+		Order("created_at ASC").Find(&orders).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch order queue", err)
+	}
+
+	now := time.Now()
+	queue := make([]QueuedOrder, 0, len(orders))
+	for _, order := range orders {
+		queue = append(queue, QueuedOrder{
+			Order:      order,
+			AgeMinutes: int(math.Floor(now.Sub(order.CreatedAt).Minutes())),
+		})
+	}
+
+	return utils.SuccessResponse(c, "Order queue retrieved successfully", queue)
+}
+
+// GetOrderByID retrieves an order with its items and status history
+func (h *OrderHandler) GetOrderByID(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid order ID", err)
+	}
+
+	var order models.Order
+	if err := h.db.Preload("Items").Preload("History", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Preload("History.Changer").Where("id = ?", orderID).First(&order).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Order not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch order", err)
+	}
+
+	return utils.SuccessResponse(c, "Order retrieved successfully", order)
+}
+
+// UpdateOrderStatus moves an order ticket along pending → preparing → ready → served,
+// or to cancelled. Serving an order consumes its recipe stock.
+func (h *OrderHandler) UpdateOrderStatus(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	orderID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid order ID", err)
+	}
+
+	var req UpdateOrderStatusRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if !models.IsValidOrderStatus(req.Status) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid order status", nil)
+	}
+	if req.Status == models.OrderStatusCancelled && req.Reason == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Reason is required to cancel an order", nil)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", orderID).First(&order).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Order not found", err)
+	}
+
+	if !order.CanTransitionTo(req.Status) {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Cannot move order from "+order.Status+" to "+req.Status, nil)
+	}
+
+	previousStatus := order.Status
+	now := time.Now()
+	if err := tx.Model(&order).Updates(map[string]interface{}{
+		"status":            req.Status,
+		"status_changed_at": now,
+	}).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update order status", err)
+	}
+
+	history := models.OrderStatusHistory{
+		OrderID:    order.ID,
+		FromStatus: previousStatus,
+		ToStatus:   req.Status,
+		Reason:     req.Reason,
+		ChangedBy:  userID,
+	}
+	if err := tx.Create(&history).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to record order history", err)
+	}
+
+	if req.Status == models.OrderStatusServed {
+		if err := models.ConsumeOrderStock(tx, order.ID, userID); err != nil {
+			tx.Rollback()
+			if errors.Is(err, models.ErrInsufficientStock) {
+				return utils.ErrorResponse(c, fiber.StatusConflict, "Not enough stock to serve this order", err)
+			}
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to complete order", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update order status", err)
+	}
+
+	h.db.Preload("Items").Preload("History", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).First(&order, "id = ?", order.ID)
+
+	h.hub.Publish(realtime.TopicOrders, "order.status_changed", order)
+
+	return utils.SuccessResponse(c, "Order status updated successfully", order)
+}
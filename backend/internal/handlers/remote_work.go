@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RemoteWorkHandler struct {
+	db *gorm.DB
+}
+
+func NewRemoteWorkHandler(db *gorm.DB) *RemoteWorkHandler {
+	return &RemoteWorkHandler{db: db}
+}
+
+type CreateRemoteWorkRequest struct {
+	StartDate string `json:"start_date" validate:"required"` // YYYY-MM-DD
+	EndDate   string `json:"end_date"`                       // YYYY-MM-DD, defaults to the start date
+	Reason    string `json:"reason" validate:"required"`
+}
+
+type RejectRemoteWorkRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+// CreateRequest asks for approval to work remotely on a range of days
+func (h *RemoteWorkHandler) CreateRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req CreateRemoteWorkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+	if req.Reason == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Reason is required", nil)
+	}
+	if req.EndDate == "" {
+		req.EndDate = req.StartDate
+	}
+
+	startDate, endDate, err := parseDateRange(req.StartDate, req.EndDate)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+	}
+	if endDate.Before(startDate) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "End date cannot be before the start date", nil)
+	}
+	if startDate.Format("2006-01-02") < time.Now().Format("2006-01-02") {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Remote work cannot be requested for past days", nil)
+	}
+
+	var overlapping int64
+	h.db.Model(&models.RemoteWorkRequest{}).
+		Where("user_id = ? AND status IN ?", userID, []string{models.RemoteWorkPending, models.RemoteWorkApproved}).
+		Where("start_date <= ? AND end_date >= ?", endDate.Format("2006-01-02"), startDate.Format("2006-01-02")).
+		Count(&overlapping)
+	if overlapping > 0 {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "You already have a remote work request covering some of these days", nil)
+	}
+
+	request := models.RemoteWorkRequest{
+		UserID:    userID,
+		StartDate: startDate,
+		EndDate:   *endDate,
+		Reason:    req.Reason,
+		Status:    models.RemoteWorkPending,
+	}
+
+	if err := h.db.Create(&request).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create remote work request", err)
+	}
+
+	h.db.Preload("User").First(&request, "id = ?", request.ID)
+
+	return utils.SuccessResponse(c, "Remote work request created successfully", request)
+}
+
+// GetAllRequests lists remote work requests; own-scoped users only see their own
+func (h *RemoteWorkHandler) GetAllRequests(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	status := c.Query("status", "")
+	userID := scopedUserFilter(c, c.Query("user_id", ""))
+	date := c.Query("date", "")
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.RemoteWorkRequest{})
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if date != "" {
+		parsedDate, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+		}
+		day := parsedDate.Format("2006-01-02")
+		query = query.Where("start_date <= ? AND end_date >= ?", day, day)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var requests []models.RemoteWorkRequest
+	if err := query.Preload("User").Preload("Reviewer").Order("start_date DESC").
+		Offset(offset).Limit(limit).Find(&requests).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch remote work requests", err)
+	}
+
+	meta := utils.PaginationMeta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+
+	return utils.PaginatedSuccessResponse(c, "Remote work requests retrieved successfully", requests, meta)
+}
+
+// ApproveRequest approves a pending remote work request
+func (h *RemoteWorkHandler) ApproveRequest(c *fiber.Ctx) error {
+	return h.review(c, models.RemoteWorkApproved, "")
+}
+
+// RejectRequest rejects a pending remote work request with a reason
+func (h *RemoteWorkHandler) RejectRequest(c *fiber.Ctx) error {
+	var req RejectRemoteWorkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+	if req.Reason == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Rejection reason is required", nil)
+	}
+	return h.review(c, models.RemoteWorkRejected, req.Reason)
+}
+
+func (h *RemoteWorkHandler) review(c *fiber.Ctx, status, rejectionReason string) error {
+	reviewerID := c.Locals("user_id").(uuid.UUID)
+
+	requestID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request ID", err)
+	}
+
+	var request models.RemoteWorkRequest
+	if err := h.db.Where("id = ?", requestID).First(&request).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Remote work request not found", err)
+	}
+	if request.Status != models.RemoteWorkPending {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Request has already been processed", nil)
+	}
+	if request.UserID == reviewerID {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You cannot review your own request", nil)
+	}
+
+	now := time.Now()
+	if err := h.db.Model(&request).Updates(map[string]interface{}{
+		"status":           status,
+		"reviewed_by":      reviewerID,
+		"reviewed_at":      now,
+		"rejection_reason": rejectionReason,
+	}).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update remote work request", err)
+	}
+
+	h.db.Preload("User").Preload("Reviewer").First(&request, "id = ?", request.ID)
+
+	return utils.SuccessResponse(c, "Remote work request "+status+" successfully", request)
+}
+
+// CancelRequest withdraws the user's own request. Approved requests can only be
+// cancelled before they start.
+func (h *RemoteWorkHandler) CancelRequest(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	requestID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request ID", err)
+	}
+
+	var request models.RemoteWorkRequest
+	if err := h.db.Where("id = ? AND user_id = ?", requestID, userID).First(&request).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Remote work request not found", err)
+	}
+
+	switch request.Status {
+	case models.RemoteWorkPending:
+	case models.RemoteWorkApproved:
+		if request.StartDate.Format("2006-01-02") <= time.Now().Format("2006-01-02") {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Remote work that has already started cannot be cancelled", nil)
+		}
+	default:
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Request has already been processed", nil)
+	}
+
+	if err := h.db.Model(&request).Update("status", models.RemoteWorkCancelled).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to cancel remote work request", err)
+	}
+
+	return utils.SuccessResponse(c, "Remote work request cancelled successfully", nil)
+}
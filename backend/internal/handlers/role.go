@@ -18,9 +18,9 @@ func NewRoleHandler(db *gorm.DB) *RoleHandler {
 }
 
 type CreateRoleRequest struct {
-	Name        string `json:"name" validate:"required"`
-	Description string `json:"description"`
-	Permissions string `json:"permissions"`
+	Name        string                `json:"name" validate:"required"`
+	Description string                `json:"description"`
+	Permissions models.PermissionList `json:"permissions"`
 }
 
 func (h *RoleHandler) CreateRole(c *fiber.Ctx) error {
@@ -29,6 +29,15 @@ func (h *RoleHandler) CreateRole(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
 	}
 
+	if req.Name == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Role name is required", nil)
+	}
+
+	permissions, err := req.Permissions.Validate()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid permissions", err)
+	}
+
 	// Check if role name already exists
 	var existingRole models.Role
 	if err := h.db.Where("name = ?", req.Name).First(&existingRole).Error; err == nil {
@@ -38,7 +47,7 @@ func (h *RoleHandler) CreateRole(c *fiber.Ctx) error {
 	role := models.Role{
 		Name:        req.Name,
 		Description: req.Description,
-		Permissions: req.Permissions,
+		Permissions: permissions,
 	}
 
 	if err := h.db.Create(&role).Error; err != nil {
@@ -73,9 +82,17 @@ func (h *RoleHandler) GetRoleByID(c *fiber.Ctx) error {
 }
 
 type UpdateRoleRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Permissions string `json:"permissions"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Permissions *models.PermissionList `json:"permissions"`
+}
+
+// GetPermissionCatalog lists every permission key a role can be granted
+func (h *RoleHandler) GetPermissionCatalog(c *fiber.Ctx) error {
+	return utils.SuccessResponse(c, "Permission catalog retrieved successfully", fiber.Map{
+		"wildcard":    models.PermissionAll,
+		"permissions": models.PermissionCatalog,
+	})
 }
 
 func (h *RoleHandler) UpdateRole(c *fiber.Ctx) error {
@@ -101,8 +118,17 @@ func (h *RoleHandler) UpdateRole(c *fiber.Ctx) error {
 	if req.Description != "" {
 		role.Description = req.Description
 	}
-	if req.Permissions != "" {
-		role.Permissions = req.Permissions
+	if req.Permissions != nil {
+		permissions, err := req.Permissions.Validate()
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid permissions", err)
+		}
+
+		hadAdminRights := role.HasAdminRights()
+		role.Permissions = permissions
+		if hadAdminRights && !role.HasAdminRights() && !models.ActiveAdminExists(h.db, role.ID, uuid.Nil) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Cannot remove admin rights from the last role that holds them", nil)
+		}
 	}
 
 	if err := h.db.Save(&role).Error; err != nil {
@@ -119,6 +145,11 @@ func (h *RoleHandler) DeleteRole(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid role ID", err)
 	}
 
+	var role models.Role
+	if err := h.db.Where("id = ?", roleID).First(&role).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Role not found", err)
+	}
+
 	// Check if role is being used by any users
 	var userCount int64
 	h.db.Model(&models.User{}).Where("role_id = ?", roleID).Count(&userCount)
@@ -126,9 +157,13 @@ func (h *RoleHandler) DeleteRole(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Cannot delete role that is assigned to users", nil)
 	}
 
+	if role.HasAdminRights() && !models.ActiveAdminExists(h.db, role.ID, uuid.Nil) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Cannot delete the last role with admin rights", nil)
+	}
+
 	if err := h.db.Delete(&models.Role{}, roleID).Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete role", err)
 	}
 
 	return utils.SuccessResponse(c, "Role deleted successfully", nil)
-}
\ No newline at end of file
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"cybercafe-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// isOwnScope reports whether the route permission was granted only for the user's own records
+func isOwnScope(c *fiber.Ctx) bool {
+	scope, _ := c.Locals("permission_scope").(string)
+	return scope == models.ScopeOwn
+}
+
+// scopedUserFilter returns the user_id filter to apply. Own-scoped requests are always
+// limited to the current user, whatever was requested.
+func scopedUserFilter(c *fiber.Ctx, requested string) string {
+	if isOwnScope(c) {
+		return c.Locals("user_id").(uuid.UUID).String()
+	}
+	return requested
+}
+
+// canAccessUser reports whether the request may touch records belonging to the given user
+func canAccessUser(c *fiber.Ctx, ownerID uuid.UUID) bool {
+	return !isOwnScope(c) || c.Locals("user_id").(uuid.UUID) == ownerID
+}
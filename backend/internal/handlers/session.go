@@ -0,0 +1,465 @@
+package handlers
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/realtime"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SessionHandler struct {
+	db  *gorm.DB
+	hub *realtime.Hub
+}
+
+func NewSessionHandler(db *gorm.DB, hub *realtime.Hub) *SessionHandler {
+	return &SessionHandler{db: db, hub: hub}
+}
+
+type StartSessionRequest struct {
+	ComputerID   uuid.UUID  `json:"computer_id" validate:"required"`
+	PackageID    uuid.UUID  `json:"package_id" validate:"required"`
+	CustomerID   *uuid.UUID `json:"customer_id"`
+	CustomerName string     `json:"customer_name"`
+	Notes        string     `json:"notes"`
+}
+
+type ExtendSessionRequest struct {
+	PackageID uuid.UUID `json:"package_id"` // extend by a whole package
+	Minutes   int       `json:"minutes"`    // or by minutes at the session package rate
+}
+
+type SessionResponse struct {
+	models.UsageSession
+	RemainingMinutes int `json:"remaining_minutes"`
+}
+
+func toSessionResponse(session models.UsageSession, now time.Time) SessionResponse {
+	return SessionResponse{
+		UsageSession:     session,
+		RemainingMinutes: session.RemainingMinutes(now),
+	}
+}
+
+// StartSession starts a usage session and marks the computer as in use
+func (h *SessionHandler) StartSession(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req StartSessionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	var pkg models.TimePackage
+	if err := h.db.Where("id = ? AND is_active = ?", req.PackageID, true).First(&pkg).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid or inactive package", err)
+	}
+
+	if req.CustomerID != nil {
+		var customer models.Customer
+		if err := h.db.Where("id = ? AND is_active = ?", *req.CustomerID, true).First(&customer).Error; err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid or inactive customer", err)
+		}
+		if req.CustomerName == "" {
+			req.CustomerName = customer.Name
+		}
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock the computer row so two counters cannot start a session on it at once
+	var computer models.Computer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", req.ComputerID).First(&computer).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Computer not found", err)
+	}
+
+	if computer.Status != models.ComputerStatusAvailable {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Computer is not available", nil)
+	}
+
+	now := time.Now()
+	session := models.UsageSession{
+		ComputerID:   computer.ID,
+		PackageID:    pkg.ID,
+		CustomerID:   req.CustomerID,
+		CustomerName: req.CustomerName,
+		Status:       models.SessionStatusActive,
+		StartTime:    now,
+		EndTime:      now.Add(time.Duration(pkg.DurationMinutes) * time.Minute),
+		BaseAmount:   pkg.Price,
+		TotalAmount:  pkg.Price,
+		StartedBy:    userID,
+		Notes:        req.Notes,
+	}
+
+	if err := tx.Create(&session).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to start session", err)
+	}
+
+	if err := tx.Model(&computer).Update("status", models.ComputerStatusInUse).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update computer status", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to start session", err)
+	}
+
+	h.db.Preload("Computer").Preload("Package").First(&session, "id = ?", session.ID)
+
+	response := toSessionResponse(session, now)
+	h.hub.Publish(realtime.TopicSessions, "session.started", response)
+	publishComputerStatus(h.hub, session.Computer)
+
+	return utils.SuccessResponse(c, "Session started successfully", response)
+}
+
+// GetAllSessions retrieves sessions with optional status and computer filters
+func (h *SessionHandler) GetAllSessions(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	status := c.Query("status", "")
+	computerIDStr := c.Query("computer_id", "")
+	date := c.Query("date", "")
+
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.UsageSession{})
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if computerIDStr != "" {
+		computerID, err := uuid.Parse(computerIDStr)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid computer ID format", err)
+		}
+		query = query.Where("computer_id = ?", computerID)
+	}
+	if date != "" {
+		parsedDate, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+		}
+		query = query.Where("DATE(start_time) = ?", parsedDate.Format("2006-01-02"))
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var sessions []models.UsageSession
+	if err := query.Preload("Computer").Preload("Package").Order("start_time DESC").Offset(offset).Limit(limit).Find(&sessions).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch sessions", err)
+	}
+
+	now := time.Now()
+	responses := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, toSessionResponse(session, now))
+	}
+
+	meta := utils.PaginationMeta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+
+	return utils.PaginatedSuccessResponse(c, "Sessions retrieved successfully", responses, meta)
+}
+
+// GetActiveSessions returns every open session for the counter view
+func (h *SessionHandler) GetActiveSessions(c *fiber.Ctx) error {
+	var sessions []models.UsageSession
+	if err := h.db.Preload("Computer").Preload("Package").
+		Where("status IN ?", []string{models.SessionStatusActive, models.SessionStatusPaused}).
+		Order("end_time ASC").Find(&sessions).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch active sessions", err)
+	}
+
+	now := time.Now()
+	responses := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, toSessionResponse(session, now))
+	}
+
+	return utils.SuccessResponse(c, "Active sessions retrieved successfully", responses)
+}
+
+// GetSessionByID retrieves a session by ID
+func (h *SessionHandler) GetSessionByID(c *fiber.Ctx) error {
+	sessionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID", err)
+	}
+
+	var session models.UsageSession
+	if err := h.db.Preload("Computer").Preload("Package").Where("id = ?", sessionID).First(&session).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch session", err)
+	}
+
+	return utils.SuccessResponse(c, "Session retrieved successfully", toSessionResponse(session, time.Now()))
+}
+
+// ExtendSession adds time to an open session, either a whole package or prorated minutes
+func (h *SessionHandler) ExtendSession(c *fiber.Ctx) error {
+	sessionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID", err)
+	}
+
+	var req ExtendSessionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if req.PackageID == uuid.Nil && req.Minutes <= 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Either package_id or minutes is required", nil)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock the session so a concurrent stop, pause or extension cannot be overwritten
+	var session models.UsageSession
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", sessionID).First(&session).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found", err)
+	}
+
+	if !session.IsOpen() {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Session is no longer active", nil)
+	}
+
+	if err := tx.Where("id = ?", session.PackageID).First(&session.Package).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load session package", err)
+	}
+
+	var minutes int
+	var amount int64
+	if req.PackageID != uuid.Nil {
+		var pkg models.TimePackage
+		if err := tx.Where("id = ? AND is_active = ?", req.PackageID, true).First(&pkg).Error; err != nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid or inactive package", err)
+		}
+		minutes = pkg.DurationMinutes
+		amount = pkg.Price
+	} else {
+		minutes = req.Minutes
+		amount = int64(math.Round(float64(req.Minutes) * session.Package.PricePerMinute()))
+	}
+
+	session.EndTime = session.EndTime.Add(time.Duration(minutes) * time.Minute)
+	session.ExtendedMinutes += minutes
+	session.ExtensionAmount += amount
+	session.TotalAmount = session.BaseAmount + session.ExtensionAmount
+	session.WarningSentAt = nil
+
+	if err := tx.Omit(clause.Associations).Save(&session).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to extend session", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to extend session", err)
+	}
+
+	h.db.Preload("Computer").Preload("Package").First(&session, "id = ?", session.ID)
+
+	response := toSessionResponse(session, time.Now())
+	h.hub.Publish(realtime.TopicSessions, "session.extended", response)
+
+	return utils.SuccessResponse(c, "Session extended successfully", response)
+}
+
+// PauseSession freezes the remaining time of an active session
+func (h *SessionHandler) PauseSession(c *fiber.Ctx) error {
+	sessionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID", err)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var session models.UsageSession
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", sessionID).First(&session).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found", err)
+	}
+
+	if session.Status != models.SessionStatusActive {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Only active sessions can be paused", nil)
+	}
+
+	now := time.Now()
+	session.Status = models.SessionStatusPaused
+	session.PausedAt = &now
+
+	if err := tx.Omit(clause.Associations).Save(&session).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to pause session", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to pause session", err)
+	}
+
+	h.db.Preload("Computer").Preload("Package").First(&session, "id = ?", session.ID)
+
+	response := toSessionResponse(session, now)
+	h.hub.Publish(realtime.TopicSessions, "session.paused", response)
+
+	return utils.SuccessResponse(c, "Session paused successfully", response)
+}
+
+// ResumeSession resumes a paused session, pushing the end time back by the paused duration
+func (h *SessionHandler) ResumeSession(c *fiber.Ctx) error {
+	sessionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID", err)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var session models.UsageSession
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", sessionID).First(&session).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found", err)
+	}
+
+	if session.Status != models.SessionStatusPaused || session.PausedAt == nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Only paused sessions can be resumed", nil)
+	}
+
+	now := time.Now()
+	resumeSession(&session, now)
+
+	if err := tx.Omit(clause.Associations).Save(&session).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to resume session", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to resume session", err)
+	}
+
+	h.db.Preload("Computer").Preload("Package").First(&session, "id = ?", session.ID)
+
+	response := toSessionResponse(session, now)
+	h.hub.Publish(realtime.TopicSessions, "session.resumed", response)
+
+	return utils.SuccessResponse(c, "Session resumed successfully", response)
+}
+
+// StopSession closes a session, computes the final charge and frees the computer
+func (h *SessionHandler) StopSession(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	sessionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID", err)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var session models.UsageSession
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", sessionID).First(&session).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found", err)
+	}
+
+	if !session.IsOpen() {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Session is no longer active", nil)
+	}
+
+	if err := tx.Where("id = ?", session.PackageID).First(&session.Package).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to load session package", err)
+	}
+
+	now := time.Now()
+	if session.Status == models.SessionStatusPaused && session.PausedAt != nil {
+		resumeSession(&session, now)
+	}
+
+	session.CalculateCharge(now)
+	session.Status = models.SessionStatusCompleted
+	session.StoppedAt = &now
+	session.StoppedBy = &userID
+
+	if err := tx.Omit(clause.Associations).Save(&session).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to stop session", err)
+	}
+
+	if err := tx.Model(&models.Computer{}).Where("id = ?", session.ComputerID).
+		Update("status", models.ComputerStatusAvailable).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update computer status", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to stop session", err)
+	}
+
+	h.db.Preload("Computer").Preload("Package").First(&session, "id = ?", session.ID)
+
+	response := toSessionResponse(session, now)
+	h.hub.Publish(realtime.TopicSessions, "session.stopped", response)
+	publishComputerStatus(h.hub, session.Computer)
+
+	return utils.SuccessResponse(c, "Session stopped successfully", response)
+}
+
+// resumeSession moves the planned end time forward by the time spent paused
+func resumeSession(session *models.UsageSession, now time.Time) {
+	paused := now.Sub(*session.PausedAt)
+	session.EndTime = session.EndTime.Add(paused)
+	session.PausedSeconds += int64(paused.Seconds())
+	session.PausedAt = nil
+	session.Status = models.SessionStatusActive
+}
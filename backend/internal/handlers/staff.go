@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
+	"cybercafe-backend/internal/config"
 	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/notify"
 	"cybercafe-backend/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -12,17 +18,19 @@ import (
 )
 
 type StaffHandler struct {
-	db *gorm.DB
+	db       *gorm.DB
+	cfg      *config.Config
+	notifier notify.Notifier
 }
 
-func NewStaffHandler(db *gorm.DB) *StaffHandler {
-	return &StaffHandler{db: db}
+func NewStaffHandler(db *gorm.DB, cfg *config.Config, notifier notify.Notifier) *StaffHandler {
+	return &StaffHandler{db: db, cfg: cfg, notifier: notifier}
 }
 
 type CreateStaffRequest struct {
 	Username   string    `json:"username" validate:"required"`
 	Email      string    `json:"email" validate:"required,email"`
-	Password   string    `json:"password" validate:"required,min=6"`
+	Password   string    `json:"password" validate:"required"`
 	Name       string    `json:"name" validate:"required"`
 	Phone      string    `json:"phone"`
 	Address    string    `json:"address"`
@@ -31,12 +39,34 @@ type CreateStaffRequest struct {
 	RoleID     uuid.UUID `json:"role_id" validate:"required"`
 }
 
+// toStaffResponses converts users to responses carrying their login lockout status
+func (h *StaffHandler) toStaffResponses(users []models.User) []models.UserResponse {
+	usernames := make([]string, 0, len(users))
+	for _, user := range users {
+		usernames = append(usernames, user.Username)
+	}
+	statuses := models.GetLockoutStatuses(h.db, usernames, time.Now(), h.cfg.GetLoginLimits())
+
+	responses := make([]models.UserResponse, 0, len(users))
+	for _, user := range users {
+		response := user.ToResponse()
+		status := statuses[user.Username]
+		response.Lockout = &status
+		responses = append(responses, response)
+	}
+	return responses
+}
+
 func (h *StaffHandler) CreateStaff(c *fiber.Ctx) error {
 	var req CreateStaffRequest
 	if err := c.BodyParser(&req); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
 	}
 
+	if err := h.cfg.GetPasswordPolicy().Validate(req.Password); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
 	// Check if username, email, KTP number, or employee ID already exists
 	var existingUser models.User
 	query := "username = ? OR email = ?"
@@ -72,6 +102,8 @@ func (h *StaffHandler) CreateStaff(c *fiber.Ctx) error {
 		EmployeeID: req.EmployeeID,
 		RoleID:     req.RoleID,
 		IsActive:   true,
+		// The admin knows the initial password, so the staff member must pick their own
+		MustChangePassword: true,
 	}
 
 	if err := user.SetPassword(req.Password); err != nil {
@@ -108,10 +140,7 @@ func (h *StaffHandler) GetAllStaff(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch staff", err)
 	}
 
-	var responses []models.UserResponse
-	for _, user := range users {
-		responses = append(responses, user.ToResponse())
-	}
+	responses := h.toStaffResponses(users)
 
 	meta := utils.PaginationMeta{
 		Page:       page,
@@ -135,7 +164,7 @@ func (h *StaffHandler) GetStaffByID(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "Staff not found", err)
 	}
 
-	return utils.SuccessResponse(c, "Staff retrieved successfully", user.ToResponse())
+	return utils.SuccessResponse(c, "Staff retrieved successfully", h.toStaffResponses([]models.User{user})[0])
 }
 
 type UpdateStaffRequest struct {
@@ -146,6 +175,8 @@ type UpdateStaffRequest struct {
 	KTPNumber  string    `json:"ktp_number"`
 	EmployeeID string    `json:"employee_id"`
 	RoleID     uuid.UUID `json:"role_id"`
+	IsActive   *bool     `json:"is_active"`
+	Password   string    `json:"password"`
 }
 
 func (h *StaffHandler) UpdateStaff(c *fiber.Ctx) error {
@@ -164,10 +195,12 @@ func (h *StaffHandler) UpdateStaff(c *fiber.Ctx) error {
 	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "Staff not found", err)
 	}
+	var role models.Role
+	h.db.Where("id = ?", user.RoleID).First(&role)
+	wasAdmin := user.IsActive && role.HasAdminRights()
 
 	// Check if role exists if provided
 	if req.RoleID != uuid.Nil {
-		var role models.Role
 		if err := h.db.Where("id = ?", req.RoleID).First(&role).Error; err != nil {
 			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid role ID", err)
 		}
@@ -193,14 +226,41 @@ func (h *StaffHandler) UpdateStaff(c *fiber.Ctx) error {
 		user.EmployeeID = req.EmployeeID
 	}
 
+	// Deactivating an account or changing its password signs out all of its sessions
+	revokeTokens := false
+	if req.IsActive != nil {
+		revokeTokens = user.IsActive && !*req.IsActive
+		user.IsActive = *req.IsActive
+	}
+
+	if wasAdmin && !(user.IsActive && role.HasAdminRights()) && !models.ActiveAdminExists(h.db, uuid.Nil, user.ID) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Cannot remove admin rights from the last active administrator", nil)
+	}
+	if req.Password != "" {
+		if err := h.cfg.GetPasswordPolicy().Validate(req.Password); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+		}
+		if err := user.SetPassword(req.Password); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to hash password", err)
+		}
+		user.MustChangePassword = true
+		revokeTokens = true
+	}
+
 	if err := h.db.Save(&user).Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update staff", err)
 	}
 
+	if revokeTokens {
+		if err := models.RevokeAllUserTokens(h.db, user.ID); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke staff sessions", err)
+		}
+	}
+
 	// Load role for response
 	h.db.Preload("Role").First(&user, user.ID)
 
-	return utils.SuccessResponse(c, "Staff updated successfully", user.ToResponse())
+	return utils.SuccessResponse(c, "Staff updated successfully", h.toStaffResponses([]models.User{user})[0])
 }
 
 func (h *StaffHandler) DeleteStaff(c *fiber.Ctx) error {
@@ -215,11 +275,214 @@ func (h *StaffHandler) DeleteStaff(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "Staff not found", err)
 	}
 
+	var role models.Role
+	h.db.Where("id = ?", user.RoleID).First(&role)
+	if user.IsActive && role.HasAdminRights() && !models.ActiveAdminExists(h.db, uuid.Nil, user.ID) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Cannot delete the last active administrator", nil)
+	}
+
 	// Soft delete by setting is_active to false
 	user.IsActive = false
 	if err := h.db.Save(&user).Error; err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete staff", err)
 	}
 
+	if err := models.RevokeAllUserTokens(h.db, user.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke staff sessions", err)
+	}
+
 	return utils.SuccessResponse(c, "Staff deleted successfully", nil)
-}
\ No newline at end of file
+}
+
+// ResetStaffPassword issues a one-time password reset token and delivers it to the staff
+// member through the configured notifier. The token is never returned to the admin.
+func (h *StaffHandler) ResetStaffPassword(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uuid.UUID)
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID", err)
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ? AND is_active = ?", userID, true).First(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Staff not found", err)
+	}
+
+	expire, err := time.ParseDuration(h.cfg.PasswordResetExpire)
+	if err != nil || expire <= 0 {
+		expire = time.Hour
+	}
+
+	token, err := utils.GenerateOpaqueToken(32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate reset token", err)
+	}
+
+	now := time.Now()
+	resetToken := models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: now.Add(expire),
+		CreatedBy: adminID,
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Only the most recent reset token is usable
+	if err := tx.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Update("used_at", now).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create reset token", err)
+	}
+
+	if err := tx.Create(&resetToken).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create reset token", err)
+	}
+
+	auditLog := models.AuditLog{
+		UserID:    adminID,
+		Action:    "PASSWORD_RESET_REQUESTED",
+		Resource:  "/staff/" + user.ID.String(),
+		Details:   fmt.Sprintf("Password reset issued for %s, expires %s", user.Username, resetToken.ExpiresAt.Format(time.RFC3339)),
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+	}
+	if err := tx.Create(&auditLog).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create reset token", err)
+	}
+
+	if err := h.notifier.SendPasswordReset(user, token, resetToken.ExpiresAt); err != nil {
+		tx.Rollback()
+		if errors.Is(err, notify.ErrNotConfigured) {
+			return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Password reset delivery is not configured", err)
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadGateway, "Failed to deliver reset token", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create reset token", err)
+	}
+
+	return utils.SuccessResponse(c, "Password reset sent successfully", fiber.Map{
+		"user_id":    user.ID,
+		"expires_at": resetToken.ExpiresAt,
+	})
+}
+
+// UnlockStaff clears the failed login count and any lockout on a staff account, along
+// with the throttles of the client IPs its recent failed logins came from
+func (h *StaffHandler) UnlockStaff(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uuid.UUID)
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID", err)
+	}
+
+	var user models.User
+	if err := h.db.Preload("Role").Where("id = ?", userID).First(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Staff not found", err)
+	}
+
+	if err := models.ClearLoginThrottle(h.db, models.LoginScopeUsername, user.Username); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to unlock staff", err)
+	}
+
+	// An IP lockout would keep the user out after the account is unlocked, so clear the
+	// IPs that failed logins to this account while their failures can still count
+	limits := h.cfg.GetLoginLimits()
+	lookback := limits.Window
+	if limits.LockoutDuration > lookback {
+		lookback = limits.LockoutDuration
+	}
+	var ipAddresses []string
+	h.db.Model(&models.AuditLog{}).Distinct("ip_address").
+		Where("user_id = ? AND action = ? AND ip_address <> '' AND created_at >= ?", user.ID, "LOGIN_FAILED", time.Now().Add(-lookback)).
+		Pluck("ip_address", &ipAddresses)
+	for _, ipAddress := range ipAddresses {
+		if err := models.ClearLoginThrottle(h.db, models.LoginScopeIP, ipAddress); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to unlock staff", err)
+		}
+	}
+
+	details := "Login lockout cleared for " + user.Username
+	if len(ipAddresses) > 0 {
+		details += "; IP throttles cleared for " + strings.Join(ipAddresses, ", ")
+	}
+	auditLog := models.AuditLog{
+		UserID:    adminID,
+		Action:    "ACCOUNT_UNLOCKED",
+		Resource:  "/staff/" + user.ID.String(),
+		Details:   details,
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+	}
+	h.db.Create(&auditLog)
+
+	return utils.SuccessResponse(c, "Staff unlocked successfully", h.toStaffResponses([]models.User{user})[0])
+}
+
+// ResetStaffTwoFactor removes two-factor authentication from a staff account whose
+// authenticator device was lost, and signs the account out everywhere. Admin roles
+// will be asked to enroll again on their next login.
+func (h *StaffHandler) ResetStaffTwoFactor(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uuid.UUID)
+
+	userID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID", err)
+	}
+	if userID == adminID {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Use the two-factor settings to change your own account", nil)
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Staff not found", err)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := models.ClearTwoFactor(tx, user.ID); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to reset two-factor authentication", err)
+	}
+
+	if err := models.RevokeAllUserTokens(tx, user.ID); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke staff sessions", err)
+	}
+
+	auditLog := models.AuditLog{
+		UserID:    adminID,
+		Action:    "TWO_FACTOR_RESET",
+		Resource:  "/staff/" + user.ID.String(),
+		Details:   "Two-factor authentication reset for " + user.Username,
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+	}
+	if err := tx.Create(&auditLog).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to reset two-factor authentication", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to reset two-factor authentication", err)
+	}
+
+	return utils.SuccessResponse(c, "Two-factor authentication reset successfully", nil)
+}
@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"strconv"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StockHandler struct {
+	db *gorm.DB
+}
+
+func NewStockHandler(db *gorm.DB) *StockHandler {
+	return &StockHandler{db: db}
+}
+
+type CreateStockItemRequest struct {
+	Name            string  `json:"name" validate:"required"`
+	Unit            string  `json:"unit" validate:"required"`
+	Category        string  `json:"category"`
+	MinLevel        float64 `json:"min_level"`
+	UnitCost        int64   `json:"unit_cost"`
+	InitialQuantity float64 `json:"initial_quantity"`
+}
+
+type UpdateStockItemRequest struct {
+	Name     string   `json:"name"`
+	Unit     string   `json:"unit"`
+	Category string   `json:"category"`
+	MinLevel *float64 `json:"min_level"`
+	UnitCost *int64   `json:"unit_cost"`
+	IsActive *bool    `json:"is_active"`
+}
+
+type CreateStockMovementRequest struct {
+	Type     string  `json:"type" validate:"required"` // purchase, consumption, waste, adjustment
+	Quantity float64 `json:"quantity" validate:"required"`
+	UnitCost int64   `json:"unit_cost"`
+	Reason   string  `json:"reason"`
+}
+
+type StockItemResponse struct {
+	models.StockItem
+	Quantity   float64 `json:"quantity"`
+	IsLowStock bool    `json:"is_low_stock"`
+}
+
+func toStockItemResponse(item models.StockItem, onHand float64) StockItemResponse {
+	return StockItemResponse{
+		StockItem:  item,
+		Quantity:   onHand,
+		IsLowStock: onHand < item.MinLevel,
+	}
+}
+
+// CreateStockItem creates a stock item, optionally recording its opening quantity as a purchase
+func (h *StockHandler) CreateStockItem(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req CreateStockItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if req.Name == "" || req.Unit == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Name and unit are required", nil)
+	}
+	if req.MinLevel < 0 || req.UnitCost < 0 || req.InitialQuantity < 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Quantities and costs cannot be negative", nil)
+	}
+
+	var existingItem models.StockItem
+	if err := h.db.Where("name = ?", req.Name).First(&existingItem).Error; err == nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Stock item name already exists", nil)
+	}
+
+	item := models.StockItem{
+		Name:     req.Name,
+		Unit:     req.Unit,
+		Category: req.Category,
+		MinLevel: req.MinLevel,
+		UnitCost: req.UnitCost,
+		IsActive: true,
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Create(&item).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create stock item", err)
+	}
+
+	if req.InitialQuantity > 0 {
+		movement := models.StockMovement{
+			StockItemID: item.ID,
+			Type:        models.StockMovementPurchase,
+			Quantity:    req.InitialQuantity,
+			UnitCost:    req.UnitCost,
+			Reason:      "Opening stock",
+			CreatedBy:   userID,
+		}
+		if err := models.RecordStockMovement(tx, &movement); err != nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to record opening stock", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create stock item", err)
+	}
+
+	return utils.SuccessResponse(c, "Stock item created successfully", toStockItemResponse(item, req.InitialQuantity))
+}
+
+// GetAllStockItems retrieves stock items with their computed on-hand quantities
+func (h *StockHandler) GetAllStockItems(c *fiber.Ctx) error {
+	category := c.Query("category", "")
+	search := c.Query("search", "")
+
+	query := h.db.Model(&models.StockItem{})
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if search != "" {
+		query = query.Where("name LIKE ?", "%"+search+"%")
+	}
+	if c.Query("include_inactive") != "true" {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var items []models.StockItem
+	if err := query.Order("name ASC").Find(&items).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch stock items", err)
+	}
+
+	levels := models.GetOnHandQuantities(h.db)
+	responses := make([]StockItemResponse, 0, len(items))
+	for _, item := range items {
+		responses = append(responses, toStockItemResponse(item, levels[item.ID]))
+	}
+
+	return utils.SuccessResponse(c, "Stock items retrieved successfully", responses)
+}
+
+// GetLowStockItems lists active items whose on-hand quantity is below their minimum level
+func (h *StockHandler) GetLowStockItems(c *fiber.Ctx) error {
+	var items []models.StockItem
+	if err := h.db.Where("is_active = ?", true).Order("name ASC").Find(&items).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch stock items", err)
+	}
+
+	levels := models.GetOnHandQuantities(h.db)
+	responses := make([]StockItemResponse, 0)
+	for _, item := range items {
+		if levels[item.ID] < item.MinLevel {
+			responses = append(responses, toStockItemResponse(item, levels[item.ID]))
+		}
+	}
+
+	return utils.SuccessResponse(c, "Low stock items retrieved successfully", responses)
+}
+
+// GetStockItemByID retrieves a stock item by ID
+func (h *StockHandler) GetStockItemByID(c *fiber.Ctx) error {
+	itemID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid stock item ID", err)
+	}
+
+	var item models.StockItem
+	if err := h.db.Where("id = ?", itemID).First(&item).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Stock item not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch stock item", err)
+	}
+
+	return utils.SuccessResponse(c, "Stock item retrieved successfully", toStockItemResponse(item, models.GetOnHandQuantity(h.db, item.ID)))
+}
+
+// UpdateStockItem updates a stock item's details. Quantity only changes through movements.
+func (h *StockHandler) UpdateStockItem(c *fiber.Ctx) error {
+	itemID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid stock item ID", err)
+	}
+
+	var req UpdateStockItemRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	var item models.StockItem
+	if err := h.db.Where("id = ?", itemID).First(&item).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Stock item not found", err)
+	}
+
+	if req.Name != "" && req.Name != item.Name {
+		var existingItem models.StockItem
+		if err := h.db.Where("name = ? AND id <> ?", req.Name, item.ID).First(&existingItem).Error; err == nil {
+			return utils.ErrorResponse(c, fiber.StatusConflict, "Stock item name already exists", nil)
+		}
+		item.Name = req.Name
+	}
+	if req.Unit != "" {
+		item.Unit = req.Unit
+	}
+	if req.Category != "" {
+		item.Category = req.Category
+	}
+	if req.MinLevel != nil {
+		if *req.MinLevel < 0 {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Minimum level cannot be negative", nil)
+		}
+		item.MinLevel = *req.MinLevel
+	}
+	if req.UnitCost != nil {
+		if *req.UnitCost < 0 {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Unit cost cannot be negative", nil)
+		}
+		item.UnitCost = *req.UnitCost
+	}
+	if req.IsActive != nil {
+		item.IsActive = *req.IsActive
+	}
+
+	if err := h.db.Save(&item).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update stock item", err)
+	}
+
+	return utils.SuccessResponse(c, "Stock item updated successfully", toStockItemResponse(item, models.GetOnHandQuantity(h.db, item.ID)))
+}
+
+// DeleteStockItem deactivates a stock item, keeping its movement history
+func (h *StockHandler) DeleteStockItem(c *fiber.Ctx) error {
+	itemID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid stock item ID", err)
+	}
+
+	var item models.StockItem
+	if err := h.db.Where("id = ?", itemID).First(&item).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Stock item not found", err)
+	}
+
+	// Soft delete by setting is_active to false
+	item.IsActive = false
+	if err := h.db.Save(&item).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete stock item", err)
+	}
+
+	return utils.SuccessResponse(c, "Stock item deleted successfully", nil)
+}
+
+// GetStockMovements returns the movement history of a stock item
+func (h *StockHandler) GetStockMovements(c *fiber.Ctx) error {
+	itemID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid stock item ID", err)
+	}
+
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "20"))
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.StockMovement{}).Where("stock_item_id = ?", itemID)
+	if movementType := c.Query("type", ""); movementType != "" {
+		query = query.Where("type = ?", movementType)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var movements []models.StockMovement
+	if err := query.Preload("Creator").Order("created_at DESC").Offset(offset).Limit(limit).Find(&movements).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch stock movements", err)
+	}
+
+	meta := utils.PaginationMeta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+
+	return utils.PaginatedSuccessResponse(c, "Stock movements retrieved successfully", movements, meta)
+}
+
+// CreateStockMovement records a purchase, consumption, waste or adjustment for a stock item
+func (h *StockHandler) CreateStockMovement(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	itemID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid stock item ID", err)
+	}
+
+	var req CreateStockMovementRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if !models.IsValidStockMovementType(req.Type) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid movement type", nil)
+	}
+	if req.Quantity == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Quantity must not be zero", nil)
+	}
+	if req.Type != models.StockMovementAdjustment && req.Quantity < 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Quantity must be positive; only adjustments may be negative", nil)
+	}
+	if req.UnitCost < 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Unit cost cannot be negative", nil)
+	}
+	if (req.Type == models.StockMovementWaste || req.Type == models.StockMovementAdjustment) && req.Reason == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Reason is required for waste and adjustments", nil)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock the item so concurrent movements see a consistent balance
+	var item models.StockItem
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", itemID).First(&item).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Stock item not found", err)
+	}
+
+	onHand := models.GetOnHandQuantity(tx, item.ID)
+	delta := models.SignedStockQuantity(req.Type, req.Quantity)
+	if delta < 0 && onHand+delta < 0 {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Insufficient stock for this movement", nil)
+	}
+
+	movement := models.StockMovement{
+		StockItemID: item.ID,
+		Type:        req.Type,
+		Quantity:    req.Quantity,
+		UnitCost:    req.UnitCost,
+		Reason:      req.Reason,
+		CreatedBy:   userID,
+	}
+	if movement.UnitCost == 0 {
+		movement.UnitCost = item.UnitCost
+	}
+
+	if err := models.RecordStockMovement(tx, &movement); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to record stock movement", err)
+	}
+
+	if delta < 0 {
+		if err := models.MarkUnavailableMenuItems(tx, []uuid.UUID{item.ID}); err != nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update menu availability", err)
+		}
+	}
+
+	// Keep the item's reference cost in line with the latest purchase price
+	if req.Type == models.StockMovementPurchase && req.UnitCost > 0 && req.UnitCost != item.UnitCost {
+		if err := tx.Model(&item).Update("unit_cost", req.UnitCost).Error; err != nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update unit cost", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to record stock movement", err)
+	}
+
+	return utils.SuccessResponse(c, "Stock movement recorded successfully", fiber.Map{
+		"movement": movement,
+		"item":     toStockItemResponse(item, onHand+delta),
+	})
+}
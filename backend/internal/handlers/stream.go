@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/realtime"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// streamHeartbeat keeps idle connections open through proxies
+const streamHeartbeat = 25 * time.Second
+
+type StreamHandler struct {
+	db  *gorm.DB
+	hub *realtime.Hub
+}
+
+func NewStreamHandler(db *gorm.DB, hub *realtime.Hub) *StreamHandler {
+	return &StreamHandler{db: db, hub: hub}
+}
+
+// streamCredentials identifies the token a stream was opened with, so the stream can be
+// re-checked after the request context is gone
+type streamCredentials struct {
+	userID    uuid.UUID
+	tokenID   string
+	sessionID string
+	issuedAt  time.Time
+	expiresAt time.Time
+}
+
+// allowedTopics derives the topics the user's role may receive from its permissions.
+// Inactive users get none.
+func (h *StreamHandler) allowedTopics(userID uuid.UUID) []realtime.TopicAccess {
+	var role models.Role
+	if err := h.db.Joins("JOIN users ON users.role_id = roles.id").
+		Where("users.id = ? AND users.is_active = ?", userID, true).First(&role).Error; err != nil {
+		return nil
+	}
+	return realtime.TopicsForRole(&role)
+}
+
+// stillAuthorized re-checks a long-lived stream: the token must not have expired or been
+// revoked, and the role must still grant every subscribed topic
+func (h *StreamHandler) stillAuthorized(credentials streamCredentials, subscriber *realtime.Subscriber, now time.Time) bool {
+	if !credentials.expiresAt.IsZero() && now.After(credentials.expiresAt) {
+		return false
+	}
+
+	var user models.User
+	if err := h.db.Select("id", "tokens_valid_after").Where("id = ?", credentials.userID).First(&user).Error; err != nil {
+		return false
+	}
+	if user.TokensValidAfter != nil && credentials.issuedAt.Before(user.TokensValidAfter.Truncate(time.Second)) {
+		return false
+	}
+	if credentials.sessionID != "" {
+		sessionID, err := uuid.Parse(credentials.sessionID)
+		if err != nil || !models.TouchDeviceSession(h.db, sessionID, now) {
+			return false
+		}
+	}
+	if credentials.tokenID != "" {
+		var count int64
+		h.db.Model(&models.RevokedToken{}).Where("token_id = ?", credentials.tokenID).Count(&count)
+		if count > 0 {
+			return false
+		}
+	}
+
+	return subscriber.PermittedBy(h.allowedTopics(credentials.userID))
+}
+
+// Stream opens a Server-Sent Events stream of kitchen, session, computer and attendance
+// events. Use ?topics=orders,computers to narrow the subscription. The topics follow the
+// role's read permissions, and the stream ends with a "revoked" event once the token or
+// those permissions are revoked.
+func (h *StreamHandler) Stream(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+	credentials := streamCredentials{userID: userID}
+	credentials.tokenID, _ = c.Locals("token_id").(string)
+	credentials.sessionID, _ = c.Locals("session_id").(string)
+	credentials.issuedAt, _ = c.Locals("token_issued_at").(time.Time)
+	credentials.expiresAt, _ = c.Locals("token_expires_at").(time.Time)
+
+	var requested []string
+	if topics := c.Query("topics", ""); topics != "" {
+		for _, topic := range strings.Split(topics, ",") {
+			if topic = strings.TrimSpace(topic); topic != "" {
+				requested = append(requested, topic)
+			}
+		}
+	}
+
+	subscriber, err := h.hub.Subscribe(userID, h.allowedTopics(userID), requested)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Cannot subscribe to event stream", err)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer h.hub.Unsubscribe(subscriber)
+
+		ready, _ := json.Marshal(fiber.Map{"topics": subscriber.Topics()})
+		fmt.Fprintf(w, "event: ready\ndata: %s\n\n", ready)
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case event, ok := <-subscriber.Events:
+				if !ok {
+					return
+				}
+				payload, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, payload)
+			case now := <-heartbeat.C:
+				if !h.stillAuthorized(credentials, subscriber, now) {
+					fmt.Fprint(w, "event: revoked\ndata: {}\n\n")
+					w.Flush()
+					return
+				}
+				fmt.Fprint(w, ": ping\n\n")
+			}
+
+			// A failed flush means the client went away
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TimePackageHandler struct {
+	db *gorm.DB
+}
+
+func NewTimePackageHandler(db *gorm.DB) *TimePackageHandler {
+	return &TimePackageHandler{db: db}
+}
+
+type CreateTimePackageRequest struct {
+	Name            string `json:"name" validate:"required"`
+	Description     string `json:"description"`
+	DurationMinutes int    `json:"duration_minutes" validate:"required"`
+	Price           int64  `json:"price" validate:"required"`
+}
+
+type UpdateTimePackageRequest struct {
+	Name            string  `json:"name"`
+	Description     *string `json:"description"`
+	DurationMinutes int     `json:"duration_minutes"`
+	Price           *int64  `json:"price"`
+	IsActive        *bool   `json:"is_active"`
+}
+
+// CreateTimePackage creates a new time package
+func (h *TimePackageHandler) CreateTimePackage(c *fiber.Ctx) error {
+	var req CreateTimePackageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if req.Name == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Package name is required", nil)
+	}
+	if req.DurationMinutes <= 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Duration must be greater than 0", nil)
+	}
+	if req.Price < 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Price cannot be negative", nil)
+	}
+
+	var existingPackage models.TimePackage
+	if err := h.db.Where("name = ?", req.Name).First(&existingPackage).Error; err == nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Package name already exists", nil)
+	}
+
+	pkg := models.TimePackage{
+		Name:            req.Name,
+		Description:     req.Description,
+		DurationMinutes: req.DurationMinutes,
+		Price:           req.Price,
+		IsActive:        true,
+	}
+
+	if err := h.db.Create(&pkg).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create time package", err)
+	}
+
+	return utils.SuccessResponse(c, "Time package created successfully", pkg)
+}
+
+// GetAllTimePackages retrieves all time packages
+func (h *TimePackageHandler) GetAllTimePackages(c *fiber.Ctx) error {
+	query := h.db.Model(&models.TimePackage{})
+
+	if c.Query("active_only") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var packages []models.TimePackage
+	if err := query.Order("duration_minutes ASC").Find(&packages).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch time packages", err)
+	}
+
+	return utils.SuccessResponse(c, "Time packages retrieved successfully", packages)
+}
+
+// GetTimePackageByID retrieves a time package by ID
+func (h *TimePackageHandler) GetTimePackageByID(c *fiber.Ctx) error {
+	packageID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid package ID", err)
+	}
+
+	var pkg models.TimePackage
+	if err := h.db.Where("id = ?", packageID).First(&pkg).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Time package not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch time package", err)
+	}
+
+	return utils.SuccessResponse(c, "Time package retrieved successfully", pkg)
+}
+
+// UpdateTimePackage updates a time package. Running sessions keep the price they started with.
+func (h *TimePackageHandler) UpdateTimePackage(c *fiber.Ctx) error {
+	packageID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid package ID", err)
+	}
+
+	var req UpdateTimePackageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	var pkg models.TimePackage
+	if err := h.db.Where("id = ?", packageID).First(&pkg).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Time package not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch time package", err)
+	}
+
+	if req.Name != "" {
+		pkg.Name = req.Name
+	}
+	if req.Description != nil {
+		pkg.Description = *req.Description
+	}
+	if req.DurationMinutes < 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Duration must be greater than 0", nil)
+	}
+	if req.DurationMinutes > 0 {
+		pkg.DurationMinutes = req.DurationMinutes
+	}
+	if req.Price != nil {
+		if *req.Price < 0 {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Price cannot be negative", nil)
+		}
+		pkg.Price = *req.Price
+	}
+	if req.IsActive != nil {
+		pkg.IsActive = *req.IsActive
+	}
+
+	if err := h.db.Save(&pkg).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update time package", err)
+	}
+
+	return utils.SuccessResponse(c, "Time package updated successfully", pkg)
+}
+
+// DeleteTimePackage deletes a time package that has never been used
+func (h *TimePackageHandler) DeleteTimePackage(c *fiber.Ctx) error {
+	packageID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid package ID", err)
+	}
+
+	var sessionCount int64
+	h.db.Model(&models.UsageSession{}).Where("package_id = ?", packageID).Count(&sessionCount)
+	if sessionCount > 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Cannot delete a package that has sessions, deactivate it instead", nil)
+	}
+
+	if err := h.db.Delete(&models.TimePackage{}, "id = ?", packageID).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete time package", err)
+	}
+
+	return utils.SuccessResponse(c, "Time package deleted successfully", nil)
+}
@@ -0,0 +1,637 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errTransactionNotFound = errors.New("Transaction not found")
+	errNotReversible       = errors.New("Only sales and purchases can be reversed")
+	errTopUpSpent          = errors.New("Member balance is lower than the top-up being reversed")
+	errNoOpenShift         = errors.New("Open a cashier shift before taking or paying out cash")
+)
+
+type TransactionHandler struct {
+	db *gorm.DB
+}
+
+func NewTransactionHandler(db *gorm.DB) *TransactionHandler {
+	return &TransactionHandler{db: db}
+}
+
+type CreateSaleRequest struct {
+	SourceType    string     `json:"source_type" validate:"required"`
+	SourceID      *uuid.UUID `json:"source_id"`
+	CustomerID    *uuid.UUID `json:"customer_id"`
+	Amount        int64      `json:"amount"`
+	PaymentMethod string     `json:"payment_method" validate:"required"`
+	Description   string     `json:"description"`
+}
+
+type CreatePurchaseRequest struct {
+	Supplier      string `json:"supplier" validate:"required"`
+	Description   string `json:"description"`
+	Amount        int64  `json:"amount" validate:"required"`
+	PaymentMethod string `json:"payment_method" validate:"required"`
+}
+
+type VoidTransactionRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+type RefundTransactionRequest struct {
+	Amount        int64  `json:"amount" validate:"required"`
+	PaymentMethod string `json:"payment_method"`
+	Reason        string `json:"reason" validate:"required"`
+}
+
+type TransactionResponse struct {
+	models.Transaction
+	ReversedAmount int64                `json:"reversed_amount"`
+	NetAmount      int64                `json:"net_amount"`
+	Reversals      []models.Transaction `json:"reversals,omitempty"`
+}
+
+type TransactionSummary struct {
+	Sales           int64            `json:"sales"`
+	Purchases       int64            `json:"purchases"`
+	Reversals       int64            `json:"reversals"`
+	Net             int64            `json:"net"`
+	ByPaymentMethod map[string]int64 `json:"by_payment_method"`
+}
+
+// CreateSale records a payment for a session, an order or a member balance top-up.
+// Session and order amounts are taken from the source; top-ups use the package price
+// when source_id names a time package, otherwise the requested amount.
+func (h *TransactionHandler) CreateSale(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req CreateSaleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if !models.IsValidPaymentMethod(req.PaymentMethod) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid payment method", nil)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	transaction := models.Transaction{
+		Type:          models.TransactionSale,
+		SourceType:    req.SourceType,
+		SourceID:      req.SourceID,
+		CustomerID:    req.CustomerID,
+		Description:   req.Description,
+		PaymentMethod: req.PaymentMethod,
+		CashierID:     userID,
+	}
+
+	switch req.SourceType {
+	case models.TransactionSourceSession:
+		if req.SourceID == nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Session ID is required", nil)
+		}
+		var session models.UsageSession
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", *req.SourceID).First(&session).Error; err != nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found", err)
+		}
+		if session.IsOpen() {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Session must be stopped before payment", nil)
+		}
+		transaction.Amount = session.TotalAmount
+		if transaction.CustomerID == nil {
+			transaction.CustomerID = session.CustomerID
+		}
+	case models.TransactionSourceOrder:
+		if req.SourceID == nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Order ID is required", nil)
+		}
+		var order models.Order
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", *req.SourceID).First(&order).Error; err != nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Order not found", err)
+		}
+		if order.Status == models.OrderStatusCancelled {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Cannot take payment for a cancelled order", nil)
+		}
+		transaction.Amount = order.Total
+		if transaction.CustomerID == nil {
+			transaction.CustomerID = order.CustomerID
+		}
+	case models.TransactionSourceTopUp:
+		if req.CustomerID == nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Customer is required for a top-up", nil)
+		}
+		if req.PaymentMethod == models.PaymentMemberBalance {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "A top-up cannot be paid from member balance", nil)
+		}
+		transaction.Amount = req.Amount
+		if req.SourceID != nil {
+			var pkg models.TimePackage
+			if err := tx.Where("id = ? AND is_active = ?", *req.SourceID, true).First(&pkg).Error; err != nil {
+				tx.Rollback()
+				return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid or inactive package", err)
+			}
+			transaction.Amount = pkg.Price
+			if transaction.Description == "" {
+				transaction.Description = pkg.Name
+			}
+		}
+	default:
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Source type must be session, order or topup", nil)
+	}
+
+	if transaction.Amount <= 0 {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Amount must be greater than 0", nil)
+	}
+
+	if req.SourceType != models.TransactionSourceTopUp && models.IsSourcePaid(tx, req.SourceType, *req.SourceID) {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusConflict, "This "+req.SourceType+" has already been paid", nil)
+	}
+
+	if transaction.CustomerID != nil {
+		var customer models.Customer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND is_active = ?", *transaction.CustomerID, true).First(&customer).Error; err != nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid or inactive customer", err)
+		}
+	}
+
+	if req.PaymentMethod == models.PaymentMemberBalance {
+		if transaction.CustomerID == nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Customer is required to pay from member balance", nil)
+		}
+		if models.GetMemberBalance(tx, *transaction.CustomerID) < transaction.Amount {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Insufficient member balance", nil)
+		}
+	}
+
+	if err := h.attachShift(tx, &transaction); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	if err := tx.Create(&transaction).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to record sale", err)
+	}
+
+	// Sessions and orders earn loyalty points when they are paid, not when they close
+	if req.SourceType != models.TransactionSourceTopUp && transaction.CustomerID != nil {
+		if _, err := models.EarnPoints(tx, *transaction.CustomerID, &transaction, transaction.Amount, userID); err != nil {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to record loyalty points", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to record sale", err)
+	}
+
+	return utils.SuccessResponse(c, "Sale recorded successfully", h.toTransactionResponse(transaction))
+}
+
+// CreatePurchase records money paid to a supplier
+func (h *TransactionHandler) CreatePurchase(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req CreatePurchaseRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	if req.Supplier == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Supplier is required", nil)
+	}
+	if req.Amount <= 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Amount must be greater than 0", nil)
+	}
+	if !models.IsValidPaymentMethod(req.PaymentMethod) || req.PaymentMethod == models.PaymentMemberBalance {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid payment method", nil)
+	}
+
+	transaction := models.Transaction{
+		Type:          models.TransactionPurchase,
+		SourceType:    models.TransactionSourceSupplier,
+		Supplier:      req.Supplier,
+		Description:   req.Description,
+		Amount:        -req.Amount,
+		PaymentMethod: req.PaymentMethod,
+		CashierID:     userID,
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := h.attachShift(tx, &transaction); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	if err := tx.Create(&transaction).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to record purchase", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to record purchase", err)
+	}
+
+	return utils.SuccessResponse(c, "Purchase recorded successfully", h.toTransactionResponse(transaction))
+}
+
+// GetAllTransactions retrieves ledger entries with optional filters
+func (h *TransactionHandler) GetAllTransactions(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+
+	offset := (page - 1) * limit
+
+	query, err := h.filteredQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var transactions []models.Transaction
+	if err := query.Preload("Cashier").Order("created_at DESC").Offset(offset).Limit(limit).Find(&transactions).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch transactions", err)
+	}
+
+	meta := utils.PaginationMeta{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+	}
+
+	return utils.PaginatedSuccessResponse(c, "Transactions retrieved successfully", transactions, meta)
+}
+
+// GetTransactionSummary totals the ledger by type and payment method
+func (h *TransactionHandler) GetTransactionSummary(c *fiber.Ctx) error {
+	query, err := h.filteredQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+	}
+
+	var rows []struct {
+		Type          string
+		PaymentMethod string
+		Total         int64
+	}
+	if err := query.Select("type, payment_method, COALESCE(SUM(amount), 0) AS total").
+		Group("type, payment_method").Scan(&rows).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to summarize transactions", err)
+	}
+
+	summary := TransactionSummary{ByPaymentMethod: make(map[string]int64)}
+	for _, row := range rows {
+		switch row.Type {
+		case models.TransactionSale:
+			summary.Sales += row.Total
+		case models.TransactionPurchase:
+			summary.Purchases += row.Total
+		default:
+			summary.Reversals += row.Total
+		}
+		summary.Net += row.Total
+		summary.ByPaymentMethod[row.PaymentMethod] += row.Total
+	}
+
+	return utils.SuccessResponse(c, "Transaction summary retrieved successfully", summary)
+}
+
+// GetTransactionByID retrieves a transaction with its voids and refunds
+func (h *TransactionHandler) GetTransactionByID(c *fiber.Ctx) error {
+	transactionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid transaction ID", err)
+	}
+
+	var transaction models.Transaction
+	if err := h.db.Preload("Cashier").Preload("Original").Where("id = ?", transactionID).First(&transaction).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Transaction not found", nil)
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch transaction", err)
+	}
+
+	if !canAccessUser(c, transaction.CashierID) {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "You can only view your own transactions", nil)
+	}
+
+	return utils.SuccessResponse(c, "Transaction retrieved successfully", h.toTransactionResponse(transaction))
+}
+
+// VoidTransaction reverses the remaining amount of a sale or purchase
+func (h *TransactionHandler) VoidTransaction(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	transactionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid transaction ID", err)
+	}
+
+	var req VoidTransactionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+	if req.Reason == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Reason is required to void a transaction", nil)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	original, status, err := h.lockReversible(tx, transactionID)
+	if err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, status, err.Error(), nil)
+	}
+
+	remaining := absAmount(original.Amount) - models.GetReversedAmount(tx, original.ID)
+	if remaining <= 0 {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Transaction has already been fully reversed", nil)
+	}
+
+	void := h.reversalOf(original, models.TransactionVoid, remaining, original.PaymentMethod, req.Reason, userID)
+	if err := h.checkTopUpReversal(tx, original, remaining); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	if err := h.attachShift(tx, &void); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	if err := tx.Create(&void).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to void transaction", err)
+	}
+
+	if err := h.reverseLoyaltyPoints(tx, original, req.Reason, userID); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to reverse loyalty points", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to void transaction", err)
+	}
+
+	return utils.SuccessResponse(c, "Transaction voided successfully", h.toTransactionResponse(void))
+}
+
+// RefundTransaction returns part or all of a sale to the customer
+func (h *TransactionHandler) RefundTransaction(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	transactionID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid transaction ID", err)
+	}
+
+	var req RefundTransactionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+	if req.Reason == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Reason is required to refund a transaction", nil)
+	}
+	if req.Amount <= 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Refund amount must be greater than 0", nil)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	original, status, err := h.lockReversible(tx, transactionID)
+	if err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, status, err.Error(), nil)
+	}
+	if original.Type != models.TransactionSale {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Only sales can be refunded", nil)
+	}
+
+	remaining := original.Amount - models.GetReversedAmount(tx, original.ID)
+	if req.Amount > remaining {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Refund exceeds the amount still refundable", nil)
+	}
+
+	// Member balance payments are always refunded to the balance
+	paymentMethod := original.PaymentMethod
+	if req.PaymentMethod != "" && original.PaymentMethod != models.PaymentMemberBalance {
+		if !models.IsValidPaymentMethod(req.PaymentMethod) {
+			tx.Rollback()
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid payment method", nil)
+		}
+		paymentMethod = req.PaymentMethod
+	}
+
+	if err := h.checkTopUpReversal(tx, original, req.Amount); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	refund := h.reversalOf(original, models.TransactionRefund, req.Amount, paymentMethod, req.Reason, userID)
+	if err := h.attachShift(tx, &refund); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error(), nil)
+	}
+
+	if err := tx.Create(&refund).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to refund transaction", err)
+	}
+
+	if err := h.reverseLoyaltyPoints(tx, original, req.Reason, userID); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to reverse loyalty points", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to refund transaction", err)
+	}
+
+	return utils.SuccessResponse(c, "Transaction refunded successfully", h.toTransactionResponse(refund))
+}
+
+func (h *TransactionHandler) filteredQuery(c *fiber.Ctx) (*gorm.DB, error) {
+	query := h.db.Model(&models.Transaction{})
+
+	if txType := c.Query("type", ""); txType != "" {
+		query = query.Where("type = ?", txType)
+	}
+	if method := c.Query("payment_method", ""); method != "" {
+		query = query.Where("payment_method = ?", method)
+	}
+	if sourceType := c.Query("source_type", ""); sourceType != "" {
+		query = query.Where("source_type = ?", sourceType)
+	}
+	if shiftID := c.Query("shift_id", ""); shiftID != "" {
+		query = query.Where("shift_id = ?", shiftID)
+	}
+	if cashierID := scopedUserFilter(c, c.Query("cashier_id", "")); cashierID != "" {
+		query = query.Where("cashier_id = ?", cashierID)
+	}
+	if customerID := c.Query("customer_id", ""); customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if startDate := c.Query("start_date", ""); startDate != "" {
+		start, err := time.Parse("2006-01-02", startDate)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("created_at >= ?", start)
+	}
+	if endDate := c.Query("end_date", ""); endDate != "" {
+		end, err := time.Parse("2006-01-02", endDate)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("created_at < ?", end.AddDate(0, 0, 1))
+	}
+
+	return query, nil
+}
+
+// lockReversible loads a sale or purchase for update so concurrent reversals cannot overshoot
+func (h *TransactionHandler) lockReversible(tx *gorm.DB, transactionID uuid.UUID) (models.Transaction, int, error) {
+	var original models.Transaction
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", transactionID).First(&original).Error; err != nil {
+		return original, fiber.StatusNotFound, errTransactionNotFound
+	}
+	if original.Type != models.TransactionSale && original.Type != models.TransactionPurchase {
+		return original, fiber.StatusBadRequest, errNotReversible
+	}
+	return original, 0, nil
+}
+
+// checkTopUpReversal stops a top-up reversal from taking the member balance below zero
+func (h *TransactionHandler) checkTopUpReversal(tx *gorm.DB, original models.Transaction, amount int64) error {
+	if original.SourceType != models.TransactionSourceTopUp || original.CustomerID == nil {
+		return nil
+	}
+	if models.GetMemberBalance(tx, *original.CustomerID) < amount {
+		return errTopUpSpent
+	}
+	return nil
+}
+
+// reverseLoyaltyPoints takes back the points a session or order sale earned for the part
+// of its payment that has now been voided or refunded
+func (h *TransactionHandler) reverseLoyaltyPoints(tx *gorm.DB, original models.Transaction, reason string, actor uuid.UUID) error {
+	if original.Type != models.TransactionSale || original.SourceID == nil {
+		return nil
+	}
+	if original.SourceType != models.TransactionSourceSession && original.SourceType != models.TransactionSourceOrder {
+		return nil
+	}
+	paid := original.Amount - models.GetReversedAmount(tx, original.ID)
+	_, err := models.ReverseEarnedPoints(tx, &original, paid, reason, actor)
+	return err
+}
+
+// attachShift links a cash transaction to the cashier's open shift so the drawer can be reconciled.
+// The shift is share-locked in the caller's transaction, so a concurrent close waits for the
+// transaction to commit, and a shift closed meanwhile no longer counts as open.
+func (h *TransactionHandler) attachShift(tx *gorm.DB, transaction *models.Transaction) error {
+	if transaction.PaymentMethod != models.PaymentCash {
+		return nil
+	}
+	shift, err := models.GetOpenShift(tx.Clauses(clause.Locking{Strength: "SHARE"}), transaction.CashierID)
+	if err != nil {
+		return errNoOpenShift
+	}
+	transaction.ShiftID = &shift.ID
+	return nil
+}
+
+// reversalOf builds a void or refund entry that mirrors the original with the opposite sign
+func (h *TransactionHandler) reversalOf(original models.Transaction, txType string, amount int64, paymentMethod, reason string, actor uuid.UUID) models.Transaction {
+	if original.Amount > 0 {
+		amount = -amount
+	}
+	return models.Transaction{
+		Type:          txType,
+		SourceType:    original.SourceType,
+		SourceID:      original.SourceID,
+		CustomerID:    original.CustomerID,
+		Supplier:      original.Supplier,
+		Description:   original.Description,
+		Amount:        amount,
+		PaymentMethod: paymentMethod,
+		OriginalID:    &original.ID,
+		Reason:        reason,
+		CashierID:     actor,
+	}
+}
+
+func (h *TransactionHandler) toTransactionResponse(transaction models.Transaction) TransactionResponse {
+	response := TransactionResponse{Transaction: transaction, NetAmount: transaction.Amount}
+
+	if transaction.Type == models.TransactionSale || transaction.Type == models.TransactionPurchase {
+		h.db.Where("original_id = ?", transaction.ID).Order("created_at ASC").Find(&response.Reversals)
+		for _, reversal := range response.Reversals {
+			response.ReversedAmount += absAmount(reversal.Amount)
+			response.NetAmount += reversal.Amount
+		}
+	}
+
+	return response
+}
+
+func absAmount(amount int64) int64 {
+	if amount < 0 {
+		return -amount
+	}
+	return amount
+}
@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"time"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// recoveryCodeCount is how many recovery codes are issued at a time
+const recoveryCodeCount = 10
+
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	PreAuthToken      string    `json:"pre_auth_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+type VerifyTwoFactorRequest struct {
+	PreAuthToken string `json:"pre_auth_token" validate:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// twoFactorChallenge answers a correct password with a pre-auth token for the second step
+func (h *AuthHandler) twoFactorChallenge(c *fiber.Ctx, user models.User) error {
+	expire, err := time.ParseDuration(h.cfg.PreAuthTokenExpire)
+	if err != nil || expire <= 0 {
+		expire = 5 * time.Minute
+	}
+
+	token, err := utils.GeneratePreAuthToken(user.ID, user.Username, h.cfg.JWTSecret, expire)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate token", err)
+	}
+
+	return utils.SuccessResponse(c, "Two-factor code required", TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		PreAuthToken:      token,
+		ExpiresAt:         time.Now().Add(expire),
+	})
+}
+
+// VerifyTwoFactor completes a login with a TOTP code or a recovery code and the
+// pre-auth token returned by Login
+func (h *AuthHandler) VerifyTwoFactor(c *fiber.Ctx) error {
+	var req VerifyTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Code or recovery code is required", nil)
+	}
+
+	claims, err := utils.ValidateToken(req.PreAuthToken, h.cfg.JWTSecret)
+	if err != nil || claims.Purpose != utils.TokenPurposeTwoFactor {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid or expired pre-auth token", nil)
+	}
+
+	var revoked int64
+	h.db.Model(&models.RevokedToken{}).Where("token_id = ?", claims.ID).Count(&revoked)
+	if revoked > 0 {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid or expired pre-auth token", nil)
+	}
+
+	now := time.Now()
+	limits := h.cfg.GetLoginLimits()
+	ipAddress := c.IP()
+	userAgent := c.Get("User-Agent")
+
+	// Code guessing counts against the same limits as password guessing
+	usernameThrottle := models.GetLoginThrottle(h.db, models.LoginScopeUsername, claims.Username)
+	if wait := usernameThrottle.RetryAfter(now, limits); wait > 0 {
+		return loginThrottled(c, wait, usernameThrottle.IsLocked(now))
+	}
+
+	var user models.User
+	if err := h.db.Preload("Role").Where("id = ? AND is_active = ?", claims.UserID, true).First(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid or expired pre-auth token", nil)
+	}
+	if !user.TOTPEnabled {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Two-factor authentication is not enabled", nil)
+	}
+
+	usedRecoveryCode := false
+	if req.Code != "" {
+		step, ok := utils.ValidateTOTP(user.TOTPSecret, req.Code, now)
+		if !ok || step <= user.TOTPLastStep {
+			h.recordLoginFailure(user.Username, ipAddress, userAgent, &user, "Invalid two-factor code", now, limits)
+			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid two-factor code", nil)
+		}
+		h.db.Model(&models.User{}).Where("id = ?", user.ID).Update("totp_last_step", step)
+	} else {
+		codeHash := utils.HashToken(utils.NormalizeRecoveryCode(req.RecoveryCode))
+		if !models.UseRecoveryCode(h.db, user.ID, codeHash, now) {
+			h.recordLoginFailure(user.Username, ipAddress, userAgent, &user, "Invalid recovery code", now, limits)
+			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid recovery code", nil)
+		}
+		usedRecoveryCode = true
+	}
+
+	// The pre-auth token is spent once the second step succeeds
+	h.db.Create(&models.RevokedToken{TokenID: claims.ID, UserID: user.ID, ExpiresAt: claims.ExpiresAt.Time})
+	models.ClearLoginThrottle(h.db, models.LoginScopeUsername, user.Username)
+
+	response, err := h.startSession(c, user)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate token", err)
+	}
+
+	details := "User logged in with two-factor code"
+	if usedRecoveryCode {
+		details = "User logged in with a recovery code"
+	}
+	auditLog := models.AuditLog{
+		UserID:    user.ID,
+		Action:    "LOGIN_SUCCESS",
+		Resource:  "/auth/2fa/verify",
+		Details:   details,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}
+	h.db.Create(&auditLog)
+
+	return utils.SuccessResponse(c, "Login successful", response)
+}
+
+// SetupTwoFactor generates a new TOTP secret for the current user. The secret stays
+// pending until EnableTwoFactor confirms a code from the authenticator app.
+func (h *AuthHandler) SetupTwoFactor(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var user models.User
+	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", err)
+	}
+	if user.TOTPEnabled {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Two-factor authentication is already enabled", nil)
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate secret", err)
+	}
+
+	if err := h.db.Model(&user).Update("totp_secret", secret).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to start two-factor setup", err)
+	}
+
+	return utils.SuccessResponse(c, "Two-factor setup started", TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(h.cfg.TOTPIssuer, user.Username, secret),
+	})
+}
+
+// EnableTwoFactor confirms the pending secret with a code and returns the recovery codes.
+// The codes are only shown this once.
+func (h *AuthHandler) EnableTwoFactor(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", err)
+	}
+	if user.TOTPEnabled {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Two-factor authentication is already enabled", nil)
+	}
+	if user.TOTPSecret == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Start two-factor setup first", nil)
+	}
+
+	step, ok := utils.ValidateTOTP(user.TOTPSecret, req.Code, time.Now())
+	if !ok {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid two-factor code", nil)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate recovery codes", err)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Model(&user).Updates(map[string]interface{}{
+		"totp_enabled":   true,
+		"totp_last_step": step,
+	}).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to enable two-factor authentication", err)
+	}
+
+	if err := models.ReplaceRecoveryCodes(tx, user.ID, hashes); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to enable two-factor authentication", err)
+	}
+
+	auditLog := models.AuditLog{
+		UserID:    user.ID,
+		Action:    "TWO_FACTOR_ENABLED",
+		Resource:  "/auth/2fa/enable",
+		Details:   "Two-factor authentication enabled",
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+	}
+	if err := tx.Create(&auditLog).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to enable two-factor authentication", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to enable two-factor authentication", err)
+	}
+
+	return utils.SuccessResponse(c, "Two-factor authentication enabled", RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTwoFactor turns two-factor authentication off after checking the password and
+// a current code. Roles for which it is mandatory cannot turn it off.
+func (h *AuthHandler) DisableTwoFactor(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req DisableTwoFactorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	var user models.User
+	if err := h.db.Preload("Role").Where("id = ?", userID).First(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", err)
+	}
+	if !user.TOTPEnabled {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Two-factor authentication is not enabled", nil)
+	}
+	if h.cfg.IsTwoFactorRequiredForAdmins() && user.Role.HasAdminRights() {
+		return utils.ErrorResponse(c, fiber.StatusForbidden, "Two-factor authentication is mandatory for your role", nil)
+	}
+
+	if !user.CheckPassword(req.Password) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Password is incorrect", nil)
+	}
+	if _, ok := utils.ValidateTOTP(user.TOTPSecret, req.Code, time.Now()); !ok {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid two-factor code", nil)
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := models.ClearTwoFactor(tx, user.ID); err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+	}
+
+	auditLog := models.AuditLog{
+		UserID:    user.ID,
+		Action:    "TWO_FACTOR_DISABLED",
+		Resource:  "/auth/2fa/disable",
+		Details:   "Two-factor authentication disabled",
+		IPAddress: c.IP(),
+		UserAgent: c.Get("User-Agent"),
+	}
+	if err := tx.Create(&auditLog).Error; err != nil {
+		tx.Rollback()
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+	}
+
+	return utils.SuccessResponse(c, "Two-factor authentication disabled", nil)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after checking a current code
+func (h *AuthHandler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var req TwoFactorCodeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	var user models.User
+	if err := h.db.Where("id = ?", userID).First(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", err)
+	}
+	if !user.TOTPEnabled {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Two-factor authentication is not enabled", nil)
+	}
+	if _, ok := utils.ValidateTOTP(user.TOTPSecret, req.Code, time.Now()); !ok {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid two-factor code", nil)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate recovery codes", err)
+	}
+
+	if err := models.ReplaceRecoveryCodes(h.db, user.ID, hashes); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to generate recovery codes", err)
+	}
+
+	return utils.SuccessResponse(c, "Recovery codes generated successfully", RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// GetTwoFactorStatus reports whether two-factor is enabled, required, and how many
+// recovery codes remain
+func (h *AuthHandler) GetTwoFactorStatus(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	var user models.User
+	if err := h.db.Preload("Role").Where("id = ?", userID).First(&user).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "User not found", err)
+	}
+
+	return utils.SuccessResponse(c, "Two-factor status retrieved successfully", fiber.Map{
+		"enabled":                  user.TOTPEnabled,
+		"required":                 h.cfg.IsTwoFactorRequiredForAdmins() && user.Role.HasAdminRights(),
+		"recovery_codes_remaining": models.CountUnusedRecoveryCodes(h.db, user.ID),
+	})
+}
+
+// newRecoveryCodes returns fresh recovery codes and the hashes to store for them
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashes = append(hashes, utils.HashToken(code))
+	}
+	return codes, hashes, nil
+}
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WorkShiftHandler struct {
+	db *gorm.DB
+}
+
+func NewWorkShiftHandler(db *gorm.DB) *WorkShiftHandler {
+	return &WorkShiftHandler{db: db}
+}
+
+type CreateShiftRequest struct {
+	Name         string `json:"name" validate:"required"`
+	StartTime    string `json:"start_time" validate:"required"` // HH:MM
+	EndTime      string `json:"end_time" validate:"required"`   // HH:MM, at or before the start for overnight shifts
+	GraceMinutes int    `json:"grace_minutes"`
+	BreakMinutes int    `json:"break_minutes"`
+	IsDefault    bool   `json:"is_default"`
+}
+
+type UpdateShiftRequest struct {
+	Name         *string `json:"name"`
+	StartTime    *string `json:"start_time"`
+	EndTime      *string `json:"end_time"`
+	GraceMinutes *int    `json:"grace_minutes"`
+	BreakMinutes *int    `json:"break_minutes"`
+	IsDefault    *bool   `json:"is_default"`
+	IsActive     *bool   `json:"is_active"`
+}
+
+type SetRosterRequest struct {
+	UserIDs  []uuid.UUID `json:"user_ids" validate:"required"`
+	ShiftID  uuid.UUID   `json:"shift_id" validate:"required"`
+	FromDate string      `json:"from_date" validate:"required"` // YYYY-MM-DD
+	ToDate   string      `json:"to_date"`                       // YYYY-MM-DD, defaults to the start date
+	Weekdays []int       `json:"weekdays"`                      // 0 (Sunday) to 6; empty means every day
+	Notes    string      `json:"notes"`
+}
+
+// maxRosterDays bounds how many days one roster request may fill
+const maxRosterDays = 92
+
+// GetShifts lists shift definitions
+func (h *WorkShiftHandler) GetShifts(c *fiber.Ctx) error {
+	query := h.db.Model(&models.Shift{})
+	if isActive := c.Query("is_active", ""); isActive != "" {
+		active, _ := strconv.ParseBool(isActive)
+		query = query.Where("is_active = ?", active)
+	}
+
+	var shifts []models.Shift
+	if err := query.Order("start_time ASC").Find(&shifts).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch shifts", err)
+	}
+
+	return utils.SuccessResponse(c, "Shifts retrieved successfully", shifts)
+}
+
+// CreateShift adds a shift definition
+func (h *WorkShiftHandler) CreateShift(c *fiber.Ctx) error {
+	var req CreateShiftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	shift := models.Shift{
+		Name:         req.Name,
+		StartTime:    req.StartTime,
+		EndTime:      req.EndTime,
+		GraceMinutes: req.GraceMinutes,
+		BreakMinutes: req.BreakMinutes,
+		IsDefault:    req.IsDefault,
+		IsActive:     true,
+	}
+	if err := shift.Validate(); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid shift", err)
+	}
+
+	var existing int64
+	h.db.Model(&models.Shift{}).Where("name = ?", shift.Name).Count(&existing)
+	if existing > 0 {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "A shift with this name already exists", nil)
+	}
+
+	if err := h.saveShift(&shift, true); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create shift", err)
+	}
+
+	return utils.SuccessResponse(c, "Shift created successfully", shift)
+}
+
+// UpdateShift changes a shift definition. The change applies to past rostered days
+// too, since statuses are evaluated when reports are read.
+func (h *WorkShiftHandler) UpdateShift(c *fiber.Ctx) error {
+	shiftID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid shift ID", err)
+	}
+
+	var req UpdateShiftRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	var shift models.Shift
+	if err := h.db.Where("id = ?", shiftID).First(&shift).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Shift not found", err)
+	}
+
+	if req.Name != nil {
+		shift.Name = *req.Name
+	}
+	if req.StartTime != nil {
+		shift.StartTime = *req.StartTime
+	}
+	if req.EndTime != nil {
+		shift.EndTime = *req.EndTime
+	}
+	if req.GraceMinutes != nil {
+		shift.GraceMinutes = *req.GraceMinutes
+	}
+	if req.BreakMinutes != nil {
+		shift.BreakMinutes = *req.BreakMinutes
+	}
+	if req.IsDefault != nil {
+		shift.IsDefault = *req.IsDefault
+	}
+	if req.IsActive != nil {
+		shift.IsActive = *req.IsActive
+	}
+	if err := shift.Validate(); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid shift", err)
+	}
+	if shift.IsDefault && !shift.IsActive {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "The default shift cannot be deactivated", nil)
+	}
+
+	var existing int64
+	h.db.Model(&models.Shift{}).Where("name = ? AND id <> ?", shift.Name, shift.ID).Count(&existing)
+	if existing > 0 {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "A shift with this name already exists", nil)
+	}
+
+	if err := h.saveShift(&shift, false); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update shift", err)
+	}
+
+	return utils.SuccessResponse(c, "Shift updated successfully", shift)
+}
+
+// saveShift stores the shift, making sure at most one shift is the default
+func (h *WorkShiftHandler) saveShift(shift *models.Shift, create bool) error {
+	return h.db.Transaction(func(tx *gorm.DB) error {
+		if shift.IsDefault {
+			if err := tx.Model(&models.Shift{}).Where("is_default = ? AND id <> ?", true, shift.ID).
+				Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		if create {
+			return tx.Create(shift).Error
+		}
+		return tx.Save(shift).Error
+	})
+}
+
+// DeleteShift removes a shift that has never been rostered; rostered shifts should be
+// deactivated instead so past reports keep their schedule
+func (h *WorkShiftHandler) DeleteShift(c *fiber.Ctx) error {
+	shiftID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid shift ID", err)
+	}
+
+	var shift models.Shift
+	if err := h.db.Where("id = ?", shiftID).First(&shift).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Shift not found", err)
+	}
+
+	var rostered int64
+	h.db.Model(&models.ShiftRoster{}).Where("shift_id = ?", shift.ID).Count(&rostered)
+	if rostered > 0 {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Shift is used in the roster; deactivate it instead", nil)
+	}
+
+	if err := h.db.Delete(&shift).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete shift", err)
+	}
+
+	return utils.SuccessResponse(c, "Shift deleted successfully", nil)
+}
+
+// GetRoster lists roster entries in a date range; own-scoped users only see their own
+func (h *WorkShiftHandler) GetRoster(c *fiber.Ctx) error {
+	userID := scopedUserFilter(c, c.Query("user_id", ""))
+	from := c.Query("from", time.Now().Format("2006-01-02"))
+	to := c.Query("to", time.Now().AddDate(0, 0, 6).Format("2006-01-02"))
+
+	fromDate, toDate, err := parseDateRange(from, to)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+	}
+	if toDate.Before(fromDate) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "End date cannot be before the start date", nil)
+	}
+
+	query := h.db.Model(&models.ShiftRoster{}).
+		Where("date BETWEEN ? AND ?", fromDate.Format("2006-01-02"), toDate.Format("2006-01-02"))
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var entries []models.ShiftRoster
+	if err := query.Preload("User").Preload("Shift").Order("date ASC").Find(&entries).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch roster", err)
+	}
+
+	return utils.SuccessResponse(c, "Roster retrieved successfully", fiber.Map{
+		"entries":       entries,
+		"default_shift": models.GetDefaultShift(h.db),
+	})
+}
+
+// SetRoster assigns a shift to employees for every matching day of a range, replacing
+// any shift they were already rostered on those days
+func (h *WorkShiftHandler) SetRoster(c *fiber.Ctx) error {
+	adminID := c.Locals("user_id").(uuid.UUID)
+
+	var req SetRosterRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+	if len(req.UserIDs) == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "At least one user is required", nil)
+	}
+	if req.ToDate == "" {
+		req.ToDate = req.FromDate
+	}
+
+	fromDate, toDate, err := parseDateRange(req.FromDate, req.ToDate)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid date format. Use YYYY-MM-DD", err)
+	}
+	if toDate.Before(fromDate) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "End date cannot be before the start date", nil)
+	}
+	if toDate.Sub(fromDate) >= maxRosterDays*24*time.Hour {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "A roster request can cover at most "+strconv.Itoa(maxRosterDays)+" days", nil)
+	}
+
+	weekdays := map[time.Weekday]bool{}
+	for _, day := range req.Weekdays {
+		if day < 0 || day > 6 {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Weekdays must be between 0 (Sunday) and 6", nil)
+		}
+		weekdays[time.Weekday(day)] = true
+	}
+
+	var shift models.Shift
+	if err := h.db.Where("id = ? AND is_active = ?", req.ShiftID, true).First(&shift).Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid shift ID", err)
+	}
+
+	var userCount int64
+	h.db.Model(&models.User{}).Where("id IN ?", req.UserIDs).Count(&userCount)
+	if int(userCount) != len(req.UserIDs) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID", nil)
+	}
+
+	var dates []time.Time
+	for day := fromDate; !day.After(*toDate); day = day.AddDate(0, 0, 1) {
+		if len(weekdays) == 0 || weekdays[day.Weekday()] {
+			dates = append(dates, day)
+		}
+	}
+
+	tx := h.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	created := 0
+	for _, userID := range req.UserIDs {
+		for _, date := range dates {
+			day := date.Format("2006-01-02")
+			if err := tx.Where("user_id = ? AND date = ?", userID, day).Delete(&models.ShiftRoster{}).Error; err != nil {
+				tx.Rollback()
+				return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update roster", err)
+			}
+			entry := models.ShiftRoster{
+				UserID:    userID,
+				ShiftID:   shift.ID,
+				Date:      date,
+				Notes:     req.Notes,
+				CreatedBy: adminID,
+			}
+			if err := tx.Create(&entry).Error; err != nil {
+				tx.Rollback()
+				return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update roster", err)
+			}
+			created++
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update roster", err)
+	}
+
+	return utils.SuccessResponse(c, "Roster updated successfully", fiber.Map{
+		"shift":   shift,
+		"entries": created,
+	})
+}
+
+// DeleteRosterEntry removes a roster entry so the day falls back to the default shift
+func (h *WorkShiftHandler) DeleteRosterEntry(c *fiber.Ctx) error {
+	entryID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid roster entry ID", err)
+	}
+
+	result := h.db.Where("id = ?", entryID).Delete(&models.ShiftRoster{})
+	if result.Error != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete roster entry", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Roster entry not found", nil)
+	}
+
+	return utils.SuccessResponse(c, "Roster entry deleted successfully", nil)
+}
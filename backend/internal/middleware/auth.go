@@ -2,14 +2,18 @@ package middleware
 
 import (
 	"strings"
+	"time"
 
 	"cybercafe-backend/internal/config"
+	"cybercafe-backend/internal/models"
 	"cybercafe-backend/internal/utils"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-func AuthRequired(cfg *config.Config) fiber.Handler {
+func AuthRequired(cfg *config.Config, db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
@@ -19,20 +23,123 @@ func AuthRequired(cfg *config.Config) fiber.Handler {
 		}
 
 		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-		claims, err := utils.ValidateToken(tokenString, cfg.JWTSecret)
-		if err != nil {
+		return authenticate(c, cfg, db, tokenString)
+	}
+}
+
+// StreamAuthRequired authenticates event stream connections. Browsers cannot set headers
+// on an EventSource, so the token may also be passed as the "token" query parameter.
+func StreamAuthRequired(cfg *config.Config, db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokenString := strings.Replace(c.Get("Authorization"), "Bearer ", "", 1)
+		if tokenString == "" {
+			tokenString = c.Query("token")
+		}
+		if tokenString == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid token",
+				"error": "Authorization header required",
 			})
 		}
 
-		// Store user info in context
-		c.Locals("user_id", claims.UserID)
-		c.Locals("username", claims.Username)
-		c.Locals("role", claims.Role)
+		return authenticate(c, cfg, db, tokenString)
+	}
+}
+
+func authenticate(c *fiber.Ctx, cfg *config.Config, db *gorm.DB, tokenString string) error {
+	claims, err := utils.ValidateToken(tokenString, cfg.JWTSecret)
+	if err != nil || claims.Purpose != "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid token",
+		})
+	}
+
+	user, ok := activeTokenUser(db, claims)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Token has been revoked",
+		})
+	}
+
+	// Until the password is changed only the endpoints needed to change it are open
+	if user.MustChangePassword && !passwordChangeAllowedPaths[c.Path()] {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":                "Password change required",
+			"must_change_password": true,
+		})
+	}
+
+	// Roles holding "all" must enroll in two-factor authentication before doing anything else
+	if cfg.IsTwoFactorRequiredForAdmins() && !user.TOTPEnabled && !twoFactorSetupAllowedPaths[c.Path()] &&
+		models.RoleRequiresTwoFactor(db, user.RoleID) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":                     "Two-factor authentication setup required",
+			"two_factor_setup_required": true,
+		})
+	}
+
+	// Store user info in context
+	c.Locals("user_id", claims.UserID)
+	c.Locals("username", claims.Username)
+	c.Locals("role", claims.Role)
+	c.Locals("token_id", claims.ID)
+	c.Locals("session_id", claims.SessionID)
+	c.Locals("token_expires_at", claims.ExpiresAt.Time)
+	if claims.IssuedAt != nil {
+		c.Locals("token_issued_at", claims.IssuedAt.Time)
+	}
 
-		return c.Next()
+	return c.Next()
+}
+
+// passwordChangeAllowedPaths are reachable while a user must change their password
+var passwordChangeAllowedPaths = map[string]bool{
+	"/api/auth/me":              true,
+	"/api/auth/logout":          true,
+	"/api/auth/change-password": true,
+}
+
+// twoFactorSetupAllowedPaths are reachable while an admin has not enrolled in two-factor
+// authentication
+var twoFactorSetupAllowedPaths = map[string]bool{
+	"/api/auth/me":              true,
+	"/api/auth/logout":          true,
+	"/api/auth/change-password": true,
+	"/api/auth/2fa":             true,
+	"/api/auth/2fa/setup":       true,
+	"/api/auth/2fa/enable":      true,
+}
+
+// activeTokenUser checks the revocation list and the user's account state. Tokens of
+// inactive users, or issued before the user's tokens were last revoked, are rejected.
+func activeTokenUser(db *gorm.DB, claims *utils.Claims) (models.User, bool) {
+	var user models.User
+	if err := db.Select("id", "role_id", "is_active", "tokens_valid_after", "must_change_password", "totp_enabled").
+		Where("id = ?", claims.UserID).First(&user).Error; err != nil {
+		return user, false
+	}
+	if !user.IsActive {
+		return user, false
+	}
+	if user.TokensValidAfter != nil && (claims.IssuedAt == nil || claims.IssuedAt.Time.Before(user.TokensValidAfter.Truncate(time.Second))) {
+		return user, false
+	}
+
+	// Tokens of a signed-out device session are rejected; active sessions get a last-seen update
+	if claims.SessionID != "" {
+		sessionID, err := uuid.Parse(claims.SessionID)
+		if err != nil || !models.TouchDeviceSession(db, sessionID, time.Now()) {
+			return user, false
+		}
+	}
+
+	if claims.ID != "" {
+		var count int64
+		db.Model(&models.RevokedToken{}).Where("token_id = ?", claims.ID).Count(&count)
+		if count > 0 {
+			return user, false
+		}
 	}
+	return user, true
 }
 
 func RoleRequired(roles ...string) fiber.Handler {
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"cybercafe-backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PermissionGuard checks route permissions against the permission list of the
+// authenticated user's role. It must run after AuthRequired.
+type PermissionGuard struct {
+	db *gorm.DB
+}
+
+func NewPermissionGuard(db *gorm.DB) *PermissionGuard {
+	return &PermissionGuard{db: db}
+}
+
+// Require allows the request only if the user's role holds the permission for any record
+func (g *PermissionGuard) Require(permission string) fiber.Handler {
+	return g.check(permission, false)
+}
+
+// RequireOwn also accepts the ".own" variant of the permission. The granted scope is
+// stored in c.Locals("permission_scope") so handlers can restrict own-scoped requests
+// to the user's records.
+func (g *PermissionGuard) RequireOwn(permission string) fiber.Handler {
+	return g.check(permission, true)
+}
+
+func (g *PermissionGuard) check(permission string, allowOwn bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("user_id").(uuid.UUID)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Authentication required",
+			})
+		}
+
+		var role models.Role
+		if err := g.db.Joins("JOIN users ON users.role_id = roles.id").
+			Where("users.id = ?", userID).First(&role).Error; err != nil {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Insufficient permissions",
+			})
+		}
+
+		granted, scope := role.Grants(permission)
+		if !granted || (scope == models.ScopeOwn && !allowOwn) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":      "Insufficient permissions",
+				"permission": permission,
+			})
+		}
+
+		c.Locals("permission_scope", scope)
+		return c.Next()
+	}
+}
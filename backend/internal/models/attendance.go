@@ -1,28 +1,49 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Attendance flag reasons
+const (
+	AttendanceFlagMaxShiftLength = "max_shift_length_exceeded"
+	AttendanceFlagNonWorkingDay  = "non_working_day"
+)
+
 type Attendance struct {
-	ID           uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
-	UserID       uuid.UUID  `json:"user_id" gorm:"type:char(36);not null"`
-	User         User       `json:"user" gorm:"foreignKey:UserID"`
-	CheckInTime  time.Time  `json:"check_in_time" gorm:"not null"`
-	CheckOutTime *time.Time `json:"check_out_time"`
-	PhotoPath    string     `json:"photo_path"`
-	CheckOutPhotoPath *string   `json:"check_out_photo_path"` // Tambahkan field ini jika belum ada
-	Latitude     float64    `json:"latitude"`
-	Longitude    float64    `json:"longitude"`
-	Address      string     `json:"address"`
-	Distance     float64    `json:"distance"`
-	IsValid      bool       `json:"is_valid" gorm:"default:true"`
-	Notes        string     `json:"notes"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID                 uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID             uuid.UUID  `json:"user_id" gorm:"type:char(36);not null"`
+	User               User       `json:"user" gorm:"foreignKey:UserID"`
+	CheckInTime        time.Time  `json:"check_in_time" gorm:"not null"`
+	CheckOutTime       *time.Time `json:"check_out_time"`
+	PhotoPath          string     `json:"photo_path"`
+	CheckOutPhotoPath  *string    `json:"check_out_photo_path"` // Tambahkan field ini jika belum ada
+	Latitude           float64    `json:"latitude"`
+	Longitude          float64    `json:"longitude"`
+	Address            string     `json:"address"`
+	Distance           float64    `json:"distance"`
+	IsValid            bool       `json:"is_valid" gorm:"default:true"`
+	LocationID         *uuid.UUID `json:"location_id" gorm:"type:uuid;index"` // nearest active location at check-in
+	Location           *Location  `json:"location,omitempty" gorm:"foreignKey:LocationID"`
+	CheckOutLatitude   *float64   `json:"check_out_latitude"`
+	CheckOutLongitude  *float64   `json:"check_out_longitude"`
+	CheckOutDistance   *float64   `json:"check_out_distance"`
+	CheckOutIsValid    *bool      `json:"check_out_is_valid"`
+	CheckOutLocationID *uuid.UUID `json:"check_out_location_id" gorm:"type:uuid;index"`
+	CheckOutLocation   *Location  `json:"check_out_location,omitempty" gorm:"foreignKey:CheckOutLocationID"`
+	BusinessDate       time.Time  `json:"business_date" gorm:"type:date;index"` // day the shift started; an overnight shift belongs to its first day
+	Timezone           string     `json:"timezone" gorm:"type:varchar(64)"`     // the site's time zone at check-in
+	Flagged            bool       `json:"flagged" gorm:"default:false;index"`
+	FlagReason         string     `json:"flag_reason"`
+	FlaggedAt          *time.Time `json:"flagged_at"`
+	OverdueAt          *time.Time `json:"overdue_at" gorm:"index"` // when the check-in was found open past the maximum shift length
+	Notes              string     `json:"notes"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 }
 
 func (a *Attendance) BeforeCreate(tx *gorm.DB) error {
@@ -36,4 +57,118 @@ func (a *Attendance) GetWorkingHours() float64 {
 	}
 	duration := a.CheckOutTime.Sub(a.CheckInTime)
 	return duration.Hours()
-}
\ No newline at end of file
+}
+
+// TimeLocation returns the time zone the record is evaluated in: the site's zone at
+// check-in, or the server's for older records
+func (a *Attendance) TimeLocation() *time.Location {
+	if a.Timezone == "" {
+		return a.CheckInTime.Location()
+	}
+	return LoadTimezone(a.Timezone)
+}
+
+// WorkDate returns the business date as midnight in the record's time zone. Records
+// from before business dates were stored fall back to the check-in day.
+func (a *Attendance) WorkDate() time.Time {
+	date := a.BusinessDate
+	if date.IsZero() {
+		date = a.CheckInTime.In(a.TimeLocation())
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, a.TimeLocation())
+}
+
+// ErrNoOpenAttendance is returned when the user is not checked in
+var ErrNoOpenAttendance = errors.New("no open attendance")
+
+// GetOpenAttendance returns the user's check-in that has not been checked out, whatever
+// day it started on. Records flagged as overdue are left for an administrator to correct.
+func GetOpenAttendance(db *gorm.DB, userID uuid.UUID) (*Attendance, error) {
+	var attendance Attendance
+	err := db.Where("user_id = ? AND check_out_time IS NULL AND overdue_at IS NULL", userID).
+		Order("check_in_time DESC").First(&attendance).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoOpenAttendance
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attendance, nil
+}
+
+// GetCurrentAttendance returns the user's open check-in, or else their record for the
+// business date of today
+func GetCurrentAttendance(db *gorm.DB, userID uuid.UUID, today time.Time) (*Attendance, error) {
+	if attendance, err := GetOpenAttendance(db, userID); err != ErrNoOpenAttendance {
+		return attendance, err
+	}
+
+	var attendance Attendance
+	if err := db.Where("user_id = ? AND business_date = ?", userID, today.Format("2006-01-02")).
+		Order("check_in_time DESC").First(&attendance).Error; err != nil {
+		return nil, err
+	}
+	return &attendance, nil
+}
+
+// ResolveBusinessDate decides which day a check-in at the given time belongs to. A
+// check-in before the end of yesterday's overnight shift belongs to yesterday, unless
+// the employee already has a record for that day.
+func ResolveBusinessDate(db *gorm.DB, userID uuid.UUID, at time.Time) time.Time {
+	today := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	yesterday := today.AddDate(0, 0, -1)
+
+	shift := GetShiftForDate(db, userID, yesterday)
+	if !shift.IsOvernight() {
+		return today
+	}
+	if _, end := shift.Window(yesterday); !at.Before(end) {
+		return today
+	}
+
+	var existing int64
+	db.Model(&Attendance{}).Where("user_id = ? AND business_date = ?", userID, yesterday.Format("2006-01-02")).Count(&existing)
+	if existing > 0 {
+		return today
+	}
+	return yesterday
+}
+
+// FlagOverdueAttendances marks check-ins that have stayed open longer than the maximum
+// shift length as overdue, so they stop blocking new check-ins and show up for review.
+// Records already flagged for another reason keep that reason. A nil userID checks
+// every user.
+func FlagOverdueAttendances(db *gorm.DB, userID uuid.UUID, maxLength time.Duration, now time.Time) ([]Attendance, error) {
+	query := db.Where("check_out_time IS NULL AND overdue_at IS NULL AND check_in_time < ?", now.Add(-maxLength))
+	if userID != uuid.Nil {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var overdue []Attendance
+	if err := query.Find(&overdue).Error; err != nil {
+		return nil, err
+	}
+	if len(overdue) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(overdue))
+	for i := range overdue {
+		ids = append(ids, overdue[i].ID)
+		overdue[i].OverdueAt = &now
+		if !overdue[i].Flagged {
+			overdue[i].Flagged = true
+			overdue[i].FlagReason = AttendanceFlagMaxShiftLength
+			overdue[i].FlaggedAt = &now
+		}
+	}
+
+	// The right-hand side sees the old flagged value, so earlier flags are kept
+	err := db.Model(&Attendance{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"overdue_at":  now,
+		"flagged":     true,
+		"flag_reason": gorm.Expr("CASE WHEN flagged THEN flag_reason ELSE ? END", AttendanceFlagMaxShiftLength),
+		"flagged_at":  gorm.Expr("CASE WHEN flagged THEN flagged_at ELSE ? END", now),
+	}).Error
+	return overdue, err
+}
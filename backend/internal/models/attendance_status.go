@@ -0,0 +1,131 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Attendance status values, in the order they take precedence
+const (
+	AttendanceStatusIncomplete = "incomplete"
+	AttendanceStatusLate       = "late"
+	AttendanceStatusEarlyLeave = "early_leave"
+	AttendanceStatusPresent    = "present"
+)
+
+// AttendanceEvaluation is an attendance record measured against the employee's shift
+type AttendanceEvaluation struct {
+	Shift             *Shift  `json:"shift"`
+	Status            string  `json:"status"`        // incomplete, late, early_leave, present
+	WorkingHours      float64 `json:"working_hours"` // excluding the shift's break
+	IsLate            bool    `json:"is_late"`
+	LateMinutes       int     `json:"late_minutes"` // counted from the shift start, not the end of the grace period
+	IsEarlyLeave      bool    `json:"is_early_leave"`
+	EarlyLeaveMinutes int     `json:"early_leave_minutes"`
+	IsIncomplete      bool    `json:"is_incomplete"`    // not checked out
+	OvertimeMinutes   int     `json:"overtime_minutes"` // past the shift end; reported alongside the status
+}
+
+// ShiftSchedule resolves which shift applied to an employee on a date. Load it once
+// for a batch of attendance records rather than querying per record.
+type ShiftSchedule struct {
+	defaultShift *Shift
+	roster       map[string]*Shift
+}
+
+func rosterKey(userID uuid.UUID, date time.Time) string {
+	return userID.String() + "|" + date.Format("2006-01-02")
+}
+
+// LoadShiftSchedule loads the roster entries covering the given attendance records
+func LoadShiftSchedule(db *gorm.DB, attendances []Attendance) *ShiftSchedule {
+	schedule := &ShiftSchedule{defaultShift: GetDefaultShift(db), roster: map[string]*Shift{}}
+	if len(attendances) == 0 {
+		return schedule
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(attendances))
+	seen := map[uuid.UUID]bool{}
+	from, to := attendances[0].WorkDate(), attendances[0].WorkDate()
+	for i := range attendances {
+		att := &attendances[i]
+		if !seen[att.UserID] {
+			seen[att.UserID] = true
+			userIDs = append(userIDs, att.UserID)
+		}
+		if date := att.WorkDate(); date.Before(from) {
+			from = date
+		} else if date.After(to) {
+			to = date
+		}
+	}
+
+	var entries []ShiftRoster
+	db.Preload("Shift").
+		Where("user_id IN ? AND date BETWEEN ? AND ?", userIDs, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Find(&entries)
+	for _, entry := range entries {
+		if entry.Shift != nil {
+			schedule.roster[rosterKey(entry.UserID, entry.Date)] = entry.Shift
+		}
+	}
+	return schedule
+}
+
+// ShiftFor returns the employee's shift on the date
+func (s *ShiftSchedule) ShiftFor(userID uuid.UUID, date time.Time) *Shift {
+	if shift, ok := s.roster[rosterKey(userID, date)]; ok {
+		return shift
+	}
+	return s.defaultShift
+}
+
+// Evaluate measures an attendance record against the employee's shift on its business date
+func (s *ShiftSchedule) Evaluate(att *Attendance) AttendanceEvaluation {
+	return EvaluateAttendance(att, s.ShiftFor(att.UserID, att.WorkDate()))
+}
+
+// EvaluateAttendance computes lateness, early leave, incompleteness and overtime of an
+// attendance record against the shift that started on its business date, so an
+// overnight shift is measured across midnight. Times are compared to the minute, so a check-in
+// at 09:00:40 for a 09:00 start is on time.
+func EvaluateAttendance(att *Attendance, shift *Shift) AttendanceEvaluation {
+	start, end := shift.Window(att.WorkDate())
+	checkIn := att.CheckInTime.Truncate(time.Minute)
+	eval := AttendanceEvaluation{Shift: shift}
+
+	if checkIn.After(start.Add(time.Duration(shift.GraceMinutes) * time.Minute)) {
+		eval.IsLate = true
+		eval.LateMinutes = int(checkIn.Sub(start).Minutes())
+	}
+
+	if att.CheckOutTime == nil {
+		eval.IsIncomplete = true
+	} else {
+		checkOut := att.CheckOutTime.Truncate(time.Minute)
+		worked := att.CheckOutTime.Sub(att.CheckInTime) - time.Duration(shift.BreakMinutes)*time.Minute
+		if worked > 0 {
+			eval.WorkingHours = worked.Hours()
+		}
+		if checkOut.Before(end) {
+			eval.IsEarlyLeave = true
+			eval.EarlyLeaveMinutes = int(end.Sub(checkOut).Minutes())
+		} else if checkOut.After(end) {
+			eval.OvertimeMinutes = int(checkOut.Sub(end).Minutes())
+		}
+	}
+
+	switch {
+	case eval.IsIncomplete:
+		eval.Status = AttendanceStatusIncomplete
+	case eval.IsLate:
+		eval.Status = AttendanceStatusLate
+	case eval.IsEarlyLeave:
+		eval.Status = AttendanceStatusEarlyLeave
+	default:
+		eval.Status = AttendanceStatusPresent
+	}
+	return eval
+}
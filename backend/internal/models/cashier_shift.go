@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Cashier shift status values
+const (
+	ShiftStatusOpen   = "open"
+	ShiftStatusClosed = "closed"
+)
+
+// CashierShift is a period during which one cashier holds the cash drawer. Every cash
+// transaction the cashier records while the shift is open is attached to it.
+type CashierShift struct {
+	ID             uuid.UUID   `json:"id" gorm:"type:char(36);primaryKey"`
+	CashierID      uuid.UUID   `json:"cashier_id" gorm:"type:char(36);not null;index"`
+	Cashier        *User       `json:"cashier,omitempty" gorm:"foreignKey:CashierID"`
+	AttendanceID   *uuid.UUID  `json:"attendance_id" gorm:"type:char(36);index"`
+	Attendance     *Attendance `json:"attendance,omitempty" gorm:"foreignKey:AttendanceID"`
+	Status         string      `json:"status" gorm:"type:varchar(20);default:'open';index"` // open, closed
+	OpeningFloat   int64       `json:"opening_float" gorm:"not null"`                       // in rupiah
+	OpenedAt       time.Time   `json:"opened_at"`
+	ClosedAt       *time.Time  `json:"closed_at"`
+	ClosedBy       *uuid.UUID  `json:"closed_by" gorm:"type:char(36)"`
+	ExpectedCash   int64       `json:"expected_cash"` // opening float plus net cash taken, set on close
+	CountedCash    *int64      `json:"counted_cash"`  // cash in the drawer when closed
+	Variance       int64       `json:"variance"`      // counted minus expected
+	VarianceReason string      `json:"variance_reason"`
+	Notes          string      `json:"notes"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+func (s *CashierShift) BeforeCreate(tx *gorm.DB) error {
+	s.ID = uuid.New()
+	return nil
+}
+
+// GetOpenShift returns the cashier's open shift, or gorm.ErrRecordNotFound
+func GetOpenShift(db *gorm.DB, cashierID uuid.UUID) (*CashierShift, error) {
+	var shift CashierShift
+	if err := db.Where("cashier_id = ? AND status = ?", cashierID, ShiftStatusOpen).First(&shift).Error; err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// GetShiftCashTotal returns the net cash taken during a shift, in rupiah
+func GetShiftCashTotal(db *gorm.DB, shiftID uuid.UUID) int64 {
+	var total int64
+	db.Model(&Transaction{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("shift_id = ? AND payment_method = ?", shiftID, PaymentCash).
+		Scan(&total)
+	return total
+}
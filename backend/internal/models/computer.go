@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Computer status values
+const (
+	ComputerStatusAvailable   = "available"
+	ComputerStatusInUse       = "in-use"
+	ComputerStatusMaintenance = "maintenance"
+	ComputerStatusOffline     = "offline"
+)
+
+type Computer struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	Name            string     `json:"name" gorm:"uniqueIndex;not null"`
+	Specs           string     `json:"specs"`
+	IPAddress       string     `json:"ip_address"`
+	Zone            string     `json:"zone"`                                               // Main Area, Gaming Zone, VIP Room, ...
+	Status          string     `json:"status" gorm:"type:varchar(20);default:'available'"` // available, in-use, maintenance, offline
+	LastMaintenance *time.Time `json:"last_maintenance"`
+	Notes           string     `json:"notes"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (c *Computer) BeforeCreate(tx *gorm.DB) error {
+	c.ID = uuid.New()
+	return nil
+}
+
+// IsValidComputerStatus checks if the given status is a known computer status
+func IsValidComputerStatus(status string) bool {
+	switch status {
+	case ComputerStatusAvailable, ComputerStatusInUse, ComputerStatusMaintenance, ComputerStatusOffline:
+		return true
+	}
+	return false
+}
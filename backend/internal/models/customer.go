@@ -0,0 +1,255 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Membership tiers, derived from lifetime earned points
+const (
+	TierRegular = "regular"
+	TierSilver  = "silver"
+	TierGold    = "gold"
+
+	SilverTierPoints = 250
+	GoldTierPoints   = 1000
+)
+
+// Loyalty rates
+const (
+	RupiahPerEarnedPoint = 1000 // 1 point for every Rp1.000 paid
+	RupiahPerPointValue  = 100  // 1 point is worth Rp100 when redeemed
+)
+
+// Point ledger entry types
+const (
+	PointEntryEarn    = "earn"
+	PointEntryRedeem  = "redeem"
+	PointEntryAdjust  = "adjust"
+	PointEntryReverse = "reverse" // earned points taken back when the payment is voided or refunded
+)
+
+// Point ledger source types
+const (
+	PointSourceSession = "session"
+	PointSourceOrder   = "order"
+	PointSourcePackage = "package"
+	PointSourceManual  = "manual"
+)
+
+var ErrPointLedgerImmutable = errors.New("point ledger entries cannot be modified")
+
+type Customer struct {
+	ID             uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	Name           string    `json:"name" gorm:"not null"`
+	Email          string    `json:"email"`
+	Phone          string    `json:"phone" gorm:"index"`
+	JoinDate       time.Time `json:"join_date"`
+	Notes          string    `json:"notes"`
+	PreferredGames string    `json:"preferred_games" gorm:"type:text"` // JSON string of game names
+	IsActive       bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (c *Customer) BeforeCreate(tx *gorm.DB) error {
+	c.ID = uuid.New()
+	if c.JoinDate.IsZero() {
+		c.JoinDate = time.Now()
+	}
+	return nil
+}
+
+// PointLedgerEntry is an append-only record of a change to a customer's points balance
+type PointLedgerEntry struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	CustomerID uuid.UUID  `json:"customer_id" gorm:"type:char(36);not null;index"`
+	Type       string     `json:"type" gorm:"type:varchar(20);not null"` // earn, redeem, adjust, reverse
+	Points     int        `json:"points" gorm:"not null"`                // positive adds, negative deducts
+	SourceType string     `json:"source_type" gorm:"type:varchar(20)"`   // session, order, package, manual
+	SourceID   *uuid.UUID `json:"source_id" gorm:"type:char(36);index"`
+	SaleID     *uuid.UUID `json:"sale_id" gorm:"type:char(36);index"` // sale transaction that earned, or whose reversal took back, the points
+	Amount     int64      `json:"amount"`                             // payment amount in rupiah that earned the points, negative when reversed
+	Reason     string     `json:"reason"`
+	CreatedBy  uuid.UUID  `json:"created_by" gorm:"type:char(36);not null"`
+	Creator    *User      `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (p *PointLedgerEntry) BeforeCreate(tx *gorm.DB) error {
+	p.ID = uuid.New()
+	return nil
+}
+
+func (p *PointLedgerEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrPointLedgerImmutable
+}
+
+func (p *PointLedgerEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrPointLedgerImmutable
+}
+
+// PointsSummary holds the values derived from a customer's ledger
+type PointsSummary struct {
+	Balance        int    `json:"points"`
+	LifetimePoints int    `json:"lifetime_points"`
+	Tier           string `json:"membership_type"`
+	Visits         int64  `json:"visits"`
+	TotalSpent     int64  `json:"total_spent"`
+}
+
+// TierForPoints returns the membership tier for the given lifetime earned points
+func TierForPoints(lifetimePoints int) string {
+	switch {
+	case lifetimePoints >= GoldTierPoints:
+		return TierGold
+	case lifetimePoints >= SilverTierPoints:
+		return TierSilver
+	}
+	return TierRegular
+}
+
+// PointsForAmount returns the points earned by a payment of the given rupiah amount
+func PointsForAmount(amount int64) int {
+	if amount <= 0 {
+		return 0
+	}
+	return int(amount / RupiahPerEarnedPoint)
+}
+
+// PointsForPrice returns the points needed to redeem something with the given rupiah price
+func PointsForPrice(price int64) int {
+	return int((price + RupiahPerPointValue - 1) / RupiahPerPointValue)
+}
+
+// earnedEntryTypes are the entries counted towards lifetime points and spending
+var earnedEntryTypes = []string{PointEntryEarn, PointEntryReverse}
+
+// GetPointsSummary derives balance, tier and spending totals from the customer's ledger
+func GetPointsSummary(db *gorm.DB, customerID uuid.UUID) PointsSummary {
+	var result struct {
+		Balance        int
+		LifetimePoints int
+		TotalSpent     int64
+	}
+	db.Model(&PointLedgerEntry{}).
+		Select("COALESCE(SUM(points), 0) AS balance, "+
+			"COALESCE(SUM(CASE WHEN type IN ? THEN points ELSE 0 END), 0) AS lifetime_points, "+
+			"COALESCE(SUM(CASE WHEN type IN ? THEN amount ELSE 0 END), 0) AS total_spent",
+			earnedEntryTypes, earnedEntryTypes).
+		Where("customer_id = ?", customerID).
+		Scan(&result)
+
+	var visits int64
+	db.Model(&UsageSession{}).Where("customer_id = ?", customerID).Count(&visits)
+
+	return PointsSummary{
+		Balance:        result.Balance,
+		LifetimePoints: result.LifetimePoints,
+		Tier:           TierForPoints(result.LifetimePoints),
+		Visits:         visits,
+		TotalSpent:     result.TotalSpent,
+	}
+}
+
+// EarnPoints records the points earned by the sale that paid for a session or order.
+// Each sale earns once; the unique index on earning sales keeps concurrent requests from
+// crediting it twice. A session or order paid again after its sale was voided earns
+// again for the new sale.
+func EarnPoints(tx *gorm.DB, customerID uuid.UUID, sale *Transaction, amount int64, actor uuid.UUID) (*PointLedgerEntry, error) {
+	points := PointsForAmount(amount)
+	if points == 0 || sale.SourceID == nil {
+		return nil, nil
+	}
+
+	var count int64
+	tx.Model(&PointLedgerEntry{}).Where("type = ? AND sale_id = ?", PointEntryEarn, sale.ID).Count(&count)
+	if count > 0 {
+		return nil, nil
+	}
+
+	entry := PointLedgerEntry{
+		CustomerID: customerID,
+		Type:       PointEntryEarn,
+		Points:     points,
+		SourceType: sale.SourceType,
+		SourceID:   sale.SourceID,
+		SaleID:     &sale.ID,
+		Amount:     amount,
+		CreatedBy:  actor,
+	}
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// ReverseEarnedPoints takes back the points a sale earned beyond what its remaining paid
+// amount would earn, after part or all of it was voided or refunded. Points earned before
+// sales were recorded on the ledger are matched by the session or order instead. It does
+// nothing when the sale earned no points.
+func ReverseEarnedPoints(tx *gorm.DB, sale *Transaction, paidAmount int64, reason string, actor uuid.UUID) (*PointLedgerEntry, error) {
+	if sale.SourceID == nil {
+		return nil, nil
+	}
+
+	var earned PointLedgerEntry
+	if err := tx.Where("type = ? AND (sale_id = ? OR (sale_id IS NULL AND source_type = ? AND source_id = ?))",
+		PointEntryEarn, sale.ID, sale.SourceType, *sale.SourceID).
+		Order("created_at DESC").First(&earned).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var reversed struct {
+		Points int
+		Amount int64
+	}
+	tx.Model(&PointLedgerEntry{}).
+		Select("COALESCE(SUM(points), 0) AS points, COALESCE(SUM(amount), 0) AS amount").
+		Where("type = ? AND sale_id = ?", PointEntryReverse, sale.ID).
+		Scan(&reversed)
+
+	keep := PointsForAmount(paidAmount)
+	if keep > earned.Points {
+		keep = earned.Points
+	}
+	points := earned.Points + reversed.Points - keep
+	if points <= 0 {
+		return nil, nil
+	}
+
+	keepAmount := paidAmount
+	if keepAmount < 0 {
+		keepAmount = 0
+	}
+	if keepAmount > earned.Amount {
+		keepAmount = earned.Amount
+	}
+
+	entry := PointLedgerEntry{
+		CustomerID: earned.CustomerID,
+		Type:       PointEntryReverse,
+		Points:     -points,
+		SourceType: sale.SourceType,
+		SourceID:   sale.SourceID,
+		SaleID:     &sale.ID,
+		Amount:     -(earned.Amount + reversed.Amount - keepAmount),
+		Reason:     reason,
+		CreatedBy:  actor,
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
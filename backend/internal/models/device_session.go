@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DeviceSession is one login on one device. Every access and refresh token issued for
+// the login carries the session ID, so revoking the session signs that device out.
+type DeviceSession struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID     uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	RevokedBy  *uuid.UUID `json:"revoked_by" gorm:"type:char(36)"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (s *DeviceSession) BeforeCreate(tx *gorm.DB) error {
+	s.ID = uuid.New()
+	return nil
+}
+
+// deviceSessionTouchInterval limits how often request activity updates last_seen_at
+const deviceSessionTouchInterval = time.Minute
+
+// StartDeviceSession records a new login from the given client
+func StartDeviceSession(db *gorm.DB, userID uuid.UUID, ipAddress, userAgent string) (*DeviceSession, error) {
+	session := DeviceSession{
+		UserID:     userID,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		LastSeenAt: time.Now(),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// TouchDeviceSession checks that the session is still active and refreshes its
+// last-seen time. It reports false for unknown or revoked sessions.
+func TouchDeviceSession(db *gorm.DB, sessionID uuid.UUID, now time.Time) bool {
+	var session DeviceSession
+	if err := db.Select("id", "revoked_at", "last_seen_at").Where("id = ?", sessionID).First(&session).Error; err != nil {
+		return false
+	}
+	if session.RevokedAt != nil {
+		return false
+	}
+	if now.Sub(session.LastSeenAt) >= deviceSessionTouchInterval {
+		db.Model(&DeviceSession{}).Where("id = ?", sessionID).Update("last_seen_at", now)
+	}
+	return true
+}
+
+// RevokeDeviceSession signs out one session and revokes its refresh tokens. Access
+// tokens already issued for it are rejected by the session check.
+func RevokeDeviceSession(tx *gorm.DB, sessionID uuid.UUID, revokedBy *uuid.UUID) error {
+	now := time.Now()
+	if err := tx.Model(&DeviceSession{}).Where("id = ? AND revoked_at IS NULL", sessionID).Updates(map[string]interface{}{
+		"revoked_at": now,
+		"revoked_by": revokedBy,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", now).Error
+}
+
+// GetActiveDeviceSessions returns the user's sessions that have not been revoked,
+// most recently used first
+func GetActiveDeviceSessions(db *gorm.DB, userID uuid.UUID) ([]DeviceSession, error) {
+	var sessions []DeviceSession
+	err := db.Where("user_id = ? AND revoked_at IS NULL", userID).Order("last_seen_at DESC").Find(&sessions).Error
+	return sessions, err
+}
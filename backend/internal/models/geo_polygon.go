@@ -0,0 +1,182 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// GeoPolygon is a GeoJSON Polygon geometry. Coordinates are rings of [longitude, latitude]
+// positions: the first ring is the outer boundary and any further rings are holes.
+type GeoPolygon struct {
+	Type        string         `json:"type"`
+	Coordinates [][][2]float64 `json:"coordinates"`
+}
+
+// Value stores the polygon as GeoJSON
+func (p GeoPolygon) Value() (driver.Value, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads a stored GeoJSON polygon
+func (p *GeoPolygon) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into GeoPolygon", value)
+	}
+	return json.Unmarshal(data, p)
+}
+
+// UnmarshalJSON accepts a Polygon geometry or a Feature wrapping one, as map editors export
+func (p *GeoPolygon) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type        string          `json:"type"`
+		Coordinates [][][2]float64  `json:"coordinates"`
+		Geometry    json.RawMessage `json:"geometry"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return errors.New("boundary must be a GeoJSON Polygon")
+	}
+	if raw.Type == "Feature" {
+		if len(raw.Geometry) == 0 {
+			return errors.New("boundary feature has no geometry")
+		}
+		return p.UnmarshalJSON(raw.Geometry)
+	}
+
+	p.Type = raw.Type
+	p.Coordinates = raw.Coordinates
+	return nil
+}
+
+// Validate checks the polygon is well formed and closes rings that were left open
+func (p *GeoPolygon) Validate() error {
+	if p.Type != "Polygon" {
+		return errors.New("boundary must be a GeoJSON Polygon")
+	}
+	if len(p.Coordinates) == 0 {
+		return errors.New("boundary has no rings")
+	}
+
+	for i, ring := range p.Coordinates {
+		for _, position := range ring {
+			if !IsValidCoordinate(position[1], position[0]) {
+				return fmt.Errorf("boundary ring %d has an invalid position %v; positions are [longitude, latitude]", i, position)
+			}
+		}
+		if len(ring) > 0 && ring[0] != ring[len(ring)-1] {
+			ring = append(ring, ring[0])
+			p.Coordinates[i] = ring
+		}
+		distinct := map[[2]float64]bool{}
+		for _, position := range ring {
+			distinct[position] = true
+		}
+		if len(distinct) < 3 {
+			return fmt.Errorf("boundary ring %d needs at least three distinct positions", i)
+		}
+		if ringArea(ring) == 0 {
+			return fmt.Errorf("boundary ring %d encloses no area; its positions lie on one line", i)
+		}
+	}
+	return nil
+}
+
+// ringArea returns the unsigned area of a closed ring in square degrees, by the
+// shoelace formula. It is only used to reject degenerate rings.
+func ringArea(ring [][2]float64) float64 {
+	area := 0.0
+	for i := 0; i+1 < len(ring); i++ {
+		area += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return math.Abs(area) / 2
+}
+
+// Contains reports whether the point lies inside the outer ring and outside every hole
+func (p *GeoPolygon) Contains(lat, lng float64) bool {
+	if len(p.Coordinates) == 0 || !ringContains(p.Coordinates[0], lat, lng) {
+		return false
+	}
+	for _, hole := range p.Coordinates[1:] {
+		if ringContains(hole, lat, lng) {
+			return false
+		}
+	}
+	return true
+}
+
+// DistanceToEdge returns the distance in meters from the point to the nearest edge of
+// any ring. Polygons for a site are small enough that a flat projection around the
+// point is accurate to well under a meter.
+func (p *GeoPolygon) DistanceToEdge(lat, lng float64) float64 {
+	metersPerDegLat := math.Pi * earthRadiusMeters / 180
+	metersPerDegLng := metersPerDegLat * math.Cos(lat*math.Pi/180)
+	project := func(position [2]float64) (float64, float64) {
+		return (position[0] - lng) * metersPerDegLng, (position[1] - lat) * metersPerDegLat
+	}
+
+	best := math.Inf(1)
+	for _, ring := range p.Coordinates {
+		for i := 0; i+1 < len(ring); i++ {
+			ax, ay := project(ring[i])
+			bx, by := project(ring[i+1])
+			if d := distanceToSegment(ax, ay, bx, by); d < best {
+				best = d
+			}
+		}
+	}
+	if math.IsInf(best, 1) {
+		return 0
+	}
+	return best
+}
+
+// Centroid returns the average of the outer ring's vertices, used as the location's
+// center when only a boundary is given
+func (p *GeoPolygon) Centroid() (float64, float64) {
+	if len(p.Coordinates) == 0 || len(p.Coordinates[0]) < 2 {
+		return 0, 0
+	}
+	ring := p.Coordinates[0][:len(p.Coordinates[0])-1] // the closing position repeats the first
+	var lat, lng float64
+	for _, position := range ring {
+		lng += position[0]
+		lat += position[1]
+	}
+	return lat / float64(len(ring)), lng / float64(len(ring))
+}
+
+// ringContains is the even-odd ray casting test
+func ringContains(ring [][2]float64, lat, lng float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lng < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// distanceToSegment returns the distance from the origin to the segment a-b
+func distanceToSegment(ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	lengthSq := dx*dx + dy*dy
+	t := 0.0
+	if lengthSq > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/lengthSq))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
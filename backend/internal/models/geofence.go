@@ -0,0 +1,99 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const earthRadiusMeters = 6371000
+
+// GeofenceResult is the server's verdict on where an attendance was recorded
+type GeofenceResult struct {
+	Location *Location `json:"location"` // matched or nearest assigned location, nil when none is assigned
+	Distance float64   `json:"distance"` // meters to that location, or to its boundary's edge
+	IsValid  bool      `json:"is_valid"` // within the location's radius or boundary, or an approved remote day
+	Remote   bool      `json:"remote"`   // matched a remote location on an approved remote work day
+}
+
+// IsValidCoordinate reports whether the latitude and longitude are usable. (0, 0) is
+// rejected since it is what a client sends when it has no GPS fix.
+func IsValidCoordinate(lat, lng float64) bool {
+	if math.IsNaN(lat) || math.IsNaN(lng) {
+		return false
+	}
+	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return false
+	}
+	return lat != 0 || lng != 0
+}
+
+// DistanceMeters returns the great-circle distance between two coordinates using the
+// Haversine formula
+func DistanceMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	lat1Rad := lat1 * math.Pi / 180
+	lat2Rad := lat2 * math.Pi / 180
+	dlat := (lat2 - lat1) * math.Pi / 180
+	dlng := (lng2 - lng1) * math.Pi / 180
+
+	a := math.Sin(dlat/2)*math.Sin(dlat/2) + math.Cos(lat1Rad)*math.Cos(lat2Rad)*math.Sin(dlng/2)*math.Sin(dlng/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+
+	result := earthRadiusMeters * c
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0
+	}
+	return result
+}
+
+// MatchLocation finds where an employee is checking in. Only locations assigned to
+// the user on the site's local date are considered. A location whose radius or
+// boundary contains the point wins over one that is merely closer; failing that, an
+// approved remote work day, in the remote location's time zone, matches an assigned
+// remote location. Otherwise the nearest assigned location is returned with IsValid
+// false.
+func MatchLocation(db *gorm.DB, userID uuid.UUID, lat, lng float64, at time.Time) (GeofenceResult, error) {
+	assigned, err := GetAssignedLocations(db, userID, at)
+	if err != nil {
+		return GeofenceResult{}, err
+	}
+
+	// Remote locations have no real coordinates and are never matched by distance
+	var sites, remote []Location
+	for _, location := range assigned {
+		if location.Type == LocationTypeRemote {
+			remote = append(remote, location)
+		} else {
+			sites = append(sites, location)
+		}
+	}
+
+	result := matchLocations(sites, lat, lng)
+	if result.IsValid {
+		return result, nil
+	}
+
+	// Remote work needs both an assigned remote location and an approved day
+	if len(remote) == 0 || !HasApprovedRemoteWork(db, userID, at.In(remote[0].TimeLocation())) {
+		return result, nil
+	}
+	return GeofenceResult{Location: &remote[0], Distance: 0, IsValid: true, Remote: true}, nil
+}
+
+func matchLocations(locations []Location, lat, lng float64) GeofenceResult {
+	var result GeofenceResult
+	for i := range locations {
+		location := &locations[i]
+		distance, inside := location.Locate(lat, lng)
+
+		better := result.Location == nil ||
+			(inside && !result.IsValid) ||
+			(inside == result.IsValid && distance < result.Distance)
+		if better {
+			result = GeofenceResult{Location: location, Distance: distance, IsValid: inside}
+		}
+	}
+	return result
+}
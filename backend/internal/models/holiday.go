@@ -0,0 +1,235 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Holiday types
+const (
+	HolidayNational    = "national"     // public holiday (hari libur nasional)
+	HolidayCutiBersama = "cuti_bersama" // government-set collective leave day
+	HolidayClosure     = "closure"      // a branch closed for the day
+)
+
+// IsValidHolidayType reports whether the holiday type is known
+func IsValidHolidayType(holidayType string) bool {
+	switch holidayType {
+	case HolidayNational, HolidayCutiBersama, HolidayClosure:
+		return true
+	}
+	return false
+}
+
+// Holiday is a day off in the company calendar. Holidays without a location apply to
+// every employee; a location's holidays and closures only apply to the employees
+// assigned to it.
+type Holiday struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	Date       time.Time  `json:"date" gorm:"type:date;not null;index"`
+	Name       string     `json:"name" gorm:"not null"`
+	Type       string     `json:"type" gorm:"type:varchar(20);not null;index"` // national, cuti_bersama, closure
+	LocationID *uuid.UUID `json:"location_id" gorm:"type:uuid;index"`          // nil for company-wide holidays
+	Location   *Location  `json:"location,omitempty" gorm:"foreignKey:LocationID"`
+	UID        string     `json:"uid" gorm:"index"` // iCalendar UID of imported holidays
+	Notes      string     `json:"notes"`
+	CreatedBy  uuid.UUID  `json:"created_by" gorm:"type:char(36)"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (h *Holiday) BeforeCreate(tx *gorm.DB) error {
+	h.ID = uuid.New()
+	return nil
+}
+
+// WorkCalendar decides which days are working days for employees over a date range.
+// The weekly days off follow the working hours of the employee's assigned locations,
+// or Monday to Friday without one. Holidays are days off too, unless the employee is
+// rostered on the day.
+type WorkCalendar struct {
+	holidays    map[string][]Holiday
+	rostered    map[uuid.UUID]map[string]bool
+	assignments map[uuid.UUID][]LocationAssignment
+}
+
+// LoadWorkCalendar loads the holidays, roster entries and location assignments covering
+// the date range. A nil userID loads the calendar of every employee.
+func LoadWorkCalendar(db *gorm.DB, userID uuid.UUID, from, to time.Time) *WorkCalendar {
+	fromDay, toDay := from.Format("2006-01-02"), to.Format("2006-01-02")
+	calendar := &WorkCalendar{
+		holidays:    map[string][]Holiday{},
+		rostered:    map[uuid.UUID]map[string]bool{},
+		assignments: map[uuid.UUID][]LocationAssignment{},
+	}
+
+	var holidays []Holiday
+	db.Where("date BETWEEN ? AND ?", fromDay, toDay).Find(&holidays)
+	for _, holiday := range holidays {
+		day := holiday.Date.Format("2006-01-02")
+		calendar.holidays[day] = append(calendar.holidays[day], holiday)
+	}
+
+	rosterQuery := db.Model(&ShiftRoster{}).Where("date BETWEEN ? AND ?", fromDay, toDay)
+	assignmentQuery := db.Preload("Location").Where("effective_from <= ? AND (effective_to IS NULL OR effective_to >= ?)", toDay, fromDay)
+	if userID != uuid.Nil {
+		rosterQuery = rosterQuery.Where("user_id = ?", userID)
+		assignmentQuery = assignmentQuery.Where("user_id = ?", userID)
+	}
+
+	var roster []ShiftRoster
+	rosterQuery.Find(&roster)
+	for _, entry := range roster {
+		if calendar.rostered[entry.UserID] == nil {
+			calendar.rostered[entry.UserID] = map[string]bool{}
+		}
+		calendar.rostered[entry.UserID][entry.Date.Format("2006-01-02")] = true
+	}
+
+	var assignments []LocationAssignment
+	assignmentQuery.Find(&assignments)
+	for _, assignment := range assignments {
+		calendar.assignments[assignment.UserID] = append(calendar.assignments[assignment.UserID], assignment)
+	}
+
+	return calendar
+}
+
+// HolidayFor returns the holiday that gives the employee the day off, if any. A
+// location's holiday applies only when every location the employee is assigned to
+// that day is closed.
+func (w *WorkCalendar) HolidayFor(userID uuid.UUID, date time.Time) *Holiday {
+	holidays := w.holidays[date.Format("2006-01-02")]
+	closed := map[uuid.UUID]*Holiday{}
+	for i := range holidays {
+		if holidays[i].LocationID == nil {
+			return &holidays[i]
+		}
+		closed[*holidays[i].LocationID] = &holidays[i]
+	}
+	if len(closed) == 0 {
+		return nil
+	}
+
+	var closure *Holiday
+	for _, assignment := range w.assignments[userID] {
+		if !assignment.IsEffectiveOn(date) {
+			continue
+		}
+		holiday, ok := closed[assignment.LocationID]
+		if !ok {
+			return nil
+		}
+		closure = holiday
+	}
+	return closure
+}
+
+// isScheduledDay reports whether the date is in the employee's working week, ignoring
+// holidays: a day any assigned location is open, or Monday to Friday when no assigned
+// location has its working days set
+func (w *WorkCalendar) isScheduledDay(userID uuid.UUID, date time.Time) bool {
+	scheduled := false
+	for _, assignment := range w.assignments[userID] {
+		if !assignment.IsEffectiveOn(date) || assignment.Location == nil || len(assignment.Location.WorkingHours.Days) == 0 {
+			continue
+		}
+		if assignment.Location.WorkingHours.IsWorkingDay(date) {
+			return true
+		}
+		scheduled = true
+	}
+	if scheduled {
+		return false
+	}
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday
+}
+
+// IsWorkingDay reports whether the employee is expected to work on the date
+func (w *WorkCalendar) IsWorkingDay(userID uuid.UUID, date time.Time) bool {
+	if w.rostered[userID][date.Format("2006-01-02")] {
+		return true
+	}
+	return w.isScheduledDay(userID, date) && w.HolidayFor(userID, date) == nil
+}
+
+// WorkingDays counts the employee's working days from one date to another, inclusive
+func (w *WorkCalendar) WorkingDays(userID uuid.UUID, from, to time.Time) int {
+	days := 0
+	for day := dateOnly(from); !day.After(dateOnly(to)); day = day.AddDate(0, 0, 1) {
+		if w.IsWorkingDay(userID, day) {
+			days++
+		}
+	}
+	return days
+}
+
+// AttendanceSummary counts an employee's days in a period against the work calendar
+type AttendanceSummary struct {
+	WorkingDays  int `json:"working_days"`  // calendar working days in the period
+	HolidayDays  int `json:"holiday_days"`  // days of the working week off for holidays and closures
+	AttendedDays int `json:"attended_days"` // working days with a check-in
+	LeaveDays    int `json:"leave_days"`    // working days on approved leave, excused
+	AbsentDays   int `json:"absent_days"`   // past working days with neither a check-in nor leave
+}
+
+// Add accumulates another summary, e.g. to total a period over several employees
+func (s *AttendanceSummary) Add(other AttendanceSummary) {
+	s.WorkingDays += other.WorkingDays
+	s.HolidayDays += other.HolidayDays
+	s.AttendedDays += other.AttendedDays
+	s.LeaveDays += other.LeaveDays
+	s.AbsentDays += other.AbsentDays
+}
+
+// AttendanceRate is the percentage of expected working days so far that were attended.
+// Leave days and days still to come are not expected.
+func (s *AttendanceSummary) AttendanceRate() float64 {
+	expected := s.AttendedDays + s.AbsentDays
+	if expected == 0 {
+		return 0
+	}
+	return float64(s.AttendedDays) / float64(expected) * 100
+}
+
+// SummarizeAttendance compares the employee's check-ins and approved leave with the
+// working days from one date to another, inclusive. Working days before now without
+// a check-in or leave are absences.
+func SummarizeAttendance(db *gorm.DB, userID uuid.UUID, from, to, now time.Time) AttendanceSummary {
+	from, to = dateOnly(from), dateOnly(to)
+	calendar := LoadWorkCalendar(db, userID, from, to)
+
+	var businessDates []time.Time
+	db.Model(&Attendance{}).Where("user_id = ? AND business_date BETWEEN ? AND ?",
+		userID, from.Format("2006-01-02"), to.Format("2006-01-02")).Pluck("business_date", &businessDates)
+	attended := map[string]bool{}
+	for _, date := range businessDates {
+		attended[date.Format("2006-01-02")] = true
+	}
+	onLeave := getLeaveDates(db, userID, from, to)[userID]
+
+	var summary AttendanceSummary
+	today := now.Format("2006-01-02")
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		if !calendar.IsWorkingDay(userID, day) {
+			if calendar.isScheduledDay(userID, day) {
+				summary.HolidayDays++
+			}
+			continue
+		}
+
+		summary.WorkingDays++
+		switch {
+		case attended[date]:
+			summary.AttendedDays++
+		case onLeave[date]:
+			summary.LeaveDays++
+		case date < today:
+			summary.AbsentDays++
+		}
+	}
+	return summary
+}
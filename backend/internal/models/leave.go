@@ -0,0 +1,174 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Leave types
+const (
+	LeaveTypeAnnual     = "annual"
+	LeaveTypeSick       = "sick"
+	LeaveTypeUnpaid     = "unpaid"
+	LeaveTypePermission = "permission"
+)
+
+// Leave request status values
+const (
+	LeavePending   = "pending"
+	LeaveApproved  = "approved"
+	LeaveRejected  = "rejected"
+	LeaveCancelled = "cancelled"
+)
+
+// IsValidLeaveType reports whether the leave type is known
+func IsValidLeaveType(leaveType string) bool {
+	switch leaveType {
+	case LeaveTypeAnnual, LeaveTypeSick, LeaveTypeUnpaid, LeaveTypePermission:
+		return true
+	}
+	return false
+}
+
+// LeaveRequest asks for time off on a range of days. Approved leave days count as
+// excused rather than absent; annual leave is deducted from the yearly balance.
+type LeaveRequest struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID          uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	User            *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Type            string     `json:"type" gorm:"type:varchar(20);not null;index"` // annual, sick, unpaid, permission
+	StartDate       time.Time  `json:"start_date" gorm:"type:date;not null;index"`
+	EndDate         time.Time  `json:"end_date" gorm:"type:date;not null;index"` // inclusive
+	Days            int        `json:"days" gorm:"not null"`                     // working days covered
+	Reason          string     `json:"reason" gorm:"not null"`
+	AttachmentPath  string     `json:"attachment_path"`                                        // medical certificate, required for sick leave; served by the attachment endpoint only
+	Status          string     `json:"status" gorm:"type:varchar(20);default:'pending';index"` // pending, approved, rejected, cancelled
+	ReviewedBy      *uuid.UUID `json:"reviewed_by" gorm:"type:char(36)"`
+	Reviewer        *User      `json:"reviewer,omitempty" gorm:"foreignKey:ReviewedBy"`
+	ReviewedAt      *time.Time `json:"reviewed_at"`
+	RejectionReason string     `json:"rejection_reason"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (r *LeaveRequest) BeforeCreate(tx *gorm.DB) error {
+	r.ID = uuid.New()
+	return nil
+}
+
+// LeaveBalance is an employee's annual leave for one year. The entitlement accrues
+// evenly month by month; carried-over days and manual adjustments are available at once.
+type LeaveBalance struct {
+	ID          uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID      uuid.UUID `json:"user_id" gorm:"type:char(36);not null;uniqueIndex:idx_leave_balance_user_year"`
+	User        *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Year        int       `json:"year" gorm:"not null;uniqueIndex:idx_leave_balance_user_year"`
+	Entitlement int       `json:"entitlement" gorm:"not null"`   // annual leave days for the full year
+	CarriedOver int       `json:"carried_over" gorm:"default:0"` // days brought forward from last year
+	Adjustment  int       `json:"adjustment" gorm:"default:0"`   // manual correction, may be negative
+	Used        int       `json:"used" gorm:"default:0"`         // approved annual leave days
+	Notes       string    `json:"notes"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (b *LeaveBalance) BeforeCreate(tx *gorm.DB) error {
+	b.ID = uuid.New()
+	return nil
+}
+
+// Accrued returns the entitlement earned by the given date: one twelfth per month,
+// credited at the start of each month
+func (b *LeaveBalance) Accrued(asOf time.Time) int {
+	switch {
+	case asOf.Year() < b.Year:
+		return 0
+	case asOf.Year() > b.Year:
+		return b.Entitlement
+	}
+	return b.Entitlement * int(asOf.Month()) / 12
+}
+
+// Available returns the annual leave days that can still be taken as of the date
+func (b *LeaveBalance) Available(asOf time.Time) int {
+	return b.Accrued(asOf) + b.CarriedOver + b.Adjustment - b.Used
+}
+
+// ErrInsufficientLeave is returned when annual leave exceeds the available balance
+var ErrInsufficientLeave = errors.New("insufficient annual leave balance")
+
+// GetLeaveBalance returns the user's balance for the year, creating it with the
+// default entitlement the first time it is needed
+func GetLeaveBalance(db *gorm.DB, userID uuid.UUID, year, defaultEntitlement int) (*LeaveBalance, error) {
+	balance := LeaveBalance{UserID: userID, Year: year, Entitlement: defaultEntitlement}
+	if err := db.Where("user_id = ? AND year = ?", userID, year).
+		Attrs(LeaveBalance{Entitlement: defaultEntitlement}).
+		FirstOrCreate(&balance).Error; err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+// DeductAnnualLeave takes approved annual leave days from the balance of the request's
+// year. Must run inside a transaction; the balance row is locked while it is checked.
+func DeductAnnualLeave(tx *gorm.DB, request *LeaveRequest, defaultEntitlement int, asOf time.Time) error {
+	balance, err := GetLeaveBalance(tx, request.UserID, request.StartDate.Year(), defaultEntitlement)
+	if err != nil {
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(balance, "id = ?", balance.ID).Error; err != nil {
+		return err
+	}
+
+	// Leave later in the year may use what will have accrued by then
+	if accrualDate := request.StartDate; accrualDate.After(asOf) {
+		asOf = accrualDate
+	}
+	if balance.Available(asOf) < request.Days {
+		return ErrInsufficientLeave
+	}
+	return tx.Model(balance).Update("used", gorm.Expr("used + ?", request.Days)).Error
+}
+
+// RestoreAnnualLeave gives back the days of a cancelled annual leave request
+func RestoreAnnualLeave(tx *gorm.DB, request *LeaveRequest) error {
+	return tx.Model(&LeaveBalance{}).
+		Where("user_id = ? AND year = ?", request.UserID, request.StartDate.Year()).
+		Update("used", gorm.Expr("GREATEST(used - ?, 0)", request.Days)).Error
+}
+
+// getLeaveDates returns, per user, the dates of approved leave within the date range.
+// A nil userID covers every user.
+func getLeaveDates(db *gorm.DB, userID uuid.UUID, from, to time.Time) map[uuid.UUID]map[string]bool {
+	from, to = dateOnly(from), dateOnly(to)
+	query := db.Where("status = ? AND start_date <= ? AND end_date >= ?",
+		LeaveApproved, to.Format("2006-01-02"), from.Format("2006-01-02"))
+	if userID != uuid.Nil {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var requests []LeaveRequest
+	query.Find(&requests)
+
+	dates := map[uuid.UUID]map[string]bool{}
+	for _, request := range requests {
+		if dates[request.UserID] == nil {
+			dates[request.UserID] = map[string]bool{}
+		}
+		for day := dateOnly(request.StartDate); !day.After(dateOnly(request.EndDate)); day = day.AddDate(0, 0, 1) {
+			if !day.Before(from) && !day.After(to) {
+				dates[request.UserID][day.Format("2006-01-02")] = true
+			}
+		}
+	}
+	return dates
+}
+
+// dateOnly strips the time and zone so dates from the database and from requests compare equal
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
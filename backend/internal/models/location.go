@@ -8,19 +8,20 @@ import (
 )
 
 type Location struct {
-	ID           uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
-	Name         string    `json:"name" gorm:"not null"`
-	Address      string    `json:"address"`
-	Latitude     float64   `json:"latitude" gorm:"type:decimal(10,8);not null"`
-	Longitude    float64   `json:"longitude" gorm:"type:decimal(11,8);not null"`
-	Radius       int       `json:"radius" gorm:"default:100"` // radius in meters
-	Type         string    `json:"type" gorm:"type:varchar(20);default:'office'"` // office, branch, client_site, remote
-	IsActive     bool      `json:"is_active" gorm:"default:true"`
-	WorkingHours string    `json:"working_hours" gorm:"type:jsonb"` // JSON string
-	Timezone     string    `json:"timezone" gorm:"default:'Asia/Jakarta'"`
-	CreatedBy    string    `json:"created_by"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey"`
+	Name         string       `json:"name" gorm:"not null"`
+	Address      string       `json:"address"`
+	Latitude     float64      `json:"latitude" gorm:"type:decimal(10,8);not null"`
+	Longitude    float64      `json:"longitude" gorm:"type:decimal(11,8);not null"`
+	Radius       int          `json:"radius" gorm:"default:100"`                     // radius in meters, used when there is no boundary
+	Boundary     *GeoPolygon  `json:"boundary" gorm:"type:jsonb"`                    // optional GeoJSON polygon, replaces the radius check
+	Type         string       `json:"type" gorm:"type:varchar(20);default:'office'"` // office, branch, client_site, remote (valid only on approved remote work days)
+	IsActive     bool         `json:"is_active" gorm:"default:true"`
+	WorkingHours WorkingHours `json:"working_hours" gorm:"type:jsonb"`        // days and hours the site is open
+	Timezone     string       `json:"timezone" gorm:"default:'Asia/Jakarta'"` // IANA name, e.g. Asia/Makassar for WITA
+	CreatedBy    string       `json:"created_by"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
 }
 
 func (l *Location) BeforeCreate(tx *gorm.DB) error {
@@ -29,19 +30,20 @@ func (l *Location) BeforeCreate(tx *gorm.DB) error {
 }
 
 type LocationResponse struct {
-	ID           uuid.UUID `json:"id"`
-	Name         string    `json:"name"`
-	Address      string    `json:"address"`
-	Latitude     float64   `json:"latitude"`
-	Longitude    float64   `json:"longitude"`
-	Radius       int       `json:"radius"`
-	Type         string    `json:"type"`
-	IsActive     bool      `json:"is_active"`
-	WorkingHours string    `json:"working_hours"`
-	Timezone     string    `json:"timezone"`
-	CreatedBy    string    `json:"created_by"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           uuid.UUID    `json:"id"`
+	Name         string       `json:"name"`
+	Address      string       `json:"address"`
+	Latitude     float64      `json:"latitude"`
+	Longitude    float64      `json:"longitude"`
+	Radius       int          `json:"radius"`
+	Boundary     *GeoPolygon  `json:"boundary"`
+	Type         string       `json:"type"`
+	IsActive     bool         `json:"is_active"`
+	WorkingHours WorkingHours `json:"working_hours"`
+	Timezone     string       `json:"timezone"`
+	CreatedBy    string       `json:"created_by"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
 }
 
 func (l *Location) ToResponse() LocationResponse {
@@ -52,6 +54,7 @@ func (l *Location) ToResponse() LocationResponse {
 		Latitude:     l.Latitude,
 		Longitude:    l.Longitude,
 		Radius:       l.Radius,
+		Boundary:     l.Boundary,
 		Type:         l.Type,
 		IsActive:     l.IsActive,
 		WorkingHours: l.WorkingHours,
@@ -60,4 +63,24 @@ func (l *Location) ToResponse() LocationResponse {
 		CreatedAt:    l.CreatedAt,
 		UpdatedAt:    l.UpdatedAt,
 	}
-}
\ No newline at end of file
+}
+
+// Locate returns how far the point is from the location and whether it is inside. With
+// a boundary the distance is to the polygon's edge, and 0 when inside; otherwise it is
+// the distance to the center compared against the radius.
+func (l *Location) Locate(lat, lng float64) (float64, bool) {
+	if l.Boundary != nil {
+		if l.Boundary.Contains(lat, lng) {
+			return 0, true
+		}
+		return l.Boundary.DistanceToEdge(lat, lng), false
+	}
+
+	distance := DistanceMeters(lat, lng, l.Latitude, l.Longitude)
+	return distance, distance <= float64(l.Radius)
+}
+
+// TimeLocation returns the location's time zone
+func (l *Location) TimeLocation() *time.Location {
+	return LoadTimezone(l.Timezone)
+}
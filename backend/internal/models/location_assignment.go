@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Location types
+const (
+	LocationTypeOffice     = "office"
+	LocationTypeBranch     = "branch"
+	LocationTypeClientSite = "client_site"
+	LocationTypeRemote     = "remote"
+)
+
+// LocationAssignment allows an employee to check in at a location between two dates.
+// EffectiveTo is inclusive; a nil EffectiveTo means the assignment has no end.
+type LocationAssignment struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID        uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	User          *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	LocationID    uuid.UUID  `json:"location_id" gorm:"type:uuid;not null;index"`
+	Location      *Location  `json:"location,omitempty" gorm:"foreignKey:LocationID"`
+	EffectiveFrom time.Time  `json:"effective_from" gorm:"type:date;not null"`
+	EffectiveTo   *time.Time `json:"effective_to" gorm:"type:date"`
+	Notes         string     `json:"notes"`
+	CreatedBy     uuid.UUID  `json:"created_by" gorm:"type:char(36)"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (a *LocationAssignment) BeforeCreate(tx *gorm.DB) error {
+	a.ID = uuid.New()
+	return nil
+}
+
+// IsEffectiveOn reports whether the assignment covers the given date
+func (a *LocationAssignment) IsEffectiveOn(date time.Time) bool {
+	day := date.Format("2006-01-02")
+	if day < a.EffectiveFrom.Format("2006-01-02") {
+		return false
+	}
+	return a.EffectiveTo == nil || day <= a.EffectiveTo.Format("2006-01-02")
+}
+
+// GetAssignedLocations returns the active locations the user may check in at, at the
+// given time. Each assignment is checked against its location's local date, so a WIT
+// site's day starts before a WIB one's.
+func GetAssignedLocations(db *gorm.DB, userID uuid.UUID, at time.Time) ([]Location, error) {
+	// Every time zone's local date is within a day of the server's
+	var assignments []LocationAssignment
+	err := db.Preload("Location").
+		Where("user_id = ? AND effective_from <= ? AND (effective_to IS NULL OR effective_to >= ?)",
+			userID, at.AddDate(0, 0, 1).Format("2006-01-02"), at.AddDate(0, 0, -1).Format("2006-01-02")).
+		Find(&assignments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var locations []Location
+	seen := map[uuid.UUID]bool{}
+	for _, assignment := range assignments {
+		location := assignment.Location
+		if location == nil || !location.IsActive || seen[location.ID] {
+			continue
+		}
+		if !assignment.IsEffectiveOn(at.In(location.TimeLocation())) {
+			continue
+		}
+		seen[location.ID] = true
+		locations = append(locations, *location)
+	}
+	return locations, nil
+}
+
+// BackfillLocationAssignments assigns every active user to every active location,
+// remote ones included, when no assignment has been made yet. Check-ins are only
+// accepted at assigned locations, so this keeps employees able to check in where they
+// did before assignments existed, until an administrator narrows them down.
+func BackfillLocationAssignments(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&LocationAssignment{}).Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+
+	var users []User
+	if err := db.Select("id", "created_at").Where("is_active = ?", true).Find(&users).Error; err != nil {
+		return err
+	}
+	var sites []Location
+	if err := db.Where("is_active = ?", true).Find(&sites).Error; err != nil {
+		return err
+	}
+	if len(users) == 0 || len(sites) == 0 {
+		return nil
+	}
+
+	assignments := make([]LocationAssignment, 0, len(users)*len(sites))
+	for _, user := range users {
+		for _, site := range sites {
+			assignments = append(assignments, LocationAssignment{
+				UserID:        user.ID,
+				LocationID:    site.ID,
+				EffectiveFrom: dateOnly(user.CreatedAt),
+				Notes:         "Assigned automatically when location assignments were introduced",
+			})
+		}
+	}
+	return db.Create(&assignments).Error
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Login throttle scopes
+const (
+	LoginScopeUsername = "username"
+	LoginScopeIP       = "ip"
+)
+
+// LoginThrottle counts recent failed logins for one username or one client IP.
+// Rows are keyed by scope and key so unknown usernames are throttled too.
+type LoginThrottle struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	Scope        string     `json:"scope" gorm:"type:varchar(20);not null;uniqueIndex:idx_login_throttle_key"` // username, ip
+	Key          string     `json:"key" gorm:"not null;uniqueIndex:idx_login_throttle_key"`
+	FailedCount  int        `json:"failed_count" gorm:"not null;default:0"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (t *LoginThrottle) BeforeCreate(tx *gorm.DB) error {
+	t.ID = uuid.New()
+	return nil
+}
+
+// LoginLimits controls how failed logins are slowed down and locked out
+type LoginLimits struct {
+	MaxAttempts      int           // failures per username before lockout
+	MaxAttemptsPerIP int           // failures per IP before lockout
+	LockoutDuration  time.Duration // how long a lockout lasts
+	Window           time.Duration // failures older than this no longer count
+	BaseDelay        time.Duration // wait after the first failure, doubled for each further one
+	MaxDelay         time.Duration
+}
+
+func (l LoginLimits) maxAttempts(scope string) int {
+	if scope == LoginScopeIP {
+		return l.MaxAttemptsPerIP
+	}
+	return l.MaxAttempts
+}
+
+// IsLocked reports whether the throttle is in a lockout at the given time
+func (t *LoginThrottle) IsLocked(now time.Time) bool {
+	return t.LockedUntil != nil && now.Before(*t.LockedUntil)
+}
+
+// activeCount returns the failures that still count at the given time
+func (t *LoginThrottle) activeCount(now time.Time, limits LoginLimits) int {
+	if t.FailedCount == 0 || (limits.Window > 0 && now.Sub(t.LastFailedAt) > limits.Window) {
+		return 0
+	}
+	return t.FailedCount
+}
+
+// RetryAfter returns how long the client must wait before the next login attempt is
+// accepted: the remaining lockout, or the progressive delay after recent failures.
+func (t *LoginThrottle) RetryAfter(now time.Time, limits LoginLimits) time.Duration {
+	if t.IsLocked(now) {
+		return t.LockedUntil.Sub(now)
+	}
+
+	count := t.activeCount(now, limits)
+	if count == 0 || limits.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := limits.BaseDelay
+	for i := 1; i < count && delay < limits.MaxDelay; i++ {
+		delay *= 2
+	}
+	if limits.MaxDelay > 0 && delay > limits.MaxDelay {
+		delay = limits.MaxDelay
+	}
+
+	if wait := t.LastFailedAt.Add(delay).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// GetLoginThrottle returns the throttle for a scope and key, or an empty one if there
+// have been no failures
+func GetLoginThrottle(db *gorm.DB, scope, key string) LoginThrottle {
+	throttle := LoginThrottle{Scope: scope, Key: key}
+	db.Where("scope = ? AND key = ?", scope, key).First(&throttle)
+	return throttle
+}
+
+// RecordLoginFailure counts a failed login and starts a lockout once the threshold for
+// the scope is reached. It returns the updated throttle.
+func RecordLoginFailure(db *gorm.DB, scope, key string, now time.Time, limits LoginLimits) (LoginThrottle, error) {
+	var throttle LoginThrottle
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&LoginThrottle{Scope: scope, Key: key, LastFailedAt: now}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("scope = ? AND key = ?", scope, key).First(&throttle).Error; err != nil {
+			return err
+		}
+
+		// An expired lockout or a quiet period starts the count again
+		if throttle.LockedUntil != nil && !throttle.IsLocked(now) {
+			throttle.LockedUntil = nil
+			throttle.FailedCount = 0
+		}
+		throttle.FailedCount = throttle.activeCount(now, limits) + 1
+		throttle.LastFailedAt = now
+
+		if max := limits.maxAttempts(scope); max > 0 && throttle.FailedCount >= max && !throttle.IsLocked(now) {
+			lockedUntil := now.Add(limits.LockoutDuration)
+			throttle.LockedUntil = &lockedUntil
+		}
+
+		return tx.Model(&throttle).Select("failed_count", "last_failed_at", "locked_until").Updates(&throttle).Error
+	})
+	return throttle, err
+}
+
+// ClearLoginThrottle removes the failure count and any lockout for a scope and key
+func ClearLoginThrottle(db *gorm.DB, scope, key string) error {
+	return db.Where("scope = ? AND key = ?", scope, key).Delete(&LoginThrottle{}).Error
+}
+
+// LockoutStatus summarises failed logins for a staff account
+type LockoutStatus struct {
+	Locked         bool       `json:"locked"`
+	LockedUntil    *time.Time `json:"locked_until"`
+	FailedAttempts int        `json:"failed_attempts"`
+	LastFailedAt   *time.Time `json:"last_failed_at"`
+}
+
+// GetLockoutStatuses returns the lockout status for each of the given usernames
+func GetLockoutStatuses(db *gorm.DB, usernames []string, now time.Time, limits LoginLimits) map[string]LockoutStatus {
+	statuses := make(map[string]LockoutStatus, len(usernames))
+	for _, username := range usernames {
+		statuses[username] = LockoutStatus{}
+	}
+	if len(usernames) == 0 {
+		return statuses
+	}
+
+	var throttles []LoginThrottle
+	db.Where("scope = ? AND key IN ?", LoginScopeUsername, usernames).Find(&throttles)
+	for _, throttle := range throttles {
+		status := LockoutStatus{FailedAttempts: throttle.activeCount(now, limits)}
+		if throttle.IsLocked(now) {
+			status.Locked = true
+			status.LockedUntil = throttle.LockedUntil
+			status.FailedAttempts = throttle.FailedCount
+		}
+		if status.FailedAttempts > 0 {
+			lastFailedAt := throttle.LastFailedAt
+			status.LastFailedAt = &lastFailedAt
+		}
+		statuses[throttle.Key] = status
+	}
+	return statuses
+}
@@ -75,21 +75,32 @@ func CanUserClaim(db *gorm.DB, userID uuid.UUID, month, year int) bool {
 	return count == 0
 }
 
-// GetValidAttendanceCount counts valid attendance for a user in a specific month/year
+// GetValidAttendanceCount counts valid attendance for a user in a specific month/year.
+// Only attendance on the user's working days in the company calendar earns the allowance.
 func GetValidAttendanceCount(db *gorm.DB, userID uuid.UUID, month, year int) (int, int) {
-	var totalCount, validCount int64
+	var totalCount int64
 	
 	// Count total attendance
 	db.Model(&Attendance{}).Where(
-		"user_id = ? AND EXTRACT(MONTH FROM check_in_time) = ? AND EXTRACT(YEAR FROM check_in_time) = ?",
+		"user_id = ? AND EXTRACT(MONTH FROM business_date) = ? AND EXTRACT(YEAR FROM business_date) = ?",
 		userID, month, year,
 	).Count(&totalCount)
 	
-	// Count valid attendance (with checkout and minimum working hours)
+	// Count valid attendance (with checkout and minimum working hours) on working days
+	var businessDates []time.Time
 	db.Model(&Attendance{}).Where(
-		"user_id = ? AND EXTRACT(MONTH FROM check_in_time) = ? AND EXTRACT(YEAR FROM check_in_time) = ? AND check_out_time IS NOT NULL AND is_valid = true",
+		"user_id = ? AND EXTRACT(MONTH FROM business_date) = ? AND EXTRACT(YEAR FROM business_date) = ? AND check_out_time IS NOT NULL AND is_valid = true",
 		userID, month, year,
-	).Count(&validCount)
-	
-	return int(totalCount), int(validCount)
+	).Pluck("business_date", &businessDates)
+
+	monthStart := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	calendar := LoadWorkCalendar(db, userID, monthStart, monthStart.AddDate(0, 1, -1))
+	validCount := 0
+	for _, date := range businessDates {
+		if calendar.IsWorkingDay(userID, date) {
+			validCount++
+		}
+	}
+
+	return int(totalCount), validCount
 }
\ No newline at end of file
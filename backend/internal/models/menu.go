@@ -0,0 +1,198 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+type MenuItem struct {
+	ID          uuid.UUID    `json:"id" gorm:"type:char(36);primaryKey"`
+	Name        string       `json:"name" gorm:"uniqueIndex;not null"`
+	Description string       `json:"description"`
+	Category    string       `json:"category"`
+	Price       int64        `json:"price" gorm:"not null"` // in rupiah
+	Available   bool         `json:"available" gorm:"default:true"`
+	Recipe      []RecipeLine `json:"recipe" gorm:"foreignKey:MenuItemID;constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+func (m *MenuItem) BeforeCreate(tx *gorm.DB) error {
+	m.ID = uuid.New()
+	return nil
+}
+
+// RecipeLine is one ingredient of a menu item's bill of materials
+type RecipeLine struct {
+	ID                 uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	MenuItemID         uuid.UUID  `json:"menu_item_id" gorm:"type:char(36);not null;index"`
+	StockItemID        uuid.UUID  `json:"stock_item_id" gorm:"type:char(36);not null;index"`
+	StockItem          *StockItem `json:"stock_item,omitempty" gorm:"foreignKey:StockItemID"`
+	QuantityPerServing float64    `json:"quantity_per_serving" gorm:"not null"`
+}
+
+func (r *RecipeLine) BeforeCreate(tx *gorm.DB) error {
+	r.ID = uuid.New()
+	return nil
+}
+
+// MaxServings returns how many servings the current stock levels can cover.
+// Items without a recipe are not limited by stock and return -1.
+func (m *MenuItem) MaxServings(levels map[uuid.UUID]float64) int {
+	if len(m.Recipe) == 0 {
+		return -1
+	}
+	servings := math.MaxInt32
+	for _, line := range m.Recipe {
+		if line.QuantityPerServing <= 0 {
+			continue
+		}
+		possible := int(math.Floor(levels[line.StockItemID] / line.QuantityPerServing))
+		if possible < servings {
+			servings = possible
+		}
+	}
+	if servings < 0 {
+		return 0
+	}
+	return servings
+}
+
+// ConsumeMenuItemStock posts consumption movements for the given servings of a menu item
+// and returns the stock items that were touched. The stock items are locked, and an
+// ingredient whose on-hand quantity cannot cover the servings fails with
+// ErrInsufficientStock instead of going negative.
+func ConsumeMenuItemStock(tx *gorm.DB, menuItemID uuid.UUID, servings int, referenceID uuid.UUID, actor uuid.UUID) ([]uuid.UUID, error) {
+	var lines []RecipeLine
+	if err := tx.Where("menu_item_id = ?", menuItemID).Find(&lines).Error; err != nil {
+		return nil, err
+	}
+
+	stockItemIDs := make([]uuid.UUID, 0, len(lines))
+	for _, line := range lines {
+		stockItemIDs = append(stockItemIDs, line.StockItemID)
+	}
+	stockItems, err := lockStockItems(tx, stockItemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		stockItem := stockItems[line.StockItemID]
+		required := line.QuantityPerServing * float64(servings)
+		if onHand := GetOnHandQuantity(tx, line.StockItemID); onHand < required {
+			return nil, fmt.Errorf("%w: %s needs %g %s, %g on hand", ErrInsufficientStock, stockItem.Name, required, stockItem.Unit, onHand)
+		}
+
+		movement := StockMovement{
+			StockItemID: line.StockItemID,
+			Type:        StockMovementConsumption,
+			Quantity:    required,
+			UnitCost:    stockItem.UnitCost,
+			ReferenceID: &referenceID,
+			Reason:      "Order consumption",
+			CreatedBy:   actor,
+		}
+		if err := RecordStockMovement(tx, &movement); err != nil {
+			return nil, err
+		}
+	}
+	return stockItemIDs, nil
+}
+
+// ConsumeOrderStock posts the stock consumption of every item of a completed order and
+// marks menu items unavailable once an ingredient cannot cover another serving. Nothing
+// is posted when any ingredient falls short.
+func ConsumeOrderStock(tx *gorm.DB, orderID uuid.UUID, actor uuid.UUID) error {
+	var items []OrderItem
+	if err := tx.Where("order_id = ?", orderID).Find(&items).Error; err != nil {
+		return err
+	}
+
+	// Lock every ingredient of the order up front, in one order, so concurrent orders
+	// sharing ingredients wait for each other instead of deadlocking
+	menuItemIDs := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		menuItemIDs = append(menuItemIDs, item.MenuItemID)
+	}
+	var stockItemIDs []uuid.UUID
+	if len(menuItemIDs) > 0 {
+		if err := tx.Model(&RecipeLine{}).Distinct("stock_item_id").
+			Where("menu_item_id IN ?", menuItemIDs).Pluck("stock_item_id", &stockItemIDs).Error; err != nil {
+			return err
+		}
+	}
+	if _, err := lockStockItems(tx, stockItemIDs); err != nil {
+		return err
+	}
+
+	var touched []uuid.UUID
+	for _, item := range items {
+		consumed, err := ConsumeMenuItemStock(tx, item.MenuItemID, item.Quantity, orderID, actor)
+		if err != nil {
+			return err
+		}
+		touched = append(touched, consumed...)
+	}
+
+	return MarkUnavailableMenuItems(tx, touched)
+}
+
+// lockStockItems loads the stock items for update, keyed by ID
+func lockStockItems(tx *gorm.DB, stockItemIDs []uuid.UUID) (map[uuid.UUID]StockItem, error) {
+	locked := make(map[uuid.UUID]StockItem, len(stockItemIDs))
+	if len(stockItemIDs) == 0 {
+		return locked, nil
+	}
+
+	ids := append([]uuid.UUID(nil), stockItemIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+
+	var stockItems []StockItem
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).
+		Order("id").Find(&stockItems).Error; err != nil {
+		return nil, err
+	}
+	for _, stockItem := range stockItems {
+		locked[stockItem.ID] = stockItem
+	}
+	return locked, nil
+}
+
+// MarkUnavailableMenuItems flips menu items to unavailable when any ingredient drawn from
+// the given stock items can no longer cover one more serving.
+func MarkUnavailableMenuItems(tx *gorm.DB, stockItemIDs []uuid.UUID) error {
+	if len(stockItemIDs) == 0 {
+		return nil
+	}
+
+	var menuItems []MenuItem
+	if err := tx.Preload("Recipe").
+		Where("available = ? AND id IN (?)", true,
+			tx.Model(&RecipeLine{}).Select("menu_item_id").Where("stock_item_id IN ?", stockItemIDs)).
+		Find(&menuItems).Error; err != nil {
+		return err
+	}
+	if len(menuItems) == 0 {
+		return nil
+	}
+
+	levels := GetOnHandQuantities(tx)
+	for _, menuItem := range menuItems {
+		if menuItem.MaxServings(levels) == 0 {
+			if err := tx.Model(&MenuItem{}).Where("id = ?", menuItem.ID).Update("available", false).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Order status values
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusPreparing = "preparing"
+	OrderStatusReady     = "ready"
+	OrderStatusServed    = "served"
+	OrderStatusCancelled = "cancelled"
+)
+
+// orderTransitions lists the statuses each order status may move to
+var orderTransitions = map[string][]string{
+	OrderStatusPending:   {OrderStatusPreparing, OrderStatusCancelled},
+	OrderStatusPreparing: {OrderStatusReady, OrderStatusCancelled},
+	OrderStatusReady:     {OrderStatusServed, OrderStatusCancelled},
+}
+
+type Order struct {
+	ID              uuid.UUID            `json:"id" gorm:"type:char(36);primaryKey"`
+	CustomerID      *uuid.UUID           `json:"customer_id" gorm:"type:char(36);index"`
+	CustomerName    string               `json:"customer_name"`
+	TableNumber     string               `json:"table_number"`
+	IsDelivery      bool                 `json:"is_delivery" gorm:"default:false"`
+	Notes           string               `json:"notes"`
+	Status          string               `json:"status" gorm:"type:varchar(20);default:'pending';index"` // pending, preparing, ready, served, cancelled
+	Total           int64                `json:"total" gorm:"not null"`                                  // in rupiah
+	Items           []OrderItem          `json:"items" gorm:"foreignKey:OrderID"`
+	History         []OrderStatusHistory `json:"history,omitempty" gorm:"foreignKey:OrderID"`
+	StatusChangedAt time.Time            `json:"status_changed_at"`
+	CreatedBy       uuid.UUID            `json:"created_by" gorm:"type:char(36);not null"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+}
+
+func (o *Order) BeforeCreate(tx *gorm.DB) error {
+	o.ID = uuid.New()
+	return nil
+}
+
+// CanTransitionTo reports whether the order may move to the given status
+func (o *Order) CanTransitionTo(status string) bool {
+	for _, next := range orderTransitions[o.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidOrderStatus checks if the given status is a known order status
+func IsValidOrderStatus(status string) bool {
+	switch status {
+	case OrderStatusPending, OrderStatusPreparing, OrderStatusReady, OrderStatusServed, OrderStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// OrderItem is a line of an order. Name and price are copied from the menu at order time.
+type OrderItem struct {
+	ID         uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	OrderID    uuid.UUID `json:"order_id" gorm:"type:char(36);not null;index"`
+	MenuItemID uuid.UUID `json:"menu_item_id" gorm:"type:char(36);not null"`
+	Name       string    `json:"name" gorm:"not null"`
+	Price      int64     `json:"price" gorm:"not null"` // unit price in rupiah
+	Quantity   int       `json:"quantity" gorm:"not null"`
+	Notes      string    `json:"notes"`
+}
+
+func (o *OrderItem) BeforeCreate(tx *gorm.DB) error {
+	o.ID = uuid.New()
+	return nil
+}
+
+// OrderStatusHistory records who moved an order ticket between statuses and when
+type OrderStatusHistory struct {
+	ID         uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	OrderID    uuid.UUID `json:"order_id" gorm:"type:char(36);not null;index"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status" gorm:"not null"`
+	Reason     string    `json:"reason"`
+	ChangedBy  uuid.UUID `json:"changed_by" gorm:"type:char(36);not null"`
+	Changer    *User     `json:"changer,omitempty" gorm:"foreignKey:ChangedBy"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (o *OrderStatusHistory) BeforeCreate(tx *gorm.DB) error {
+	o.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PermissionAll grants every permission
+const PermissionAll = "all"
+
+// Permission scopes. A permission key may end in ".own" to limit it to records
+// that belong to the user, e.g. "attendance.write.own".
+const (
+	ScopeAny = "any"
+	ScopeOwn = "own"
+)
+
+// PermissionDefinition describes one permission in the catalog. The key is
+// "<resource>.<action>"; Scopes lists the scopes the key may be granted with.
+type PermissionDefinition struct {
+	Key         string   `json:"key"`
+	Resource    string   `json:"resource"`
+	Action      string   `json:"action"`
+	Scopes      []string `json:"scopes"`
+	Description string   `json:"description"`
+}
+
+var anyScope = []string{ScopeAny}
+var ownScopes = []string{ScopeAny, ScopeOwn}
+
+// PermissionCatalog lists every permission the routes check
+var PermissionCatalog = []PermissionDefinition{
+	{Key: "staff.read", Resource: "staff", Action: "read", Scopes: anyScope, Description: "View staff accounts"},
+	{Key: "staff.write", Resource: "staff", Action: "write", Scopes: anyScope, Description: "Create and update staff accounts"},
+	{Key: "staff.delete", Resource: "staff", Action: "delete", Scopes: anyScope, Description: "Delete staff accounts"},
+	{Key: "roles.read", Resource: "roles", Action: "read", Scopes: anyScope, Description: "View roles and the permission catalog"},
+	{Key: "roles.write", Resource: "roles", Action: "write", Scopes: anyScope, Description: "Create and update roles"},
+	{Key: "roles.delete", Resource: "roles", Action: "delete", Scopes: anyScope, Description: "Delete roles"},
+	{Key: "attendance.read", Resource: "attendance", Action: "read", Scopes: ownScopes, Description: "View attendance, meal allowance previews and dashboards"},
+	{Key: "attendance.write", Resource: "attendance", Action: "write", Scopes: ownScopes, Description: "Check in and out, claim meal allowance and edit attendance"},
+	{Key: "attendance.delete", Resource: "attendance", Action: "delete", Scopes: anyScope, Description: "Delete attendance records"},
+	{Key: "reports.read", Resource: "reports", Action: "read", Scopes: anyScope, Description: "Export attendance reports"},
+	{Key: "locations.write", Resource: "locations", Action: "write", Scopes: anyScope, Description: "Create and update attendance locations"},
+	{Key: "locations.delete", Resource: "locations", Action: "delete", Scopes: anyScope, Description: "Delete attendance locations"},
+	{Key: "schedules.write", Resource: "schedules", Action: "write", Scopes: anyScope, Description: "Manage work shifts, the roster and the holiday calendar"},
+	{Key: "remote_work.approve", Resource: "remote_work", Action: "approve", Scopes: anyScope, Description: "Approve and reject remote work requests"},
+	{Key: "leave.read", Resource: "leave", Action: "read", Scopes: anyScope, Description: "View every employee's leave requests, balances and medical certificates"},
+	{Key: "leave.approve", Resource: "leave", Action: "approve", Scopes: anyScope, Description: "Approve and reject leave requests and adjust leave balances"},
+	{Key: "audit.read", Resource: "audit", Action: "read", Scopes: anyScope, Description: "View the audit log"},
+	{Key: "meal_allowance.read", Resource: "meal_allowance", Action: "read", Scopes: anyScope, Description: "View all meal allowance claims and statistics"},
+	{Key: "meal_allowance.write", Resource: "meal_allowance", Action: "write", Scopes: anyScope, Description: "Change the meal allowance policy"},
+	{Key: "meal_allowance.approve", Resource: "meal_allowance", Action: "approve", Scopes: anyScope, Description: "Approve, reject and settle meal allowance claims"},
+	{Key: "computers.read", Resource: "computers", Action: "read", Scopes: anyScope, Description: "View computers"},
+	{Key: "computers.write", Resource: "computers", Action: "write", Scopes: anyScope, Description: "Create and update computers"},
+	{Key: "computers.delete", Resource: "computers", Action: "delete", Scopes: anyScope, Description: "Delete computers"},
+	{Key: "packages.read", Resource: "packages", Action: "read", Scopes: anyScope, Description: "View time packages"},
+	{Key: "packages.write", Resource: "packages", Action: "write", Scopes: anyScope, Description: "Create and update time packages"},
+	{Key: "packages.delete", Resource: "packages", Action: "delete", Scopes: anyScope, Description: "Delete time packages"},
+	{Key: "sessions.read", Resource: "sessions", Action: "read", Scopes: anyScope, Description: "View usage sessions"},
+	{Key: "sessions.write", Resource: "sessions", Action: "write", Scopes: anyScope, Description: "Start, extend, pause and stop usage sessions"},
+	{Key: "customers.read", Resource: "customers", Action: "read", Scopes: anyScope, Description: "View customers and loyalty points"},
+	{Key: "customers.write", Resource: "customers", Action: "write", Scopes: anyScope, Description: "Manage customers and loyalty points"},
+	{Key: "customers.delete", Resource: "customers", Action: "delete", Scopes: anyScope, Description: "Deactivate customers"},
+	{Key: "stock.read", Resource: "stock", Action: "read", Scopes: anyScope, Description: "View stock items and movements"},
+	{Key: "stock.write", Resource: "stock", Action: "write", Scopes: anyScope, Description: "Manage stock items and record movements"},
+	{Key: "stock.delete", Resource: "stock", Action: "delete", Scopes: anyScope, Description: "Delete stock items"},
+	{Key: "menu.read", Resource: "menu", Action: "read", Scopes: anyScope, Description: "View menu items"},
+	{Key: "menu.write", Resource: "menu", Action: "write", Scopes: anyScope, Description: "Create and update menu items"},
+	{Key: "menu.delete", Resource: "menu", Action: "delete", Scopes: anyScope, Description: "Delete menu items"},
+	{Key: "orders.read", Resource: "orders", Action: "read", Scopes: anyScope, Description: "View kitchen orders"},
+	{Key: "orders.write", Resource: "orders", Action: "write", Scopes: anyScope, Description: "Place orders and update their status"},
+	{Key: "transactions.read", Resource: "transactions", Action: "read", Scopes: ownScopes, Description: "View the transaction ledger"},
+	{Key: "transactions.write", Resource: "transactions", Action: "write", Scopes: anyScope, Description: "Record sales and purchases"},
+	{Key: "transactions.void", Resource: "transactions", Action: "void", Scopes: anyScope, Description: "Void and refund transactions"},
+	{Key: "shifts.read", Resource: "shifts", Action: "read", Scopes: ownScopes, Description: "View cashier shifts"},
+	{Key: "shifts.write", Resource: "shifts", Action: "write", Scopes: ownScopes, Description: "Open and close cashier shifts"},
+}
+
+// PermissionList is a role's list of permission keys, stored as a JSON array
+type PermissionList []string
+
+// Value stores the list as a JSON array
+func (p PermissionList) Value() (driver.Value, error) {
+	if p == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(p))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads a stored JSON array. Unparseable legacy values grant nothing.
+func (p *PermissionList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*p = PermissionList{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into PermissionList", value)
+	}
+
+	var permissions []string
+	if strings.TrimSpace(string(data)) == "" || json.Unmarshal(data, &permissions) != nil {
+		permissions = []string{}
+	}
+	*p = permissions
+	return nil
+}
+
+// UnmarshalJSON accepts a JSON array, or a string holding a JSON array as older clients send
+func (p *PermissionList) UnmarshalJSON(data []byte) error {
+	var permissions []string
+	if err := json.Unmarshal(data, &permissions); err == nil {
+		*p = permissions
+		return nil
+	}
+
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return fmt.Errorf("permissions must be a list of permission keys")
+	}
+	if strings.TrimSpace(encoded) == "" {
+		*p = PermissionList{}
+		return nil
+	}
+	if err := json.Unmarshal([]byte(encoded), &permissions); err != nil {
+		return fmt.Errorf("permissions must be a list of permission keys")
+	}
+	*p = permissions
+	return nil
+}
+
+// Validate checks every key against the catalog and returns the list without duplicates
+func (p PermissionList) Validate() (PermissionList, error) {
+	seen := make(map[string]bool, len(p))
+	valid := make(PermissionList, 0, len(p))
+	for _, key := range p {
+		key = strings.TrimSpace(key)
+		if !isKnownPermission(key) {
+			return nil, fmt.Errorf("unknown permission %q", key)
+		}
+		if !seen[key] {
+			seen[key] = true
+			valid = append(valid, key)
+		}
+	}
+	return valid, nil
+}
+
+func isKnownPermission(key string) bool {
+	if key == PermissionAll {
+		return true
+	}
+	base, scope := key, ScopeAny
+	if strings.HasSuffix(key, "."+ScopeOwn) {
+		base, scope = strings.TrimSuffix(key, "."+ScopeOwn), ScopeOwn
+	}
+	for _, definition := range PermissionCatalog {
+		if definition.Key != base {
+			continue
+		}
+		for _, allowed := range definition.Scopes {
+			if allowed == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasAdminRights reports whether the role holds the "all" wildcard
+func (r *Role) HasAdminRights() bool {
+	for _, granted := range r.Permissions {
+		if granted == PermissionAll {
+			return true
+		}
+	}
+	return false
+}
+
+// Grants reports whether the role holds the permission and whether it applies to
+// any record or only the user's own.
+func (r *Role) Grants(permission string) (bool, string) {
+	own := false
+	for _, granted := range r.Permissions {
+		switch granted {
+		case PermissionAll, permission:
+			return true, ScopeAny
+		case permission + "." + ScopeOwn:
+			own = true
+		}
+	}
+	if own {
+		return true, ScopeOwn
+	}
+	return false, ""
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Remote work request status values
+const (
+	RemoteWorkPending   = "pending"
+	RemoteWorkApproved  = "approved"
+	RemoteWorkRejected  = "rejected"
+	RemoteWorkCancelled = "cancelled"
+)
+
+// RemoteWorkRequest asks to work away from the assigned sites for a range of days.
+// A check-in at a remote location is only valid on days covered by an approved request.
+type RemoteWorkRequest struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID          uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	User            *User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	StartDate       time.Time  `json:"start_date" gorm:"type:date;not null;index"`
+	EndDate         time.Time  `json:"end_date" gorm:"type:date;not null;index"` // inclusive
+	Reason          string     `json:"reason" gorm:"not null"`
+	Status          string     `json:"status" gorm:"type:varchar(20);default:'pending';index"` // pending, approved, rejected, cancelled
+	ReviewedBy      *uuid.UUID `json:"reviewed_by" gorm:"type:char(36)"`
+	Reviewer        *User      `json:"reviewer,omitempty" gorm:"foreignKey:ReviewedBy"`
+	ReviewedAt      *time.Time `json:"reviewed_at"`
+	RejectionReason string     `json:"rejection_reason"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (r *RemoteWorkRequest) BeforeCreate(tx *gorm.DB) error {
+	r.ID = uuid.New()
+	return nil
+}
+
+// HasApprovedRemoteWork reports whether the user has approved remote work on the date of
+// the given time in its own time zone; pass the site's local time
+func HasApprovedRemoteWork(db *gorm.DB, userID uuid.UUID, date time.Time) bool {
+	day := date.Format("2006-01-02")
+	var count int64
+	db.Model(&RemoteWorkRequest{}).
+		Where("user_id = ? AND status = ? AND start_date <= ? AND end_date >= ?", userID, RemoteWorkApproved, day, day).
+		Count(&count)
+	return count > 0
+}
@@ -8,15 +8,29 @@ import (
 )
 
 type Role struct {
-	ID          uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
-	Name        string    `json:"name" gorm:"uniqueIndex;not null"`
-	Description string    `json:"description"`
-	Permissions string    `json:"permissions" gorm:"type:text"` // JSON string of permissions
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uuid.UUID      `json:"id" gorm:"type:char(36);primaryKey"`
+	Name        string         `json:"name" gorm:"uniqueIndex;not null"`
+	Description string         `json:"description"`
+	Permissions PermissionList `json:"permissions" gorm:"type:text"` // JSON array of permission keys
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
 }
 
 func (r *Role) BeforeCreate(tx *gorm.DB) error {
 	r.ID = uuid.New()
 	return nil
-}
\ No newline at end of file
+}
+
+// ActiveAdminExists reports whether an active user holds a role with admin rights,
+// leaving out the given role and user. Pass uuid.Nil to leave out neither.
+func ActiveAdminExists(db *gorm.DB, exceptRoleID, exceptUserID uuid.UUID) bool {
+	var roles []Role
+	db.Where("id <> ? AND id IN (?)", exceptRoleID,
+		db.Model(&User{}).Select("role_id").Where("is_active = ? AND id <> ?", true, exceptUserID)).Find(&roles)
+	for _, role := range roles {
+		if role.HasAdminRights() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Usage session status values
+const (
+	SessionStatusActive    = "active"
+	SessionStatusPaused    = "paused"
+	SessionStatusCompleted = "completed"
+	SessionStatusExpired   = "expired"
+)
+
+// TimePackage is a prepaid block of computer time sold to customers
+type TimePackage struct {
+	ID              uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	Name            string    `json:"name" gorm:"uniqueIndex;not null"`
+	Description     string    `json:"description"`
+	DurationMinutes int       `json:"duration_minutes" gorm:"not null"`
+	Price           int64     `json:"price" gorm:"not null"` // in rupiah
+	IsActive        bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func (t *TimePackage) BeforeCreate(tx *gorm.DB) error {
+	t.ID = uuid.New()
+	return nil
+}
+
+// PricePerMinute returns the package rate used for prorated extensions and overtime
+func (t *TimePackage) PricePerMinute() float64 {
+	if t.DurationMinutes <= 0 {
+		return 0
+	}
+	return float64(t.Price) / float64(t.DurationMinutes)
+}
+
+// UsageSession is a customer's use of a computer under a time package
+type UsageSession struct {
+	ID              uuid.UUID   `json:"id" gorm:"type:char(36);primaryKey"`
+	ComputerID      uuid.UUID   `json:"computer_id" gorm:"type:char(36);not null;index"`
+	Computer        Computer    `json:"computer" gorm:"foreignKey:ComputerID"`
+	PackageID       uuid.UUID   `json:"package_id" gorm:"type:char(36);not null"`
+	Package         TimePackage `json:"package" gorm:"foreignKey:PackageID"`
+	CustomerID      *uuid.UUID  `json:"customer_id" gorm:"type:char(36);index"`
+	CustomerName    string      `json:"customer_name"`
+	Status          string      `json:"status" gorm:"type:varchar(20);default:'active';index"` // active, paused, completed, expired
+	StartTime       time.Time   `json:"start_time" gorm:"not null"`
+	EndTime         time.Time   `json:"end_time" gorm:"not null"` // planned end, moved by extend and resume
+	PausedAt        *time.Time  `json:"paused_at"`
+	PausedSeconds   int64       `json:"paused_seconds" gorm:"default:0"`
+	ExtendedMinutes int         `json:"extended_minutes" gorm:"default:0"`
+	BaseAmount      int64       `json:"base_amount" gorm:"not null"` // in rupiah
+	ExtensionAmount int64       `json:"extension_amount" gorm:"default:0"`
+	OvertimeAmount  int64       `json:"overtime_amount" gorm:"default:0"`
+	TotalAmount     int64       `json:"total_amount" gorm:"default:0"`
+	WarningSentAt   *time.Time  `json:"warning_sent_at"`
+	StoppedAt       *time.Time  `json:"stopped_at"`
+	StartedBy       uuid.UUID   `json:"started_by" gorm:"type:char(36);not null"`
+	StoppedBy       *uuid.UUID  `json:"stopped_by" gorm:"type:char(36)"`
+	Notes           string      `json:"notes"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+}
+
+func (s *UsageSession) BeforeCreate(tx *gorm.DB) error {
+	s.ID = uuid.New()
+	return nil
+}
+
+// IsOpen reports whether the session still holds its computer
+func (s *UsageSession) IsOpen() bool {
+	return s.Status == SessionStatusActive || s.Status == SessionStatusPaused
+}
+
+// RemainingMinutes returns the whole minutes left before the planned end time
+func (s *UsageSession) RemainingMinutes(now time.Time) int {
+	if s.PausedAt != nil {
+		now = *s.PausedAt
+	}
+	remaining := s.EndTime.Sub(now).Minutes()
+	if remaining < 0 {
+		return 0
+	}
+	return int(math.Ceil(remaining))
+}
+
+// CalculateCharge computes the final amount owed when the session closes at the given time.
+// Time used past the planned end is billed per started minute at the package rate.
+func (s *UsageSession) CalculateCharge(closedAt time.Time) {
+	s.OvertimeAmount = 0
+	if closedAt.After(s.EndTime) {
+		overtimeMinutes := math.Ceil(closedAt.Sub(s.EndTime).Minutes())
+		s.OvertimeAmount = int64(math.Round(overtimeMinutes * s.Package.PricePerMinute()))
+	}
+	s.TotalAmount = s.BaseAmount + s.ExtensionAmount + s.OvertimeAmount
+}
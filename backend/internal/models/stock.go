@@ -0,0 +1,119 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Stock movement types
+const (
+	StockMovementPurchase    = "purchase"
+	StockMovementConsumption = "consumption"
+	StockMovementWaste       = "waste"
+	StockMovementAdjustment  = "adjustment"
+)
+
+var ErrStockMovementImmutable = errors.New("stock movements cannot be modified")
+
+type StockItem struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null"`
+	Unit      string    `json:"unit" gorm:"not null"` // kg, liters, pcs, ...
+	Category  string    `json:"category"`
+	MinLevel  float64   `json:"min_level" gorm:"default:0"`
+	UnitCost  int64     `json:"unit_cost" gorm:"default:0"` // latest purchase cost in rupiah
+	IsActive  bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (s *StockItem) BeforeCreate(tx *gorm.DB) error {
+	s.ID = uuid.New()
+	return nil
+}
+
+// StockMovement is an append-only change to a stock item's on-hand quantity
+type StockMovement struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	StockItemID uuid.UUID  `json:"stock_item_id" gorm:"type:char(36);not null;index"`
+	StockItem   *StockItem `json:"stock_item,omitempty" gorm:"foreignKey:StockItemID"`
+	Type        string     `json:"type" gorm:"type:varchar(20);not null"` // purchase, consumption, waste, adjustment
+	Quantity    float64    `json:"quantity" gorm:"not null"`              // signed: positive adds stock, negative removes it
+	UnitCost    int64      `json:"unit_cost" gorm:"default:0"`            // in rupiah
+	Reason      string     `json:"reason"`
+	ReferenceID *uuid.UUID `json:"reference_id" gorm:"type:char(36);index"` // e.g. the order that consumed it
+	CreatedBy   uuid.UUID  `json:"created_by" gorm:"type:char(36);not null"`
+	Creator     *User      `json:"creator,omitempty" gorm:"foreignKey:CreatedBy"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (m *StockMovement) BeforeCreate(tx *gorm.DB) error {
+	m.ID = uuid.New()
+	return nil
+}
+
+func (m *StockMovement) BeforeUpdate(tx *gorm.DB) error {
+	return ErrStockMovementImmutable
+}
+
+func (m *StockMovement) BeforeDelete(tx *gorm.DB) error {
+	return ErrStockMovementImmutable
+}
+
+// IsValidStockMovementType checks if the given type is a known movement type
+func IsValidStockMovementType(movementType string) bool {
+	switch movementType {
+	case StockMovementPurchase, StockMovementConsumption, StockMovementWaste, StockMovementAdjustment:
+		return true
+	}
+	return false
+}
+
+// SignedStockQuantity applies the direction implied by the movement type.
+// Adjustments keep the sign given by the caller.
+func SignedStockQuantity(movementType string, quantity float64) float64 {
+	switch movementType {
+	case StockMovementPurchase:
+		if quantity < 0 {
+			return -quantity
+		}
+	case StockMovementConsumption, StockMovementWaste:
+		if quantity > 0 {
+			return -quantity
+		}
+	}
+	return quantity
+}
+
+// GetOnHandQuantity sums the movements of a single stock item
+func GetOnHandQuantity(db *gorm.DB, stockItemID uuid.UUID) float64 {
+	var onHand float64
+	db.Model(&StockMovement{}).Where("stock_item_id = ?", stockItemID).
+		Select("COALESCE(SUM(quantity), 0)").Scan(&onHand)
+	return onHand
+}
+
+// GetOnHandQuantities sums movements for every stock item, keyed by item ID
+func GetOnHandQuantities(db *gorm.DB) map[uuid.UUID]float64 {
+	var rows []struct {
+		StockItemID uuid.UUID
+		OnHand      float64
+	}
+	db.Model(&StockMovement{}).Select("stock_item_id, COALESCE(SUM(quantity), 0) AS on_hand").
+		Group("stock_item_id").Scan(&rows)
+
+	levels := make(map[uuid.UUID]float64, len(rows))
+	for _, row := range rows {
+		levels[row.StockItemID] = row.OnHand
+	}
+	return levels
+}
+
+// RecordStockMovement appends a movement, normalising the quantity sign for its type
+func RecordStockMovement(tx *gorm.DB, movement *StockMovement) error {
+	movement.Quantity = SignedStockQuantity(movement.Type, movement.Quantity)
+	return tx.Create(movement).Error
+}
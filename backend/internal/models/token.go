@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RefreshToken is a persisted, single-use refresh token. Only the SHA-256 hash of
+// the token is stored. Using a token rotates it: the old row is revoked and points
+// to its replacement.
+type RefreshToken struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID       uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	SessionID    *uuid.UUID `json:"session_id" gorm:"type:char(36);index"`
+	TokenHash    string     `json:"-" gorm:"type:char(64);uniqueIndex;not null"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedByID *uuid.UUID `json:"replaced_by_id" gorm:"type:char(36)"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (r *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	r.ID = uuid.New()
+	return nil
+}
+
+// IsActive reports whether the token can still be exchanged
+func (r *RefreshToken) IsActive(now time.Time) bool {
+	return r.RevokedAt == nil && now.Before(r.ExpiresAt)
+}
+
+// RevokedToken is an access token revoked before it expired, identified by its jti
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	TokenID   string    `json:"token_id" gorm:"type:varchar(64);uniqueIndex;not null"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);not null;index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r *RevokedToken) BeforeCreate(tx *gorm.DB) error {
+	r.ID = uuid.New()
+	return nil
+}
+
+// RevokeAllUserTokens invalidates every access and refresh token issued to the user so far
+// and ends all of the user's device sessions
+func RevokeAllUserTokens(tx *gorm.DB, userID uuid.UUID) error {
+	now := time.Now()
+	if err := tx.Model(&User{}).Where("id = ?", userID).Update("tokens_valid_after", now).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&DeviceSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
+
+// PasswordResetToken is a one-time token an admin issues so a user can set a new password
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	TokenHash string     `json:"-" gorm:"type:char(64);uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedBy uuid.UUID  `json:"created_by" gorm:"type:char(36);not null"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (p *PasswordResetToken) BeforeCreate(tx *gorm.DB) error {
+	p.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Transaction types
+const (
+	TransactionSale     = "sale"
+	TransactionPurchase = "purchase"
+	TransactionVoid     = "void"
+	TransactionRefund   = "refund"
+)
+
+// Transaction source types
+const (
+	TransactionSourceSession  = "session"
+	TransactionSourceOrder    = "order"
+	TransactionSourceTopUp    = "topup"
+	TransactionSourceSupplier = "supplier"
+)
+
+// Payment methods
+const (
+	PaymentCash          = "cash"
+	PaymentQRIS          = "qris"
+	PaymentTransfer      = "transfer"
+	PaymentMemberBalance = "member_balance"
+)
+
+var ErrTransactionImmutable = errors.New("transactions cannot be modified, record a void or refund instead")
+
+// Transaction is an append-only entry in the point-of-sale ledger. Amounts are signed
+// rupiah: money in (sales, top-ups) is positive, money out (purchases) is negative, and
+// voids and refunds carry the opposite sign of the entry they reverse.
+type Transaction struct {
+	ID            uuid.UUID    `json:"id" gorm:"type:char(36);primaryKey"`
+	Type          string       `json:"type" gorm:"type:varchar(20);not null;index"`        // sale, purchase, void, refund
+	SourceType    string       `json:"source_type" gorm:"type:varchar(20);not null;index"` // session, order, topup, supplier
+	SourceID      *uuid.UUID   `json:"source_id" gorm:"type:char(36);index"`
+	CustomerID    *uuid.UUID   `json:"customer_id" gorm:"type:char(36);index"`
+	Supplier      string       `json:"supplier"`
+	Description   string       `json:"description"`
+	Amount        int64        `json:"amount" gorm:"not null"`                                // in rupiah
+	PaymentMethod string       `json:"payment_method" gorm:"type:varchar(20);not null;index"` // cash, qris, transfer, member_balance
+	OriginalID    *uuid.UUID   `json:"original_id" gorm:"type:char(36);index"`
+	Original      *Transaction `json:"original,omitempty" gorm:"foreignKey:OriginalID"`
+	Reason        string       `json:"reason"`
+	CashierID     uuid.UUID    `json:"cashier_id" gorm:"type:char(36);not null;index"`
+	Cashier       *User        `json:"cashier,omitempty" gorm:"foreignKey:CashierID"`
+	ShiftID       *uuid.UUID   `json:"shift_id" gorm:"type:char(36);index"` // set for cash transactions
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+func (t *Transaction) BeforeCreate(tx *gorm.DB) error {
+	t.ID = uuid.New()
+	return nil
+}
+
+func (t *Transaction) BeforeUpdate(tx *gorm.DB) error {
+	return ErrTransactionImmutable
+}
+
+func (t *Transaction) BeforeDelete(tx *gorm.DB) error {
+	return ErrTransactionImmutable
+}
+
+// IsValidPaymentMethod checks if the given payment method is accepted
+func IsValidPaymentMethod(method string) bool {
+	switch method {
+	case PaymentCash, PaymentQRIS, PaymentTransfer, PaymentMemberBalance:
+		return true
+	}
+	return false
+}
+
+// GetReversedAmount returns how much of a transaction has been voided or refunded,
+// as a positive rupiah amount
+func GetReversedAmount(db *gorm.DB, transactionID uuid.UUID) int64 {
+	var reversed int64
+	db.Model(&Transaction{}).
+		Select("COALESCE(SUM(ABS(amount)), 0)").
+		Where("original_id = ? AND type IN ?", transactionID, []string{TransactionVoid, TransactionRefund}).
+		Scan(&reversed)
+	return reversed
+}
+
+// IsSourcePaid reports whether a session or order already has a sale that was not voided
+func IsSourcePaid(db *gorm.DB, sourceType string, sourceID uuid.UUID) bool {
+	_, err := GetPaidSale(db, sourceType, sourceID)
+	return err == nil
+}
+
+// GetPaidSale returns the sale that paid for a session or order and was not voided
+func GetPaidSale(db *gorm.DB, sourceType string, sourceID uuid.UUID) (*Transaction, error) {
+	var sale Transaction
+	if err := db.Where("type = ? AND source_type = ? AND source_id = ?", TransactionSale, sourceType, sourceID).
+		Where("NOT EXISTS (SELECT 1 FROM transactions v WHERE v.original_id = transactions.id AND v.type = ?)", TransactionVoid).
+		Order("created_at DESC").First(&sale).Error; err != nil {
+		return nil, err
+	}
+	return &sale, nil
+}
+
+// GetMemberBalance returns a customer's prepaid balance: top-ups less member balance payments
+func GetMemberBalance(db *gorm.DB, customerID uuid.UUID) int64 {
+	var balance int64
+	db.Model(&Transaction{}).
+		Select("COALESCE(SUM(CASE WHEN source_type = ? THEN amount ELSE 0 END), 0) - "+
+			"COALESCE(SUM(CASE WHEN source_type <> ? AND payment_method = ? THEN amount ELSE 0 END), 0)",
+			TransactionSourceTopUp, TransactionSourceTopUp, PaymentMemberBalance).
+		Where("customer_id = ?", customerID).
+		Scan(&balance)
+	return balance
+}
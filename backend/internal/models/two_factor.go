@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the authenticator
+// device is lost. Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
+	CodeHash  string     `json:"-" gorm:"type:char(64);not null;index"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (r *RecoveryCode) BeforeCreate(tx *gorm.DB) error {
+	r.ID = uuid.New()
+	return nil
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes and stores the given hashes
+func ReplaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	codes := make([]RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, RecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It reports false when the
+// code does not exist or was already used.
+func UseRecoveryCode(db *gorm.DB, userID uuid.UUID, codeHash string, now time.Time) bool {
+	result := db.Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", now)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// CountUnusedRecoveryCodes returns how many recovery codes the user has left
+func CountUnusedRecoveryCodes(db *gorm.DB, userID uuid.UUID) int64 {
+	var count int64
+	db.Model(&RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count)
+	return count
+}
+
+// ClearTwoFactor disables two-factor authentication for the user and removes the
+// secret and recovery codes
+func ClearTwoFactor(tx *gorm.DB, userID uuid.UUID) error {
+	if err := tx.Model(&User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":    "",
+		"totp_enabled":   false,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error
+}
+
+// RoleRequiresTwoFactor reports whether the role holds admin rights, which makes
+// two-factor authentication mandatory when the policy flag is on
+func RoleRequiresTwoFactor(db *gorm.DB, roleID uuid.UUID) bool {
+	var role Role
+	if err := db.Select("id", "permissions").Where("id = ?", roleID).First(&role).Error; err != nil {
+		return false
+	}
+	return role.HasAdminRights()
+}
@@ -9,20 +9,25 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
-	Username     string    `json:"username" gorm:"uniqueIndex;not null"`
-	Email        string    `json:"email" gorm:"uniqueIndex;not null"`
-	Password     string    `json:"-" gorm:"not null"`
-	Name         string    `json:"name" gorm:"not null"`
-	Phone        string    `json:"phone"`
-	Address      string    `json:"address"`
-	KTPNumber    string    `json:"ktp_number" gorm:"uniqueIndex"`
-	EmployeeID   string    `json:"employee_id" gorm:"uniqueIndex"`
-	RoleID       uuid.UUID `json:"role_id" gorm:"type:char(36);not null"`
-	Role         Role      `json:"role" gorm:"foreignKey:RoleID"`
-	IsActive     bool      `json:"is_active" gorm:"default:true"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID                 uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	Username           string     `json:"username" gorm:"uniqueIndex;not null"`
+	Email              string     `json:"email" gorm:"uniqueIndex;not null"`
+	Password           string     `json:"-" gorm:"not null"`
+	Name               string     `json:"name" gorm:"not null"`
+	Phone              string     `json:"phone"`
+	Address            string     `json:"address"`
+	KTPNumber          string     `json:"ktp_number" gorm:"uniqueIndex"`
+	EmployeeID         string     `json:"employee_id" gorm:"uniqueIndex"`
+	RoleID             uuid.UUID  `json:"role_id" gorm:"type:char(36);not null"`
+	Role               Role       `json:"role" gorm:"foreignKey:RoleID"`
+	IsActive           bool       `json:"is_active" gorm:"default:true"`
+	TokensValidAfter   *time.Time `json:"-"` // tokens issued before this time are rejected
+	MustChangePassword bool       `json:"must_change_password" gorm:"default:false"`
+	TOTPSecret         string     `json:"-"` // base32, set at setup and kept while enabled
+	TOTPEnabled        bool       `json:"totp_enabled" gorm:"default:false"`
+	TOTPLastStep       int64      `json:"-"` // last accepted time step, so a code works only once
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
@@ -45,29 +50,34 @@ func (u *User) CheckPassword(password string) bool {
 }
 
 type UserResponse struct {
-	ID         uuid.UUID `json:"id"`
-	Username   string    `json:"username"`
-	Email      string    `json:"email"`
-	Name       string    `json:"name"`
-	Phone      string    `json:"phone"`
-	Address    string    `json:"address"`
-	KTPNumber  string    `json:"ktp_number"`
-	EmployeeID string    `json:"employee_id"`
-	Role       Role      `json:"role"`
-	IsActive   bool      `json:"is_active"`
+	ID                 uuid.UUID      `json:"id"`
+	Username           string         `json:"username"`
+	Email              string         `json:"email"`
+	Name               string         `json:"name"`
+	Phone              string         `json:"phone"`
+	Address            string         `json:"address"`
+	KTPNumber          string         `json:"ktp_number"`
+	EmployeeID         string         `json:"employee_id"`
+	Role               Role           `json:"role"`
+	IsActive           bool           `json:"is_active"`
+	MustChangePassword bool           `json:"must_change_password"`
+	TOTPEnabled        bool           `json:"totp_enabled"`
+	Lockout            *LockoutStatus `json:"lockout,omitempty"` // set on staff management responses
 }
 
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:         u.ID,
-		Username:   u.Username,
-		Email:      u.Email,
-		Name:       u.Name,
-		Phone:      u.Phone,
-		Address:    u.Address,
-		KTPNumber:  u.KTPNumber,
-		EmployeeID: u.EmployeeID,
-		Role:       u.Role,
-		IsActive:   u.IsActive,
+		ID:                 u.ID,
+		Username:           u.Username,
+		Email:              u.Email,
+		Name:               u.Name,
+		Phone:              u.Phone,
+		Address:            u.Address,
+		KTPNumber:          u.KTPNumber,
+		EmployeeID:         u.EmployeeID,
+		Role:               u.Role,
+		IsActive:           u.IsActive,
+		MustChangePassword: u.MustChangePassword,
+		TOTPEnabled:        u.TOTPEnabled,
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Shift is a working shift definition. Start and end are wall-clock times (HH:MM); an
+// end at or before the start means the shift finishes the next day.
+type Shift struct {
+	ID           uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	Name         string    `json:"name" gorm:"not null;uniqueIndex"`
+	StartTime    string    `json:"start_time" gorm:"type:varchar(5);not null"` // HH:MM
+	EndTime      string    `json:"end_time" gorm:"type:varchar(5);not null"`   // HH:MM
+	GraceMinutes int       `json:"grace_minutes" gorm:"default:0"`             // check-in this late still counts as on time
+	BreakMinutes int       `json:"break_minutes" gorm:"default:0"`             // unpaid break, not counted as working time
+	IsDefault    bool      `json:"is_default" gorm:"default:false"`            // used for days with no roster entry
+	IsActive     bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (s *Shift) BeforeCreate(tx *gorm.DB) error {
+	s.ID = uuid.New()
+	return nil
+}
+
+// fallbackShift is the office day the attendance reports used before shifts existed,
+// applied when no default shift has been configured
+var fallbackShift = Shift{Name: "Office hours", StartTime: "09:00", EndTime: "17:00"}
+
+// ParseShiftClock parses an HH:MM time of day
+func ParseShiftClock(value string) (time.Time, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return time.Time{}, errors.New("times must be in HH:MM format")
+	}
+	return parsed, nil
+}
+
+// Validate checks the shift's times and minute values, and stores both times as HH:MM
+func (s *Shift) Validate() error {
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	start, err := ParseShiftClock(s.StartTime)
+	if err != nil {
+		return err
+	}
+	end, err := ParseShiftClock(s.EndTime)
+	if err != nil {
+		return err
+	}
+	s.StartTime, s.EndTime = start.Format("15:04"), end.Format("15:04")
+	if s.GraceMinutes < 0 || s.BreakMinutes < 0 {
+		return errors.New("grace and break minutes cannot be negative")
+	}
+	if s.BreakMinutes >= int(s.Length()/time.Minute) {
+		return errors.New("break must be shorter than the shift")
+	}
+	return nil
+}
+
+// IsOvernight reports whether the shift ends on the day after it starts
+func (s *Shift) IsOvernight() bool {
+	start, _ := ParseShiftClock(s.StartTime)
+	end, _ := ParseShiftClock(s.EndTime)
+	return !end.After(start)
+}
+
+// Length returns the time from shift start to shift end, including the break
+func (s *Shift) Length() time.Duration {
+	start, _ := ParseShiftClock(s.StartTime)
+	end, _ := ParseShiftClock(s.EndTime)
+	if s.IsOvernight() {
+		end = end.Add(24 * time.Hour)
+	}
+	return end.Sub(start)
+}
+
+// PlannedHours returns the working time the shift expects, excluding the break
+func (s *Shift) PlannedHours() float64 {
+	return (s.Length() - time.Duration(s.BreakMinutes)*time.Minute).Hours()
+}
+
+// Window returns when the shift starts and ends for the given date, in that date's time zone
+func (s *Shift) Window(date time.Time) (time.Time, time.Time) {
+	clock, _ := ParseShiftClock(s.StartTime)
+	start := time.Date(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), 0, 0, date.Location())
+	return start, start.Add(s.Length())
+}
+
+// ShiftRoster assigns an employee a shift on a date. There is at most one entry per
+// employee per day; days without an entry use the default shift.
+type ShiftRoster struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);not null;uniqueIndex:idx_shift_roster_user_date"`
+	User      *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	ShiftID   uuid.UUID `json:"shift_id" gorm:"type:char(36);not null;index"`
+	Shift     *Shift    `json:"shift,omitempty" gorm:"foreignKey:ShiftID"`
+	Date      time.Time `json:"date" gorm:"type:date;not null;uniqueIndex:idx_shift_roster_user_date"`
+	Notes     string    `json:"notes"`
+	CreatedBy uuid.UUID `json:"created_by" gorm:"type:char(36)"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r *ShiftRoster) BeforeCreate(tx *gorm.DB) error {
+	r.ID = uuid.New()
+	return nil
+}
+
+// GetDefaultShift returns the configured default shift, or the built-in 09:00-17:00
+// office day when none is set
+func GetDefaultShift(db *gorm.DB) *Shift {
+	var shift Shift
+	if err := db.Where("is_default = ? AND is_active = ?", true, true).First(&shift).Error; err != nil {
+		fallback := fallbackShift
+		return &fallback
+	}
+	return &shift
+}
+
+// GetShiftForDate returns the employee's rostered shift on the date, or the default shift
+func GetShiftForDate(db *gorm.DB, userID uuid.UUID, date time.Time) *Shift {
+	var entry ShiftRoster
+	if err := db.Preload("Shift").Where("user_id = ? AND date = ?", userID, date.Format("2006-01-02")).
+		First(&entry).Error; err == nil && entry.Shift != nil {
+		return entry.Shift
+	}
+	return GetDefaultShift(db)
+}
+
+// HasRosterEntry reports whether the employee was explicitly rostered on the date
+func HasRosterEntry(db *gorm.DB, userID uuid.UUID, date time.Time) bool {
+	var count int64
+	db.Model(&ShiftRoster{}).Where("user_id = ? AND date = ?", userID, date.Format("2006-01-02")).Count(&count)
+	return count > 0
+}
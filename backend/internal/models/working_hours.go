@@ -0,0 +1,141 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimezone is used for locations and records without a valid time zone
+const DefaultTimezone = "Asia/Jakarta"
+
+// WorkingHours is a location's opening schedule. Days are ISO weekdays, 1 (Monday) to
+// 7 (Sunday); an empty list places no restriction on the day.
+type WorkingHours struct {
+	Start string `json:"start"` // HH:MM
+	End   string `json:"end"`   // HH:MM
+	Days  []int  `json:"days"`
+}
+
+// Value stores the working hours as JSON
+func (w WorkingHours) Value() (driver.Value, error) {
+	data, err := json.Marshal(w)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan reads stored working hours; NULL reads as no schedule
+func (w *WorkingHours) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*w = WorkingHours{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into WorkingHours", value)
+	}
+	if len(data) == 0 {
+		*w = WorkingHours{}
+		return nil
+	}
+	return json.Unmarshal(data, w)
+}
+
+// UnmarshalJSON accepts an object, or a string holding a JSON object as older clients send
+func (w *WorkingHours) UnmarshalJSON(data []byte) error {
+	type plain WorkingHours
+	var hours plain
+	if err := json.Unmarshal(data, &hours); err == nil {
+		*w = WorkingHours(hours)
+		return nil
+	}
+
+	var encoded string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return errors.New("working hours must be an object with start, end and days")
+	}
+	if strings.TrimSpace(encoded) == "" {
+		*w = WorkingHours{}
+		return nil
+	}
+	if err := json.Unmarshal([]byte(encoded), &hours); err != nil {
+		return errors.New("working hours must be an object with start, end and days")
+	}
+	*w = WorkingHours(hours)
+	return nil
+}
+
+// Validate checks the times and weekdays
+func (w *WorkingHours) Validate() error {
+	if w.Start != "" || w.End != "" {
+		if _, err := ParseShiftClock(w.Start); err != nil {
+			return err
+		}
+		if _, err := ParseShiftClock(w.End); err != nil {
+			return err
+		}
+	}
+	for _, day := range w.Days {
+		if day < 1 || day > 7 {
+			return errors.New("days must be between 1 (Monday) and 7 (Sunday)")
+		}
+	}
+	return nil
+}
+
+// IsWorkingDay reports whether the site is open on the date's weekday
+func (w *WorkingHours) IsWorkingDay(date time.Time) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	weekday := int(date.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	for _, day := range w.Days {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+var timezoneCache sync.Map
+
+// LoadTimezone returns the named IANA time zone, such as Asia/Makassar (WITA) or
+// Asia/Jayapura (WIT). Unknown or empty names fall back to DefaultTimezone.
+func LoadTimezone(name string) *time.Location {
+	if name == "" {
+		name = DefaultTimezone
+	}
+	if cached, ok := timezoneCache.Load(name); ok {
+		return cached.(*time.Location)
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		if name != DefaultTimezone {
+			return LoadTimezone(DefaultTimezone)
+		}
+		// Without a time zone database, WIB is a fixed UTC+7
+		location = time.FixedZone("WIB", 7*60*60)
+	}
+	timezoneCache.Store(name, location)
+	return location
+}
+
+// IsValidTimezone reports whether the name is a known IANA time zone
+func IsValidTimezone(name string) bool {
+	_, err := time.LoadLocation(name)
+	return err == nil && name != ""
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"cybercafe-backend/internal/config"
+	"cybercafe-backend/internal/models"
+)
+
+// Notifier delivers account messages to staff. Implementations must not log or
+// store the reset token anywhere the requesting admin could read it back.
+type Notifier interface {
+	SendPasswordReset(user models.User, token string, expiresAt time.Time) error
+}
+
+// ErrNotConfigured is returned when no delivery channel has been set up
+var ErrNotConfigured = errors.New("no notification channel is configured; set NOTIFIER=webhook and NOTIFY_WEBHOOK_URL")
+
+// New returns the notifier selected by NOTIFIER: "webhook" posts to NOTIFY_WEBHOOK_URL.
+// Without a configured channel nothing is delivered, so password resets are refused.
+func New(cfg *config.Config) Notifier {
+	if cfg.Notifier == "webhook" && cfg.NotifyWebhookURL != "" {
+		return &WebhookNotifier{
+			url:    cfg.NotifyWebhookURL,
+			client: &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+	log.Println("[NOTIFY] No notification channel configured; password resets cannot be delivered")
+	return &UnconfiguredNotifier{}
+}
+
+// UnconfiguredNotifier refuses every message. Reset tokens are never written to the
+// server log, where the requesting admin could read them back.
+type UnconfiguredNotifier struct{}
+
+func (n *UnconfiguredNotifier) SendPasswordReset(user models.User, token string, expiresAt time.Time) error {
+	log.Printf("[NOTIFY] Password reset for %s, expiring %s, not delivered: no notification channel configured",
+		user.Username, expiresAt.Format(time.RFC3339))
+	return ErrNotConfigured
+}
+
+// WebhookNotifier posts messages as JSON to an external service (email, WhatsApp gateway, ...)
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *WebhookNotifier) SendPasswordReset(user models.User, token string, expiresAt time.Time) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":       "password_reset",
+		"user_id":    user.ID,
+		"username":   user.Username,
+		"email":      user.Email,
+		"phone":      user.Phone,
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notification webhook returned %s", resp.Status)
+	}
+	return nil
+}
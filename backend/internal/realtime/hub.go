@@ -0,0 +1,208 @@
+package realtime
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"cybercafe-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Event topics
+const (
+	TopicOrders     = "orders"
+	TopicSessions   = "sessions"
+	TopicComputers  = "computers"
+	TopicAttendance = "attendance"
+)
+
+var AllTopics = []string{TopicOrders, TopicSessions, TopicComputers, TopicAttendance}
+
+var ErrHubClosed = errors.New("event hub is closed")
+
+// subscriberBuffer is how many events a slow client may fall behind before events are dropped
+const subscriberBuffer = 64
+
+// TopicAccess describes a topic a role may subscribe to. OwnOnly limits the
+// subscriber to events that concern the subscriber's own user.
+type TopicAccess struct {
+	Topic   string
+	OwnOnly bool
+}
+
+// topicPermissions maps each topic to the permission needed to receive it. An own-scope
+// grant limits the topic to events about the subscriber.
+var topicPermissions = map[string]string{
+	TopicOrders:     "orders.read",
+	TopicSessions:   "sessions.read",
+	TopicComputers:  "computers.read",
+	TopicAttendance: "attendance.read",
+}
+
+// TopicsForRole returns the topics the role's permissions allow it to subscribe to
+func TopicsForRole(role *models.Role) []TopicAccess {
+	var access []TopicAccess
+	for _, topic := range AllTopics {
+		granted, scope := role.Grants(topicPermissions[topic])
+		if !granted {
+			continue
+		}
+		access = append(access, TopicAccess{Topic: topic, OwnOnly: scope == models.ScopeOwn})
+	}
+	return access
+}
+
+type Event struct {
+	Topic     string      `json:"topic"`
+	Type      string      `json:"type"`
+	Data      interface{} `json:"data"`
+	Timestamp time.Time   `json:"timestamp"`
+
+	// UserID is the user the event concerns, used for own-only topics
+	UserID *uuid.UUID `json:"-"`
+}
+
+type Subscriber struct {
+	UserID uuid.UUID
+	Events chan Event
+
+	topics map[string]TopicAccess
+}
+
+func (s *Subscriber) accepts(event Event) bool {
+	access, ok := s.topics[event.Topic]
+	if !ok {
+		return false
+	}
+	if access.OwnOnly {
+		return event.UserID != nil && *event.UserID == s.UserID
+	}
+	return true
+}
+
+// Hub fans events out to connected stream subscribers
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscriber]struct{}
+	closed      bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[*Subscriber]struct{})}
+}
+
+// Subscribe registers a subscriber for the requested topics, limited to the allowed
+// ones. An empty request subscribes to every allowed topic.
+func (h *Hub) Subscribe(userID uuid.UUID, allowed []TopicAccess, requested []string) (*Subscriber, error) {
+	wanted := make(map[string]bool, len(requested))
+	for _, topic := range requested {
+		wanted[topic] = true
+	}
+
+	topics := make(map[string]TopicAccess)
+	for _, access := range allowed {
+		if len(wanted) == 0 || wanted[access.Topic] {
+			topics[access.Topic] = access
+		}
+	}
+	if len(topics) == 0 {
+		return nil, errors.New("no permitted topics requested")
+	}
+
+	subscriber := &Subscriber{
+		UserID: userID,
+		Events: make(chan Event, subscriberBuffer),
+		topics: topics,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+	h.subscribers[subscriber] = struct{}{}
+	return subscriber, nil
+}
+
+// Unsubscribe removes a subscriber and closes its event channel
+func (h *Hub) Unsubscribe(subscriber *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[subscriber]; ok {
+		delete(h.subscribers, subscriber)
+		close(subscriber.Events)
+	}
+}
+
+// Topics returns the topic names a subscriber receives
+func (s *Subscriber) Topics() []string {
+	topics := make([]string, 0, len(s.topics))
+	for topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// PermittedBy reports whether the allowed topics still cover everything the subscriber
+// receives, e.g. after the role's permissions were changed
+func (s *Subscriber) PermittedBy(allowed []TopicAccess) bool {
+	permitted := make(map[string]TopicAccess, len(allowed))
+	for _, access := range allowed {
+		permitted[access.Topic] = access
+	}
+	for topic, current := range s.topics {
+		access, ok := permitted[topic]
+		if !ok || (access.OwnOnly && !current.OwnOnly) {
+			return false
+		}
+	}
+	return true
+}
+
+// Publish broadcasts an event to every subscriber allowed to see it. It never blocks;
+// subscribers that are too far behind miss the event.
+func (h *Hub) Publish(topic, eventType string, data interface{}) {
+	h.publish(Event{Topic: topic, Type: eventType, Data: data})
+}
+
+// PublishForUser broadcasts an event that concerns a specific user
+func (h *Hub) PublishForUser(topic, eventType string, userID uuid.UUID, data interface{}) {
+	h.publish(Event{Topic: topic, Type: eventType, Data: data, UserID: &userID})
+}
+
+func (h *Hub) publish(event Event) {
+	if h == nil {
+		return
+	}
+	event.Timestamp = time.Now()
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for subscriber := range h.subscribers {
+		if !subscriber.accepts(event) {
+			continue
+		}
+		select {
+		case subscriber.Events <- event:
+		default:
+			log.Printf("[REALTIME] Dropping %s event for slow subscriber %s", event.Type, subscriber.UserID)
+		}
+	}
+}
+
+// Close disconnects every subscriber so open streams can finish during shutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for subscriber := range h.subscribers {
+		delete(h.subscribers, subscriber)
+		close(subscriber.Events)
+	}
+}
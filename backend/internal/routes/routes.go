@@ -4,25 +4,43 @@ import (
 	"cybercafe-backend/internal/config"
 	"cybercafe-backend/internal/handlers"
 	"cybercafe-backend/internal/middleware"
+	"cybercafe-backend/internal/notify"
+	"cybercafe-backend/internal/realtime"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
-func Setup(app *fiber.App, db *gorm.DB, cfg *config.Config) {
+func Setup(app *fiber.App, db *gorm.DB, cfg *config.Config, hub *realtime.Hub, notifier notify.Notifier) {
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(db, cfg)
-	staffHandler := handlers.NewStaffHandler(db)
+	staffHandler := handlers.NewStaffHandler(db, cfg, notifier)
 	roleHandler := handlers.NewRoleHandler(db)
-	attendanceHandler := handlers.NewAttendanceHandler(db, cfg)
+	attendanceHandler := handlers.NewAttendanceHandler(db, cfg, hub)
 	auditHandler := handlers.NewAuditHandler(db)
 	locationHandler := handlers.NewLocationHandler(db)
+	locationAssignmentHandler := handlers.NewLocationAssignmentHandler(db)
+	remoteWorkHandler := handlers.NewRemoteWorkHandler(db)
+	leaveHandler := handlers.NewLeaveHandler(db, cfg)
+	holidayHandler := handlers.NewHolidayHandler(db)
+	workShiftHandler := handlers.NewWorkShiftHandler(db)
 	mealAllowanceHandler := handlers.NewMealAllowanceHandler(db, cfg)
 	dashboardHandler := handlers.NewDashboardHandler(db, cfg)
+	computerHandler := handlers.NewComputerHandler(db, hub)
+	timePackageHandler := handlers.NewTimePackageHandler(db)
+	sessionHandler := handlers.NewSessionHandler(db, hub)
+	customerHandler := handlers.NewCustomerHandler(db)
+	stockHandler := handlers.NewStockHandler(db)
+	menuHandler := handlers.NewMenuHandler(db)
+	orderHandler := handlers.NewOrderHandler(db, hub)
+	transactionHandler := handlers.NewTransactionHandler(db)
+	cashierShiftHandler := handlers.NewCashierShiftHandler(db)
+	streamHandler := handlers.NewStreamHandler(db, hub)
 
 	// Initialize middleware
-	authMiddleware := middleware.AuthRequired(cfg)
+	authMiddleware := middleware.AuthRequired(cfg, db)
 	auditMiddleware := middleware.AuditLogger(db)
+	perm := middleware.NewPermissionGuard(db)
 
 	// API routes
 	api := app.Group("/api")
@@ -30,73 +48,220 @@ func Setup(app *fiber.App, db *gorm.DB, cfg *config.Config) {
 	// Auth routes (public)
 	auth := api.Group("/auth")
 	auth.Post("/login", authHandler.Login)
+	auth.Post("/refresh", authHandler.RefreshToken)
 	auth.Post("/logout", authMiddleware, authHandler.Logout)
 	auth.Get("/me", authMiddleware, authHandler.GetProfile)
+	auth.Post("/change-password", authMiddleware, authHandler.ChangePassword)
+	auth.Post("/reset-password", authHandler.ResetPassword)
+	auth.Get("/password-policy", authHandler.GetPasswordPolicy)
+	auth.Get("/sessions", authMiddleware, authHandler.GetSessions)
+	auth.Delete("/sessions/:id", authMiddleware, authHandler.RevokeSession)
+	auth.Post("/2fa/verify", authHandler.VerifyTwoFactor)
+	auth.Get("/2fa", authMiddleware, authHandler.GetTwoFactorStatus)
+	auth.Post("/2fa/setup", authMiddleware, authHandler.SetupTwoFactor)
+	auth.Post("/2fa/enable", authMiddleware, authHandler.EnableTwoFactor)
+	auth.Post("/2fa/disable", authMiddleware, authHandler.DisableTwoFactor)
+	auth.Post("/2fa/recovery-codes", authMiddleware, authHandler.RegenerateRecoveryCodes)
+
+	// Real-time event stream (SSE). Browsers' EventSource cannot set headers,
+	// so the token may also be passed as ?token=
+	api.Get("/stream", middleware.StreamAuthRequired(cfg, db), streamHandler.Stream)
 
 	// Protected routes
 	protected := api.Group("", authMiddleware, auditMiddleware)
 
 	// Staff routes
 	staff := protected.Group("/staff")
-	staff.Get("/", staffHandler.GetAllStaff)
-	staff.Post("/", staffHandler.CreateStaff)
-	staff.Get("/:id", staffHandler.GetStaffByID)
-	staff.Put("/:id", staffHandler.UpdateStaff)
-	staff.Delete("/:id", staffHandler.DeleteStaff)
+	staff.Get("/", perm.Require("staff.read"), staffHandler.GetAllStaff)
+	staff.Post("/", perm.Require("staff.write"), staffHandler.CreateStaff)
+	staff.Get("/:id", perm.Require("staff.read"), staffHandler.GetStaffByID)
+	staff.Put("/:id", perm.Require("staff.write"), staffHandler.UpdateStaff)
+	staff.Delete("/:id", perm.Require("staff.delete"), staffHandler.DeleteStaff)
+	staff.Post("/:id/reset-password", perm.Require("staff.write"), staffHandler.ResetStaffPassword)
+	staff.Post("/:id/unlock", perm.Require("staff.write"), staffHandler.UnlockStaff)
+	staff.Post("/:id/reset-2fa", perm.Require("staff.write"), staffHandler.ResetStaffTwoFactor)
+	staff.Get("/:id/sessions", perm.Require("staff.read"), staffHandler.GetStaffSessions)
+	staff.Delete("/:id/sessions", perm.Require("staff.write"), staffHandler.RevokeStaffSessions)
 
 	// Role routes
 	roles := protected.Group("/roles")
-	roles.Get("/", roleHandler.GetAllRoles)
-	roles.Post("/", roleHandler.CreateRole)
-	roles.Get("/:id", roleHandler.GetRoleByID)
-	roles.Put("/:id", roleHandler.UpdateRole)
-	roles.Delete("/:id", roleHandler.DeleteRole)
+	roles.Get("/", perm.Require("roles.read"), roleHandler.GetAllRoles)
+	roles.Get("/permissions", perm.Require("roles.read"), roleHandler.GetPermissionCatalog)
+	roles.Post("/", perm.Require("roles.write"), roleHandler.CreateRole)
+	roles.Get("/:id", perm.Require("roles.read"), roleHandler.GetRoleByID)
+	roles.Put("/:id", perm.Require("roles.write"), roleHandler.UpdateRole)
+	roles.Delete("/:id", perm.Require("roles.delete"), roleHandler.DeleteRole)
 
 	// Attendance routes
 	attendance := protected.Group("/attendance")
-	attendance.Post("/check-in", attendanceHandler.CheckIn)
-	attendance.Post("/check-out", attendanceHandler.CheckOut)
-	attendance.Get("/my", attendanceHandler.GetMyAttendance)
-	attendance.Get("/all", attendanceHandler.GetAllAttendance)
-	attendance.Get("/stats", attendanceHandler.GetAttendanceStats)
-	attendance.Get("/today", attendanceHandler.GetTodayAttendance)
-	attendance.Get("/employee/:userId/detail", attendanceHandler.GetEmployeeAttendanceDetail)
-	attendance.Get("/history", attendanceHandler.GetAttendanceHistory)
-	attendance.Get("/history/stats", attendanceHandler.GetAttendanceStatsByPeriod)
-	attendance.Get("/history/export", attendanceHandler.ExportAttendanceHistory)
-	attendance.Put("/:id", attendanceHandler.UpdateAttendance)
-	attendance.Delete("/:id", attendanceHandler.DeleteAttendance)
+	attendance.Post("/check-in", perm.RequireOwn("attendance.write"), attendanceHandler.CheckIn)
+	attendance.Post("/check-out", perm.RequireOwn("attendance.write"), attendanceHandler.CheckOut)
+	attendance.Get("/my", perm.RequireOwn("attendance.read"), attendanceHandler.GetMyAttendance)
+	attendance.Get("/all", perm.RequireOwn("attendance.read"), attendanceHandler.GetAllAttendance)
+	attendance.Get("/stats", perm.RequireOwn("attendance.read"), attendanceHandler.GetAttendanceStats)
+	attendance.Get("/today", perm.RequireOwn("attendance.read"), attendanceHandler.GetTodayAttendance)
+	attendance.Get("/employee/:userId/detail", perm.RequireOwn("attendance.read"), attendanceHandler.GetEmployeeAttendanceDetail)
+	attendance.Get("/history", perm.RequireOwn("attendance.read"), attendanceHandler.GetAttendanceHistory)
+	attendance.Get("/history/stats", perm.RequireOwn("attendance.read"), attendanceHandler.GetAttendanceStatsByPeriod)
+	attendance.Get("/history/export", perm.Require("reports.read"), attendanceHandler.ExportAttendanceHistory)
+	attendance.Put("/:id", perm.RequireOwn("attendance.write"), attendanceHandler.UpdateAttendance)
+	attendance.Delete("/:id", perm.Require("attendance.delete"), attendanceHandler.DeleteAttendance)
 
 	// Location routes under attendance (sesuai dokumentasi API)
-	attendance.Get("/locations", locationHandler.GetAllLocations)
-	attendance.Post("/locations", locationHandler.CreateLocation)
-	attendance.Get("/locations/nearby", locationHandler.GetNearbyLocations)
-	attendance.Post("/locations/validate", locationHandler.ValidateLocation)
-	attendance.Get("/locations/:id", locationHandler.GetLocationByID)
-	attendance.Put("/locations/:id", locationHandler.UpdateLocation)
-	attendance.Delete("/locations/:id", locationHandler.DeleteLocation)
+	attendance.Get("/locations", perm.RequireOwn("attendance.read"), locationHandler.GetAllLocations)
+	attendance.Post("/locations", perm.Require("locations.write"), locationHandler.CreateLocation)
+	attendance.Get("/locations/nearby", perm.RequireOwn("attendance.read"), locationHandler.GetNearbyLocations)
+	attendance.Post("/locations/validate", perm.RequireOwn("attendance.read"), locationHandler.ValidateLocation)
+	attendance.Get("/locations/:id", perm.RequireOwn("attendance.read"), locationHandler.GetLocationByID)
+	attendance.Put("/locations/:id", perm.Require("locations.write"), locationHandler.UpdateLocation)
+	attendance.Delete("/locations/:id", perm.Require("locations.delete"), locationHandler.DeleteLocation)
+
+	// Which locations each employee may check in at
+	attendance.Get("/assignments", perm.RequireOwn("attendance.read"), locationAssignmentHandler.GetAllAssignments)
+	attendance.Get("/assignments/my-locations", perm.RequireOwn("attendance.read"), locationAssignmentHandler.GetMyLocations)
+	attendance.Post("/assignments", perm.Require("locations.write"), locationAssignmentHandler.CreateAssignment)
+	attendance.Put("/assignments/:id", perm.Require("locations.write"), locationAssignmentHandler.UpdateAssignment)
+	attendance.Delete("/assignments/:id", perm.Require("locations.write"), locationAssignmentHandler.DeleteAssignment)
+
+	// Remote work requests
+	attendance.Get("/remote-work", perm.RequireOwn("attendance.read"), remoteWorkHandler.GetAllRequests)
+	attendance.Post("/remote-work", perm.RequireOwn("attendance.write"), remoteWorkHandler.CreateRequest)
+	attendance.Put("/remote-work/:id/approve", perm.Require("remote_work.approve"), remoteWorkHandler.ApproveRequest)
+	attendance.Put("/remote-work/:id/reject", perm.Require("remote_work.approve"), remoteWorkHandler.RejectRequest)
+	attendance.Put("/remote-work/:id/cancel", perm.RequireOwn("attendance.write"), remoteWorkHandler.CancelRequest)
+
+	// Leave requests and yearly leave balances
+	attendance.Get("/leave", perm.RequireOwn("attendance.read"), leaveHandler.GetLeaveRequests)
+	attendance.Post("/leave", perm.RequireOwn("attendance.write"), leaveHandler.CreateLeaveRequest)
+	attendance.Get("/leave/balance", perm.RequireOwn("attendance.read"), leaveHandler.GetLeaveBalance)
+	attendance.Put("/leave/balance/:userId", perm.Require("leave.approve"), leaveHandler.UpdateLeaveBalance)
+	attendance.Get("/leave/:id/attachment", perm.RequireOwn("attendance.read"), leaveHandler.GetLeaveAttachment)
+	attendance.Put("/leave/:id/approve", perm.Require("leave.approve"), leaveHandler.ApproveLeaveRequest)
+	attendance.Put("/leave/:id/reject", perm.Require("leave.approve"), leaveHandler.RejectLeaveRequest)
+	attendance.Put("/leave/:id/cancel", perm.RequireOwn("attendance.write"), leaveHandler.CancelLeaveRequest)
+
+	// Work shifts and the roster that lateness is measured against
+	attendance.Get("/shifts", perm.RequireOwn("attendance.read"), workShiftHandler.GetShifts)
+	attendance.Post("/shifts", perm.Require("schedules.write"), workShiftHandler.CreateShift)
+	attendance.Put("/shifts/:id", perm.Require("schedules.write"), workShiftHandler.UpdateShift)
+	attendance.Delete("/shifts/:id", perm.Require("schedules.write"), workShiftHandler.DeleteShift)
+	attendance.Get("/roster", perm.RequireOwn("attendance.read"), workShiftHandler.GetRoster)
+	attendance.Post("/roster", perm.Require("schedules.write"), workShiftHandler.SetRoster)
+	attendance.Delete("/roster/:id", perm.Require("schedules.write"), workShiftHandler.DeleteRosterEntry)
+
+	// Company calendar of public holidays, cuti bersama and branch closures
+	attendance.Get("/holidays", perm.RequireOwn("attendance.read"), holidayHandler.GetHolidays)
+	attendance.Get("/holidays/working-days", perm.RequireOwn("attendance.read"), holidayHandler.GetWorkingDays)
+	attendance.Post("/holidays", perm.Require("schedules.write"), holidayHandler.CreateHoliday)
+	attendance.Post("/holidays/import", perm.Require("schedules.write"), holidayHandler.ImportHolidays)
+	attendance.Put("/holidays/:id", perm.Require("schedules.write"), holidayHandler.UpdateHoliday)
+	attendance.Delete("/holidays/:id", perm.Require("schedules.write"), holidayHandler.DeleteHoliday)
 
 	// Audit routes
 	audit := protected.Group("/audit")
-	audit.Get("/", auditHandler.GetAuditLogs)
+	audit.Get("/", perm.Require("audit.read"), auditHandler.GetAuditLogs)
 
 	// Meal Allowance routes
 	mealAllowance := protected.Group("/meal-allowance")
-	mealAllowance.Get("/preview", mealAllowanceHandler.GetMealAllowancePreview)
-	mealAllowance.Post("/claim", mealAllowanceHandler.ClaimMealAllowance)
-	mealAllowance.Get("/my", mealAllowanceHandler.GetMyMealAllowances)
-	mealAllowance.Get("/all", mealAllowanceHandler.GetAllMealAllowances)
-	mealAllowance.Put("/:id/approve", mealAllowanceHandler.ApproveMealAllowance)
-	mealAllowance.Put("/:id/reject", mealAllowanceHandler.RejectMealAllowance)
-	mealAllowance.Put("/:id/claim-status", mealAllowanceHandler.UpdateMealAllowanceClaimStatus)
-	mealAllowance.Post("/direct-approve", mealAllowanceHandler.DirectApproveMealAllowance)
-	mealAllowance.Get("/policy", mealAllowanceHandler.GetMealAllowancePolicy)
-	mealAllowance.Put("/policy", mealAllowanceHandler.UpdateMealAllowancePolicy)
-	mealAllowance.Get("/management", attendanceHandler.GetMealAllowanceManagement)
-	mealAllowance.Get("/stats", mealAllowanceHandler.GetMealAllowanceStats)
+	mealAllowance.Get("/preview", perm.RequireOwn("attendance.read"), mealAllowanceHandler.GetMealAllowancePreview)
+	mealAllowance.Post("/claim", perm.RequireOwn("attendance.write"), mealAllowanceHandler.ClaimMealAllowance)
+	mealAllowance.Get("/my", perm.RequireOwn("attendance.read"), mealAllowanceHandler.GetMyMealAllowances)
+	mealAllowance.Get("/all", perm.Require("meal_allowance.read"), mealAllowanceHandler.GetAllMealAllowances)
+	mealAllowance.Put("/:id/approve", perm.Require("meal_allowance.approve"), mealAllowanceHandler.ApproveMealAllowance)
+	mealAllowance.Put("/:id/reject", perm.Require("meal_allowance.approve"), mealAllowanceHandler.RejectMealAllowance)
+	mealAllowance.Put("/:id/claim-status", perm.Require("meal_allowance.approve"), mealAllowanceHandler.UpdateMealAllowanceClaimStatus)
+	mealAllowance.Post("/direct-approve", perm.Require("meal_allowance.approve"), mealAllowanceHandler.DirectApproveMealAllowance)
+	mealAllowance.Get("/policy", perm.RequireOwn("attendance.read"), mealAllowanceHandler.GetMealAllowancePolicy)
+	mealAllowance.Put("/policy", perm.Require("meal_allowance.write"), mealAllowanceHandler.UpdateMealAllowancePolicy)
+	mealAllowance.Get("/management", perm.Require("meal_allowance.read"), attendanceHandler.GetMealAllowanceManagement)
+	mealAllowance.Get("/stats", perm.Require("meal_allowance.read"), mealAllowanceHandler.GetMealAllowanceStats)
 
 	// Dashboard routes
 	dashboard := protected.Group("/dashboard")
-	dashboard.Get("/employee", dashboardHandler.GetEmployeeDashboard)
-	dashboard.Get("/employee/summary", dashboardHandler.GetEmployeeSummary)
+	dashboard.Get("/employee", perm.RequireOwn("attendance.read"), dashboardHandler.GetEmployeeDashboard)
+	dashboard.Get("/employee/summary", perm.RequireOwn("attendance.read"), dashboardHandler.GetEmployeeSummary)
+
+	// Computer routes
+	computers := protected.Group("/computers")
+	computers.Get("/", perm.Require("computers.read"), computerHandler.GetAllComputers)
+	computers.Post("/", perm.Require("computers.write"), computerHandler.CreateComputer)
+	computers.Get("/:id", perm.Require("computers.read"), computerHandler.GetComputerByID)
+	computers.Put("/:id", perm.Require("computers.write"), computerHandler.UpdateComputer)
+	computers.Delete("/:id", perm.Require("computers.delete"), computerHandler.DeleteComputer)
+
+	// Time package routes
+	packages := protected.Group("/packages")
+	packages.Get("/", perm.Require("packages.read"), timePackageHandler.GetAllTimePackages)
+	packages.Post("/", perm.Require("packages.write"), timePackageHandler.CreateTimePackage)
+	packages.Get("/:id", perm.Require("packages.read"), timePackageHandler.GetTimePackageByID)
+	packages.Put("/:id", perm.Require("packages.write"), timePackageHandler.UpdateTimePackage)
+	packages.Delete("/:id", perm.Require("packages.delete"), timePackageHandler.DeleteTimePackage)
+
+	// Usage session routes
+	sessions := protected.Group("/sessions")
+	sessions.Get("/", perm.Require("sessions.read"), sessionHandler.GetAllSessions)
+	sessions.Get("/active", perm.Require("sessions.read"), sessionHandler.GetActiveSessions)
+	sessions.Post("/start", perm.Require("sessions.write"), sessionHandler.StartSession)
+	sessions.Get("/:id", perm.Require("sessions.read"), sessionHandler.GetSessionByID)
+	sessions.Post("/:id/extend", perm.Require("sessions.write"), sessionHandler.ExtendSession)
+	sessions.Post("/:id/pause", perm.Require("sessions.write"), sessionHandler.PauseSession)
+	sessions.Post("/:id/resume", perm.Require("sessions.write"), sessionHandler.ResumeSession)
+	sessions.Post("/:id/stop", perm.Require("sessions.write"), sessionHandler.StopSession)
+
+	// Customer routes
+	customers := protected.Group("/customers")
+	customers.Get("/", perm.Require("customers.read"), customerHandler.GetAllCustomers)
+	customers.Post("/", perm.Require("customers.write"), customerHandler.CreateCustomer)
+	customers.Get("/:id", perm.Require("customers.read"), customerHandler.GetCustomerByID)
+	customers.Put("/:id", perm.Require("customers.write"), customerHandler.UpdateCustomer)
+	customers.Delete("/:id", perm.Require("customers.delete"), customerHandler.DeleteCustomer)
+	customers.Get("/:id/points", perm.Require("customers.read"), customerHandler.GetPointsHistory)
+	customers.Post("/:id/points/earn", perm.Require("customers.write"), customerHandler.EarnPoints)
+	customers.Post("/:id/points/redeem", perm.Require("customers.write"), customerHandler.RedeemPoints)
+	customers.Post("/:id/points/adjust", perm.Require("customers.write"), customerHandler.AdjustPoints)
+
+	// Stock routes
+	stock := protected.Group("/stock")
+	stock.Get("/", perm.Require("stock.read"), stockHandler.GetAllStockItems)
+	stock.Post("/", perm.Require("stock.write"), stockHandler.CreateStockItem)
+	stock.Get("/low", perm.Require("stock.read"), stockHandler.GetLowStockItems)
+	stock.Get("/:id", perm.Require("stock.read"), stockHandler.GetStockItemByID)
+	stock.Put("/:id", perm.Require("stock.write"), stockHandler.UpdateStockItem)
+	stock.Delete("/:id", perm.Require("stock.delete"), stockHandler.DeleteStockItem)
+	stock.Get("/:id/movements", perm.Require("stock.read"), stockHandler.GetStockMovements)
+	stock.Post("/:id/movements", perm.Require("stock.write"), stockHandler.CreateStockMovement)
+
+	// Menu routes
+	menu := protected.Group("/menu")
+	menu.Get("/", perm.Require("menu.read"), menuHandler.GetAllMenuItems)
+	menu.Post("/", perm.Require("menu.write"), menuHandler.CreateMenuItem)
+	menu.Get("/:id", perm.Require("menu.read"), menuHandler.GetMenuItemByID)
+	menu.Put("/:id", perm.Require("menu.write"), menuHandler.UpdateMenuItem)
+	menu.Delete("/:id", perm.Require("menu.delete"), menuHandler.DeleteMenuItem)
+
+	// Kitchen order routes
+	orders := protected.Group("/orders")
+	orders.Get("/", perm.Require("orders.read"), orderHandler.GetAllOrders)
+	orders.Post("/", perm.Require("orders.write"), orderHandler.CreateOrder)
+	orders.Get("/queue", perm.Require("orders.read"), orderHandler.GetOrderQueue)
+	orders.Get("/:id", perm.Require("orders.read"), orderHandler.GetOrderByID)
+	orders.Put("/:id/status", perm.Require("orders.write"), orderHandler.UpdateOrderStatus)
+
+	// Transaction ledger routes
+	transactions := protected.Group("/transactions")
+	transactions.Get("/", perm.RequireOwn("transactions.read"), transactionHandler.GetAllTransactions)
+	transactions.Get("/summary", perm.RequireOwn("transactions.read"), transactionHandler.GetTransactionSummary)
+	transactions.Post("/sales", perm.Require("transactions.write"), transactionHandler.CreateSale)
+	transactions.Post("/purchases", perm.Require("transactions.write"), transactionHandler.CreatePurchase)
+	transactions.Get("/:id", perm.RequireOwn("transactions.read"), transactionHandler.GetTransactionByID)
+	transactions.Post("/:id/void", perm.Require("transactions.void"), transactionHandler.VoidTransaction)
+	transactions.Post("/:id/refund", perm.Require("transactions.void"), transactionHandler.RefundTransaction)
+
+	// Cashier shift routes
+	shifts := protected.Group("/shifts")
+	shifts.Get("/", perm.RequireOwn("shifts.read"), cashierShiftHandler.GetAllShifts)
+	shifts.Post("/open", perm.RequireOwn("shifts.write"), cashierShiftHandler.OpenShift)
+	shifts.Get("/current", perm.RequireOwn("shifts.write"), cashierShiftHandler.GetCurrentShift)
+	shifts.Get("/:id", perm.RequireOwn("shifts.read"), cashierShiftHandler.GetShiftByID)
+	shifts.Post("/:id/close", perm.RequireOwn("shifts.write"), cashierShiftHandler.CloseShift)
 }
\ No newline at end of file
@@ -55,4 +55,9 @@ func IsValidImageFile(filename string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// IsValidDocumentFile accepts images and PDFs, e.g. scanned medical certificates
+func IsValidDocumentFile(filename string) bool {
+	return IsValidImageFile(filename) || strings.ToLower(filepath.Ext(filename)) == ".pdf"
+}
@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ICalEvent is an all-day view of a VEVENT: the dates it covers, without times
+type ICalEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Categories  []string
+	Start       time.Time // first day
+	End         time.Time // last day, inclusive
+}
+
+// ParseICal reads the events of an iCalendar (.ics) file, such as a public holiday
+// calendar export. Recurrence rules are not expanded; each event covers the days
+// from DTSTART to DTEND.
+func ParseICal(r io.Reader) ([]ICalEvent, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []ICalEvent
+	var current *ICalEvent
+	var endValue string
+	var endIsDate bool
+	sawCalendar := false
+
+	for number, line := range lines {
+		name, params, value := splitICalProperty(line)
+		switch {
+		case name == "BEGIN" && value == "VCALENDAR":
+			sawCalendar = true
+		case name == "BEGIN" && value == "VEVENT":
+			current = &ICalEvent{}
+			endValue, endIsDate = "", false
+		case name == "END" && value == "VEVENT":
+			if current == nil {
+				return nil, fmt.Errorf("line %d: END:VEVENT without BEGIN:VEVENT", number+1)
+			}
+			if current.Start.IsZero() {
+				return nil, fmt.Errorf("line %d: event %q has no DTSTART", number+1, current.Summary)
+			}
+			current.End = current.Start
+			if endValue != "" {
+				end, err := parseICalDate(endValue)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", number+1, err)
+				}
+				// An all-day DTEND, or a midnight end time, is the first day not covered
+				if endIsDate || strings.HasSuffix(strings.TrimSuffix(endValue, "Z"), "T000000") {
+					end = end.AddDate(0, 0, -1)
+				}
+				if end.After(current.Start) {
+					current.End = end
+				}
+			}
+			events = append(events, *current)
+			current = nil
+		case current == nil:
+			// Calendar properties and other components are not needed
+		case name == "UID":
+			current.UID = value
+		case name == "SUMMARY":
+			current.Summary = unescapeICalText(value)
+		case name == "DESCRIPTION":
+			current.Description = unescapeICalText(value)
+		case name == "CATEGORIES":
+			for _, category := range strings.Split(value, ",") {
+				if category = strings.TrimSpace(unescapeICalText(category)); category != "" {
+					current.Categories = append(current.Categories, category)
+				}
+			}
+		case name == "DTSTART":
+			start, err := parseICalDate(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", number+1, err)
+			}
+			current.Start = start
+		case name == "DTEND":
+			endValue = value
+			endIsDate = strings.Contains(strings.ToUpper(params), "VALUE=DATE") && !strings.Contains(value, "T")
+		}
+	}
+
+	if !sawCalendar {
+		return nil, errors.New("not an iCalendar file: missing BEGIN:VCALENDAR")
+	}
+	return events, nil
+}
+
+// unfoldICalLines joins continuation lines, which start with a space or tab
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// splitICalProperty splits "NAME;PARAM=x:value" into its name, parameters and value.
+// Colons inside quoted parameter values do not end the parameters.
+func splitICalProperty(line string) (string, string, string) {
+	quoted := false
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ':' && !quoted:
+			head, value := line[:i], line[i+1:]
+			name, params := head, ""
+			if semicolon := strings.IndexByte(head, ';'); semicolon >= 0 {
+				name, params = head[:semicolon], head[semicolon+1:]
+			}
+			return strings.ToUpper(name), params, value
+		}
+	}
+	return strings.ToUpper(line), "", ""
+}
+
+// parseICalDate reads the calendar date of a DATE (20060102) or DATE-TIME
+// (20060102T150405, optionally with a trailing Z) value
+func parseICalDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return date, nil
+}
+
+func unescapeICalText(value string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return replacer.Replace(value)
+}
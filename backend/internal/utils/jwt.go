@@ -8,18 +8,43 @@ import (
 )
 
 type Claims struct {
-	UserID   uuid.UUID `json:"user_id"`
-	Username string    `json:"username"`
-	Role     string    `json:"role"`
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Purpose   string    `json:"purpose,omitempty"` // empty for access tokens
+	SessionID string    `json:"sid,omitempty"`     // device session the token belongs to
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uuid.UUID, username, role, secret string, expireDuration time.Duration) (string, error) {
+// TokenPurposeTwoFactor marks a pre-auth token that is only good for the second login step
+const TokenPurposeTwoFactor = "2fa"
+
+func GenerateToken(userID, sessionID uuid.UUID, username, role, secret string, expireDuration time.Duration) (string, error) {
+	claims := Claims{
+		UserID:    userID,
+		Username:  username,
+		Role:      role,
+		SessionID: sessionID.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expireDuration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+// GeneratePreAuthToken issues a short-lived token proving the password step of a login
+// succeeded. It cannot be used as an access token.
+func GeneratePreAuthToken(userID uuid.UUID, username, secret string, expireDuration time.Duration) (string, error) {
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
-		Role:     role,
+		Purpose:  TokenPurposeTwoFactor,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expireDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
@@ -43,4 +68,4 @@ func ValidateToken(tokenString, secret string) (*Claims, error) {
 	}
 
 	return nil, jwt.ErrInvalidKey
-}
\ No newline at end of file
+}
@@ -0,0 +1,90 @@
+package workers
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"cybercafe-backend/internal/config"
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/realtime"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AttendanceFlagWorker periodically flags check-ins left open longer than the maximum
+// shift length, so forgotten check-outs surface for review instead of blocking the
+// employee's next check-in.
+type AttendanceFlagWorker struct {
+	db        *gorm.DB
+	hub       *realtime.Hub
+	interval  time.Duration
+	maxLength time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func NewAttendanceFlagWorker(db *gorm.DB, cfg *config.Config, hub *realtime.Hub) *AttendanceFlagWorker {
+	interval, err := time.ParseDuration(cfg.AttendanceCheckInterval)
+	if err != nil || interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	return &AttendanceFlagWorker{
+		db:        db,
+		hub:       hub,
+		interval:  interval,
+		maxLength: cfg.GetMaxShiftLength(),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start runs the worker loop in its own goroutine
+func (w *AttendanceFlagWorker) Start() {
+	go w.run()
+	log.Printf("Attendance flag worker started (interval %s, max shift length %s)", w.interval, w.maxLength)
+}
+
+// Stop signals the worker to exit and waits for the current scan to finish
+func (w *AttendanceFlagWorker) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+	log.Println("Attendance flag worker stopped")
+}
+
+func (w *AttendanceFlagWorker) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.scan()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.scan()
+		}
+	}
+}
+
+func (w *AttendanceFlagWorker) scan() {
+	flagged, err := models.FlagOverdueAttendances(w.db, uuid.Nil, w.maxLength, time.Now())
+	if err != nil {
+		log.Printf("[ATTENDANCE FLAG] Failed to flag open attendance: %v", err)
+		return
+	}
+
+	for i := range flagged {
+		log.Printf("[ATTENDANCE FLAG] Attendance %s of user %s open since %s exceeded the maximum shift length",
+			flagged[i].ID, flagged[i].UserID, flagged[i].CheckInTime.Format(time.RFC3339))
+		w.hub.PublishForUser(realtime.TopicAttendance, "attendance.flagged", flagged[i].UserID, flagged[i])
+	}
+}
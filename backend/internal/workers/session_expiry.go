@@ -0,0 +1,195 @@
+package workers
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"cybercafe-backend/internal/config"
+	"cybercafe-backend/internal/models"
+	"cybercafe-backend/internal/realtime"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SessionExpiryWorker periodically closes usage sessions that ran past their end time
+// and flags sessions that are about to run out.
+type SessionExpiryWorker struct {
+	db            *gorm.DB
+	hub           *realtime.Hub
+	interval      time.Duration
+	warningBefore time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func NewSessionExpiryWorker(db *gorm.DB, cfg *config.Config, hub *realtime.Hub) *SessionExpiryWorker {
+	interval, err := time.ParseDuration(cfg.SessionCheckInterval)
+	if err != nil || interval <= 0 {
+		interval = time.Minute
+	}
+	warningBefore, err := time.ParseDuration(cfg.SessionWarningBefore)
+	if err != nil || warningBefore < 0 {
+		warningBefore = 5 * time.Minute
+	}
+
+	return &SessionExpiryWorker{
+		db:            db,
+		hub:           hub,
+		interval:      interval,
+		warningBefore: warningBefore,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// Start runs the worker loop in its own goroutine
+func (w *SessionExpiryWorker) Start() {
+	go w.run()
+	log.Printf("Session expiry worker started (interval %s, warning %s before end)", w.interval, w.warningBefore)
+}
+
+// Stop signals the worker to exit and waits for the current scan to finish
+func (w *SessionExpiryWorker) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+	log.Println("Session expiry worker stopped")
+}
+
+func (w *SessionExpiryWorker) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	w.scan()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.scan()
+		}
+	}
+}
+
+func (w *SessionExpiryWorker) scan() {
+	now := time.Now()
+
+	var expired []models.UsageSession
+	if err := w.db.Where("status = ? AND end_time <= ?", models.SessionStatusActive, now).
+		Find(&expired).Error; err != nil {
+		log.Printf("[SESSION EXPIRY] Failed to fetch expired sessions: %v", err)
+		return
+	}
+
+	for _, session := range expired {
+		select {
+		case <-w.stop:
+			return
+		default:
+		}
+		if err := w.expireSession(session.ID, now); err != nil {
+			log.Printf("[SESSION EXPIRY] Failed to expire session %s: %v", session.ID, err)
+		}
+	}
+
+	if w.warningBefore > 0 {
+		w.sendWarnings(now)
+	}
+}
+
+// expireSession closes a single session inside a transaction so a crash or shutdown
+// never leaves the session expired while its computer is still marked in use.
+func (w *SessionExpiryWorker) expireSession(sessionID uuid.UUID, now time.Time) error {
+	var session models.UsageSession
+	expired := false
+
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Package").Preload("Computer").
+			Where("id = ?", sessionID).First(&session).Error; err != nil {
+			return err
+		}
+
+		// The session may have been stopped or extended since the scan query ran
+		if session.Status != models.SessionStatusActive || session.EndTime.After(now) {
+			return nil
+		}
+
+		session.CalculateCharge(session.EndTime)
+		session.Status = models.SessionStatusExpired
+		session.StoppedAt = &now
+
+		if err := tx.Omit(clause.Associations).Save(&session).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Computer{}).Where("id = ?", session.ComputerID).
+			Update("status", models.ComputerStatusAvailable).Error; err != nil {
+			return err
+		}
+
+		// Attributed to the cashier who started the session since audit rows reference a user
+		auditLog := models.AuditLog{
+			UserID:   session.StartedBy,
+			Action:   "SESSION_EXPIRED",
+			Resource: "/sessions/" + session.ID.String(),
+			Details: fmt.Sprintf("Session for computer %s expired automatically at %s, total charge %d",
+				session.ComputerID, session.EndTime.Format(time.RFC3339), session.TotalAmount),
+		}
+		if err := tx.Create(&auditLog).Error; err != nil {
+			return err
+		}
+
+		expired = true
+		return nil
+	})
+	if err != nil || !expired {
+		return err
+	}
+
+	session.Computer.Status = models.ComputerStatusAvailable
+	w.hub.Publish(realtime.TopicSessions, "session.expired", session)
+	w.hub.Publish(realtime.TopicComputers, "computer.status_changed", map[string]interface{}{
+		"id":     session.Computer.ID,
+		"name":   session.Computer.Name,
+		"zone":   session.Computer.Zone,
+		"status": session.Computer.Status,
+	})
+	return nil
+}
+
+func (w *SessionExpiryWorker) sendWarnings(now time.Time) {
+	var sessions []models.UsageSession
+	if err := w.db.Preload("Computer").
+		Where("status = ? AND warning_sent_at IS NULL AND end_time > ? AND end_time <= ?",
+			models.SessionStatusActive, now, now.Add(w.warningBefore)).
+		Find(&sessions).Error; err != nil {
+		log.Printf("[SESSION EXPIRY] Failed to fetch expiring sessions: %v", err)
+		return
+	}
+
+	for _, session := range sessions {
+		if err := w.db.Model(&models.UsageSession{}).Where("id = ?", session.ID).
+			Update("warning_sent_at", now).Error; err != nil {
+			log.Printf("[SESSION EXPIRY] Failed to mark warning for session %s: %v", session.ID, err)
+			continue
+		}
+		log.Printf("[SESSION EXPIRY] Session on %s ends in %d minute(s)", session.Computer.Name, session.RemainingMinutes(now))
+		w.hub.Publish(realtime.TopicSessions, "session.warning", map[string]interface{}{
+			"session_id":        session.ID,
+			"computer_id":       session.ComputerID,
+			"computer_name":     session.Computer.Name,
+			"customer_name":     session.CustomerName,
+			"end_time":          session.EndTime,
+			"remaining_minutes": session.RemainingMinutes(now),
+		})
+	}
+}
@@ -171,7 +171,9 @@ const StaffManagement = () => {
     setRoleForm({
       name: role.name || '',
       description: role.description || '',
-      permissions: role.permissions || '[]'
+      permissions: Array.isArray(role.permissions)
+        ? JSON.stringify(role.permissions)
+        : role.permissions || '[]'
     });
     setShowRoleModal(true);
   };
@@ -671,7 +673,10 @@ const StaffManagement = () => {
                     <div>
                       <label className="block text-sm font-medium text-gray-700">Permissions</label>
                       <div className="mt-1">
-                        {JSON.parse(selectedStaff.role.permissions).map((permission, index) => (
+                        {(Array.isArray(selectedStaff.role.permissions)
+                          ? selectedStaff.role.permissions
+                          : JSON.parse(selectedStaff.role.permissions)
+                        ).map((permission, index) => (
                           <span key={index} className="inline-block bg-gray-100 text-gray-800 text-xs px-2 py-1 rounded mr-1 mb-1">
                             {permission}
                           </span>